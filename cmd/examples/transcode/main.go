@@ -1,18 +1,17 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"os"
-	"sync"
 	"syscall"
 
 	// Packages
+	media "github.com/mutablelogic/go-media"
 	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
 )
 
-// This example encodes an audio an video stream to a file
+// This example transcodes the audio and video streams of a file to another file
 func main() {
 	// Bail out when we receive a signal
 	ctx := ContextForSignal(os.Interrupt, syscall.SIGQUIT)
@@ -29,69 +28,19 @@ func main() {
 	}
 	defer in.Close()
 
-	// Map to output file
-	decoding, err := in.Map(func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
-		// This is where you specify the output format for the input stream
-		return par, nil
-	})
-	if err != nil {
-		log.Fatal(err)
-	}
-
-	// Create an output file from from the map
-	out, err := ffmpeg.Create(os.Args[2], ffmpeg.OptContext(decoding))
-	if err != nil {
+	// Transcode to the output file
+	if err := in.TranscodeFile(ctx, os.Args[2], func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		// This is where you specify the output format for the input stream,
+		// or return nil to ignore the stream
+		switch par.Type() {
+		case media.AUDIO, media.VIDEO:
+			return par, nil
+		default:
+			return nil, nil
+		}
+	}); err != nil {
 		log.Fatal(err)
 	}
-	defer out.Close()
-
-	// Decoding goroutine
-	var result error
-	var wg sync.WaitGroup
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		// This is where we decode the stream
-		result = errors.Join(result, in.DecodeWithContext(ctx, decoding, func(stream int, frame *ffmpeg.Frame) error {
 
-			// Add the frame onto the encoding queue
-			fmt.Println("->DECODE:", stream, frame)
-			decoding.C(stream) <- frame
-			fmt.Println("<-DECODE")
-
-			// Return success
-			return nil
-		}))
-	}()
-
-	// Encoding goroutine
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-
-		// This is where we encode the stream
-		result = errors.Join(result, out.Encode(ctx, func(stream int) (*ffmpeg.Frame, error) {
-			fmt.Println("->ENCODE", stream)
-			select {
-			case frame := <-decoding.C(stream):
-				fmt.Println("<-ENCODE", frame)
-				return frame, nil
-			default:
-				// Not ready to pass a frame back
-				fmt.Println("<-ENCODE", nil)
-				return nil, nil
-			}
-		}, nil))
-		if result != nil {
-			fmt.Println("ERROR:", result)
-		}
-	}()
-
-	wg.Wait()
-
-	if result != nil {
-		log.Fatal(result)
-	}
-	fmt.Println("Transcoded to", out)
+	fmt.Println("Transcoded to", os.Args[2])
 }
@@ -13,6 +13,7 @@ import (
 func main() {
 	in := flag.String("in", "", "input file")
 	out := flag.String("out", "", "output file")
+	nochapters := flag.Bool("nochapters", false, "do not copy chapters")
 	flag.Parse()
 
 	// Check in and out
@@ -77,6 +78,21 @@ func main() {
 		stream_index = stream_index + 1
 	}
 
+	// Copy the chapters
+	if !*nochapters {
+		for _, in_chapter := range input.Chapters() {
+			out_chapter := ff.AVFormat_new_chapter(output, in_chapter.Id(), in_chapter.TimeBase(), in_chapter.Start(), in_chapter.End())
+			if out_chapter == nil {
+				log.Fatal("failed to create new chapter")
+			}
+			if metadata, err := ff.AVUtil_dict_copy(in_chapter.Metadata(), 0); err != nil {
+				log.Fatal(err)
+			} else {
+				out_chapter.SetMetadata(metadata)
+			}
+		}
+	}
+
 	// Dump the output format
 	ff.AVFormat_dump_format(output, 0, *out)
 
@@ -0,0 +1,5 @@
+/*
+Package analysis provides measurements over decoded audio, such as waveform
+peaks for display in audio players and EBU R128 loudness
+*/
+package analysis
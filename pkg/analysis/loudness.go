@@ -0,0 +1,502 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Loudness measures the loudness of audio according to EBU R128 and
+// ITU-R BS.1770, with K-weighting, gating of the integrated loudness and
+// loudness range, and true-peak measurement by oversampling
+type Loudness struct {
+	samplerate int
+	block      int         // Number of samples in each 100ms block
+	n          int         // Number of samples in the current block
+	channels   []*channel  // State for each channel
+	history    []float64   // Energy of the most recent blocks, up to three seconds
+	momentary  []float64   // Energy of each 400ms gating block
+	shortterm  []float64   // Energy of each three second block
+	max        [2]float64  // Maximum momentary and short-term energy
+	interp     [][]float64 // True-peak interpolation filter for each phase
+}
+
+type channel struct {
+	weight float64
+	filter [2]biquad
+	sum    float64   // Sum of squares of the weighted samples in the current block
+	x      []float64 // Recent samples, for true-peak interpolation
+	peak   float64   // Maximum absolute sample value
+	tpeak  float64   // Maximum absolute interpolated value
+}
+
+// Biquad filter in transposed direct form II
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	z1, z2             float64
+}
+
+type jsonLoudness struct {
+	Integrated  *float64 `json:"integrated,omitempty"`
+	Range       float64  `json:"range"`
+	Momentary   *float64 `json:"momentary_max,omitempty"`
+	ShortTerm   *float64 `json:"short_term_max,omitempty"`
+	TruePeak    *float64 `json:"true_peak,omitempty"`
+	SamplePeak  *float64 `json:"sample_peak,omitempty"`
+	ReplayGain  *float64 `json:"replaygain_gain,omitempty"`
+	SampleRate  int      `json:"sample_rate"`
+	NumChannels int      `json:"channels"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	loudnessOffset       = -0.691 // Offset of the loudness, in LUFS
+	loudnessAbsoluteGate = -70.0  // Absolute gate, in LUFS
+	loudnessRelativeGate = -10.0  // Relative gate for integrated loudness, in LU
+	loudnessRangeGate    = -20.0  // Relative gate for loudness range, in LU
+	loudnessRangeLow     = 0.10   // Lower percentile for loudness range
+	loudnessRangeHigh    = 0.95   // Upper percentile for loudness range
+	loudnessMomentary    = 4      // Number of blocks in the momentary window (400ms)
+	loudnessShortTerm    = 30     // Number of blocks in the short-term window (3s)
+	loudnessSurround     = 1.41   // Weight of surround channels (+1.5dB)
+	truePeakTaps         = 49     // Number of taps in the true-peak interpolation filter
+	replayGainReference  = -18.0  // ReplayGain 2.0 reference loudness, in LUFS
+	r128Reference        = -23.0  // EBU R128 reference loudness, in LUFS
+)
+
+// Metadata keys for ReplayGain and R128 gain tags
+const (
+	MetaReplayGainTrackGain = "REPLAYGAIN_TRACK_GAIN"
+	MetaReplayGainTrackPeak = "REPLAYGAIN_TRACK_PEAK"
+	MetaR128TrackGain       = "R128_TRACK_GAIN"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a loudness meter for a channel layout (for example, "stereo" or
+// "5.1") and sample rate. Low-frequency effects channels are not included
+// in the measurement, and surround channels are weighted by +1.5dB.
+func NewLoudness(layout string, samplerate int) (*Loudness, error) {
+	var ch ff.AVChannelLayout
+	if err := ff.AVUtil_channel_layout_from_string(&ch, layout); err != nil {
+		return nil, ErrBadParameter.Withf("invalid channel layout %q", layout)
+	}
+	defer ff.AVUtil_channel_layout_uninit(&ch)
+	if samplerate <= 0 {
+		return nil, ErrBadParameter.Withf("invalid sample rate %d", samplerate)
+	}
+
+	// Set the channel weights and filters
+	l := &Loudness{
+		samplerate: samplerate,
+		block:      max(samplerate/10, 1),
+		interp:     truePeakFilter(truePeakFactor(samplerate)),
+		max:        [2]float64{math.NaN(), math.NaN()},
+	}
+	for i := 0; i < ch.NumChannels(); i++ {
+		name, _ := ff.AVUtil_channel_name(ff.AVUtil_channel_layout_channel_from_index(&ch, i))
+		l.channels = append(l.channels, &channel{
+			weight: channelWeight(name),
+			filter: kweighting(float64(samplerate)),
+			x:      make([]float64, len(l.interp[0])),
+		})
+	}
+
+	// Return success
+	return l, nil
+}
+
+// Decode the best audio stream from the reader, and return the loudness
+func ReadLoudness(ctx context.Context, r *ffmpeg.Reader) (*Loudness, error) {
+	stream := r.BestStream(media.AUDIO)
+	if stream < 0 {
+		return nil, ErrNotFound.With("no audio stream")
+	}
+
+	// Resample to planar float32 with the same channel layout and sample rate
+	par := r.Par(stream)
+	ch := par.ChannelLayout()
+	layout, err := ff.AVUtil_channel_layout_describe(&ch)
+	if err != nil {
+		return nil, err
+	}
+	meter, err := NewLoudness(layout, par.Samplerate())
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the frames
+	samples := make([][]float32, ch.NumChannels())
+	if err := r.Decode(ctx, func(s int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if s == stream {
+			return ffmpeg.NewAudioPar("fltp", layout, par.Samplerate())
+		}
+		return nil, nil
+	}, func(_ int, frame *ffmpeg.Frame) error {
+		for c := range samples {
+			samples[c] = frame.Float32(c)
+		}
+		return meter.Write(samples)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Return success
+	return meter, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (l *Loudness) MarshalJSON() ([]byte, error) {
+	var gain *float64
+	if i := l.Integrated(); !math.IsInf(i, -1) {
+		gain = finite(replayGainReference - i)
+	}
+	return json.Marshal(jsonLoudness{
+		Integrated:  finite(l.Integrated()),
+		Range:       l.Range(),
+		Momentary:   finite(l.MaxMomentary()),
+		ShortTerm:   finite(l.MaxShortTerm()),
+		TruePeak:    finite(l.TruePeak()),
+		SamplePeak:  finite(l.SamplePeak()),
+		ReplayGain:  gain,
+		SampleRate:  l.samplerate,
+		NumChannels: len(l.channels),
+	})
+}
+
+func (l *Loudness) String() string {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Add planar samples for each channel, between -1 and 1. Every channel
+// should have the same number of samples.
+func (l *Loudness) Write(samples [][]float32) error {
+	if len(samples) != len(l.channels) {
+		return ErrBadParameter.Withf("expected %d channels, got %d", len(l.channels), len(samples))
+	}
+	n := len(samples[0])
+	for _, s := range samples[1:] {
+		if len(s) != n {
+			return ErrBadParameter.With("channels have different numbers of samples")
+		}
+	}
+
+	// Filter the samples, and complete each block when full
+	for i := 0; i < n; {
+		j := min(n, i+l.block-l.n)
+		for c, s := range samples {
+			l.channels[c].write(s[i:j], l.interp)
+		}
+		l.n += j - i
+		i = j
+		if l.n == l.block {
+			l.flush()
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the momentary loudness of the last 400ms, in LUFS, or -Inf if
+// there are not enough samples
+func (l *Loudness) Momentary() float64 {
+	return loudness(l.window(loudnessMomentary))
+}
+
+// Return the short-term loudness of the last three seconds, in LUFS, or -Inf
+// if there are not enough samples
+func (l *Loudness) ShortTerm() float64 {
+	return loudness(l.window(loudnessShortTerm))
+}
+
+// Return the maximum momentary loudness, in LUFS
+func (l *Loudness) MaxMomentary() float64 {
+	return loudness(l.max[0])
+}
+
+// Return the maximum short-term loudness, in LUFS
+func (l *Loudness) MaxShortTerm() float64 {
+	return loudness(l.max[1])
+}
+
+// Return the integrated loudness, in LUFS, or -Inf if the audio is silent
+func (l *Loudness) Integrated() float64 {
+	gated := gate(l.momentary, loudnessRelativeGate)
+	if len(gated) == 0 {
+		return math.Inf(-1)
+	}
+	return loudness(mean(gated))
+}
+
+// Return the loudness range, in LU
+func (l *Loudness) Range() float64 {
+	gated := gate(l.shortterm, loudnessRangeGate)
+	if len(gated) == 0 {
+		return 0
+	}
+	slices.Sort(gated)
+	low := gated[int(math.Round(float64(len(gated)-1)*loudnessRangeLow))]
+	high := gated[int(math.Round(float64(len(gated)-1)*loudnessRangeHigh))]
+	return loudness(high) - loudness(low)
+}
+
+// Return the maximum true peak of all channels, in dBTP
+func (l *Loudness) TruePeak() float64 {
+	var peak float64
+	for _, c := range l.channels {
+		peak = max(peak, c.tpeak, c.peak)
+	}
+	return 20 * math.Log10(peak)
+}
+
+// Return the maximum sample peak of all channels, in dBFS
+func (l *Loudness) SamplePeak() float64 {
+	var peak float64
+	for _, c := range l.channels {
+		peak = max(peak, c.peak)
+	}
+	return 20 * math.Log10(peak)
+}
+
+// Return ReplayGain 2.0 and R128 track gain tags, which can be written with
+// OptMetadata. The ReplayGain peak is the linear true peak. Returns nil if
+// the audio is silent.
+func (l *Loudness) Metadata() []*ffmpeg.Metadata {
+	i := l.Integrated()
+	if math.IsInf(i, -1) {
+		return nil
+	}
+	r128 := math.Round((r128Reference - i) * 256)
+	return []*ffmpeg.Metadata{
+		ffmpeg.NewMetadata(MetaReplayGainTrackGain, fmt.Sprintf("%.2f dB", replayGainReference-i)),
+		ffmpeg.NewMetadata(MetaReplayGainTrackPeak, fmt.Sprintf("%.6f", math.Pow(10, l.TruePeak()/20))),
+		ffmpeg.NewMetadata(MetaR128TrackGain, strconv.Itoa(int(max(math.MinInt16, min(math.MaxInt16, r128))))),
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Complete the current block, and update the momentary and short-term
+// blocks when there are enough blocks
+func (l *Loudness) flush() {
+	var energy float64
+	for _, c := range l.channels {
+		energy += c.weight * c.sum / float64(l.n)
+		c.sum = 0
+	}
+	l.n = 0
+
+	// Keep three seconds of blocks
+	l.history = append(l.history, energy)
+	if len(l.history) > loudnessShortTerm {
+		l.history = l.history[1:]
+	}
+
+	// Gating blocks overlap by 75%, and short-term blocks are updated
+	// every 100ms
+	if e := l.window(loudnessMomentary); !math.IsNaN(e) {
+		l.momentary = append(l.momentary, e)
+		l.max[0] = nanmax(l.max[0], e)
+	}
+	if e := l.window(loudnessShortTerm); !math.IsNaN(e) {
+		l.shortterm = append(l.shortterm, e)
+		l.max[1] = nanmax(l.max[1], e)
+	}
+}
+
+// Return the mean energy of the most recent blocks, or NaN if there are not
+// enough blocks
+func (l *Loudness) window(n int) float64 {
+	if len(l.history) < n {
+		return math.NaN()
+	}
+	return mean(l.history[len(l.history)-n:])
+}
+
+// Filter samples, and measure the peak and true peak
+func (c *channel) write(samples []float32, interp [][]float64) {
+	for _, v := range samples {
+		x := float64(v)
+		c.peak = max(c.peak, math.Abs(x))
+
+		// Interpolate between samples for each phase
+		if len(interp) > 1 {
+			copy(c.x[1:], c.x)
+			c.x[0] = x
+			for _, h := range interp {
+				var y float64
+				for k, hk := range h {
+					y += hk * c.x[k]
+				}
+				c.tpeak = max(c.tpeak, math.Abs(y))
+			}
+		}
+
+		// Apply the K-weighting filter
+		y := c.filter[1].process(c.filter[0].process(x))
+		c.sum += y * y
+	}
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.z1
+	f.z1 = f.b1*x - f.a1*y + f.z2
+	f.z2 = f.b2*x - f.a2*y
+	return y
+}
+
+// Return the K-weighting filter for a sample rate, which is a high-shelf
+// filter followed by a high-pass filter
+func kweighting(fs float64) [2]biquad {
+	var result [2]biquad
+
+	// High-shelf filter, modelling the acoustic effect of the head
+	f0, g, q := 1681.974450955533, 3.999843853973347, 0.7071752369554196
+	k := math.Tan(math.Pi * f0 / fs)
+	vh := math.Pow(10, g/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/q + k*k
+	result[0] = biquad{
+		b0: (vh + vb*k/q + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/q + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	// High-pass filter (RLB weighting)
+	f0, q = 38.13547087602444, 0.5003270373238773
+	k = math.Tan(math.Pi * f0 / fs)
+	a0 = 1 + k/q + k*k
+	result[1] = biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/q + k*k) / a0,
+	}
+
+	return result
+}
+
+// Return the oversampling factor for true-peak measurement, so that the
+// oversampled rate is at least 192kHz
+func truePeakFactor(samplerate int) int {
+	switch {
+	case samplerate < 96000:
+		return 4
+	case samplerate < 192000:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// Return a windowed-sinc interpolation filter for each phase
+func truePeakFilter(factor int) [][]float64 {
+	result := make([][]float64, factor)
+	for j := 0; j < truePeakTaps; j++ {
+		m := float64(j) - float64(truePeakTaps-1)/2
+		h := 1.0
+		if math.Abs(m) > 1e-6 {
+			h = math.Sin(m*math.Pi/float64(factor)) / (m * math.Pi / float64(factor))
+		}
+		h *= 0.5 * (1 - math.Cos(2*math.Pi*float64(j)/float64(truePeakTaps-1)))
+		result[j%factor] = append(result[j%factor], h)
+	}
+	return result
+}
+
+// Return the weight of a channel from the channel name
+func channelWeight(name string) float64 {
+	switch name {
+	case "LFE", "LFE2":
+		return 0
+	case "SL", "SR", "BL", "BR", "BC", "SDL", "SDR":
+		return loudnessSurround
+	default:
+		return 1
+	}
+}
+
+// Return the blocks above the absolute gate, and above the relative gate
+// from the mean of those blocks
+func gate(blocks []float64, relative float64) []float64 {
+	abs := make([]float64, 0, len(blocks))
+	for _, e := range blocks {
+		if loudness(e) >= loudnessAbsoluteGate {
+			abs = append(abs, e)
+		}
+	}
+	if len(abs) == 0 {
+		return nil
+	}
+	threshold := loudness(mean(abs)) + relative
+	result := abs[:0]
+	for _, e := range abs {
+		if loudness(e) >= threshold {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+// Return the loudness for an energy, in LUFS, or -Inf
+func loudness(energy float64) float64 {
+	if math.IsNaN(energy) || energy <= 0 {
+		return math.Inf(-1)
+	}
+	return loudnessOffset + 10*math.Log10(energy)
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// Return the maximum, ignoring an initial NaN
+func nanmax(a, b float64) float64 {
+	if math.IsNaN(a) {
+		return b
+	}
+	return max(a, b)
+}
+
+// Return a pointer to a finite value, or nil
+func finite(v float64) *float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return nil
+	}
+	return &v
+}
@@ -0,0 +1,200 @@
+package analysis_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	analysis "github.com/mutablelogic/go-media/pkg/analysis"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+// Write a sine wave with the amplitude in dBFS to each channel
+func writeSine(l *analysis.Loudness, channels []bool, freq, dbfs float64, samplerate int, secs float64) error {
+	amplitude := math.Pow(10, dbfs/20)
+	samples := make([][]float32, len(channels))
+	for c := range samples {
+		samples[c] = make([]float32, 1000)
+	}
+	n := int(secs * float64(samplerate))
+	for i := 0; i < n; i += 1000 {
+		for c, on := range channels {
+			for j := range samples[c] {
+				if on {
+					samples[c][j] = float32(amplitude * math.Sin(2*math.Pi*freq*float64(i+j)/float64(samplerate)))
+				} else {
+					samples[c][j] = 0
+				}
+			}
+		}
+		if err := l.Write(samples); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Demux the best audio stream, and return a copy of the packet payloads
+func readPackets(t *testing.T, r *ffmpeg.Reader, fn func(*ffmpeg.Packet) error) [][]byte {
+	var result [][]byte
+	audio := r.BestStream(media.AUDIO)
+	assert.NoError(t, r.Demux(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if stream == audio {
+			return par, nil
+		}
+		return nil, nil
+	}, func(_ int, packet *ffmpeg.Packet) error {
+		result = append(result, bytes.Clone(packet.Bytes()))
+		if fn != nil {
+			return fn(packet)
+		}
+		return nil
+	}))
+	return result
+}
+
+func Test_loudness_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// EBU Tech 3341 test case: stereo 1kHz sine at -23 dBFS is -23 LUFS
+	for _, samplerate := range []int{44100, 48000} {
+		l, err := analysis.NewLoudness("stereo", samplerate)
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		assert.True(math.IsInf(l.Integrated(), -1))
+		assert.True(math.IsInf(l.Momentary(), -1))
+		assert.Nil(l.Metadata())
+
+		assert.NoError(writeSine(l, []bool{true, true}, 1000, -23, samplerate, 20))
+		assert.InDelta(-23, l.Integrated(), 0.1)
+		assert.InDelta(-23, l.Momentary(), 0.1)
+		assert.InDelta(-23, l.ShortTerm(), 0.1)
+		assert.InDelta(-23, l.MaxMomentary(), 0.1)
+		assert.InDelta(-23, l.MaxShortTerm(), 0.1)
+		assert.InDelta(0, l.Range(), 0.1)
+		assert.InDelta(-23, l.TruePeak(), 0.2)
+		assert.InDelta(-23, l.SamplePeak(), 0.2)
+		t.Log(l)
+
+		// Tags
+		tags := make(map[string]string)
+		for _, meta := range l.Metadata() {
+			tags[meta.Key()] = meta.Value()
+		}
+		if gain, err := strconv.ParseFloat(strings.TrimSuffix(tags[analysis.MetaReplayGainTrackGain], " dB"), 64); assert.NoError(err) {
+			assert.InDelta(5, gain, 0.1)
+		}
+		if peak, err := strconv.ParseFloat(tags[analysis.MetaReplayGainTrackPeak], 64); assert.NoError(err) {
+			assert.InDelta(math.Pow(10, -23.0/20), peak, 0.01)
+		}
+		if gain, err := strconv.Atoi(tags[analysis.MetaR128TrackGain]); assert.NoError(err) {
+			assert.InDelta(0, gain, 26)
+		}
+	}
+}
+
+func Test_loudness_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// The LFE channel is not measured, and surround channels are weighted
+	l, err := analysis.NewLoudness("5.1", 48000)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(writeSine(l, []bool{false, false, false, true, false, false}, 50, -10, 48000, 5))
+	assert.True(math.IsInf(l.Integrated(), -1))
+
+	l, err = analysis.NewLoudness("5.1", 48000)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(writeSine(l, []bool{true, false, false, false, false, false}, 1000, -20, 48000, 5))
+	front := l.Integrated()
+	l, err = analysis.NewLoudness("5.1", 48000)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(writeSine(l, []bool{false, false, false, false, true, false}, 1000, -20, 48000, 5))
+	assert.InDelta(1.5, l.Integrated()-front, 0.05)
+
+	// Loudness range of a step between two levels
+	l, err = analysis.NewLoudness("mono", 48000)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(writeSine(l, []bool{true}, 1000, -30, 48000, 20))
+	assert.NoError(writeSine(l, []bool{true}, 1000, -20, 48000, 20))
+	assert.InDelta(10, l.Range(), 0.5)
+
+	// Invalid parameters
+	_, err = analysis.NewLoudness("nonexistent", 48000)
+	assert.Error(err)
+	_, err = analysis.NewLoudness("stereo", 0)
+	assert.Error(err)
+	assert.Error(l.Write([][]float32{{0}, {0}}))
+}
+
+func Test_loudness_003(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := ffmpeg.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	l, err := analysis.ReadLoudness(context.Background(), r)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.False(math.IsInf(l.Integrated(), -1))
+	t.Log(l)
+
+	// Write the tags when remuxing, copying the audio stream
+	tmp, err := os.MkdirTemp("", t.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "sample.mp3")
+	audio := r.BestStream(media.AUDIO)
+	writer, err := ffmpeg.Create(filename,
+		ffmpeg.OptStreamCopy(1, r.Par(audio)),
+		ffmpeg.OptMetadata(l.Metadata()...),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Remux the packets, keeping a copy of the payloads
+	assert.NoError(r.Seek(0, -1, ffmpeg.SEEK_BACKWARD))
+	packets := readPackets(t, r, func(packet *ffmpeg.Packet) error {
+		return writer.WritePacket(1, packet)
+	})
+	assert.NoError(writer.Close())
+	assert.NotEmpty(packets)
+
+	// Read the tags back
+	r2, err := ffmpeg.Open(filename)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+	for _, entry := range l.Metadata() {
+		if tag := r2.Metadata(entry.Key()); assert.Len(tag, 1, entry.Key()) {
+			assert.Equal(entry.Value(), tag[0].Value())
+		}
+	}
+
+	// The audio is not re-encoded
+	assert.Equal(packets, readPackets(t, r2, nil))
+}
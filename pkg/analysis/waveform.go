@@ -0,0 +1,325 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"math"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Waveform contains the peaks for each block of samples in each channel,
+// which can be serialized to JSON in the audiowaveform data format, or
+// rendered as an image
+type Waveform struct {
+	SampleRate      int      // Sample rate of the audio
+	SamplesPerPixel int      // Number of samples in each block
+	Bits            int      // Resolution of the JSON data, 8 or 16
+	Peaks           [][]Peak // Peaks for each channel
+
+	acc []peak // Current block for each channel
+	n   int    // Number of samples in the current block
+}
+
+// Peak is the minimum, maximum and RMS value of a block of samples, between
+// -1 and 1
+type Peak struct {
+	Min float32 `json:"min"`
+	Max float32 `json:"max"`
+	RMS float32 `json:"rms"`
+}
+
+type peak struct {
+	min, max float32
+	sum      float64 // Sum of squares
+}
+
+// audiowaveform data format, with interleaved min and max for each channel
+type jsonWaveform struct {
+	Version         int   `json:"version"`
+	Channels        int   `json:"channels"`
+	SampleRate      int   `json:"sample_rate"`
+	SamplesPerPixel int   `json:"samples_per_pixel"`
+	Bits            int   `json:"bits"`
+	Length          int   `json:"length"`
+	Data            []int `json:"data"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	waveformBits    = 16
+	waveformVersion = 2
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create an empty waveform for the number of channels and sample rate, with
+// a peak for every samplesPerPixel samples
+func NewWaveform(channels, samplerate, samplesPerPixel int) (*Waveform, error) {
+	if channels <= 0 {
+		return nil, ErrBadParameter.Withf("invalid number of channels %d", channels)
+	} else if samplerate <= 0 {
+		return nil, ErrBadParameter.Withf("invalid sample rate %d", samplerate)
+	} else if samplesPerPixel <= 0 {
+		return nil, ErrBadParameter.Withf("invalid samples per pixel %d", samplesPerPixel)
+	}
+	return &Waveform{
+		SampleRate:      samplerate,
+		SamplesPerPixel: samplesPerPixel,
+		Bits:            waveformBits,
+		Peaks:           make([][]Peak, channels),
+		acc:             make([]peak, channels),
+	}, nil
+}
+
+// Decode the best audio stream from the reader, resampled to the sample rate,
+// and return the waveform with a peak for every samplesPerPixel samples
+func ReadWaveform(ctx context.Context, r *ffmpeg.Reader, samplerate, samplesPerPixel int) (*Waveform, error) {
+	stream := r.BestStream(media.AUDIO)
+	if stream < 0 {
+		return nil, ErrNotFound.With("no audio stream")
+	}
+
+	// Resample to planar float32 with the same channel layout
+	ch := r.Par(stream).ChannelLayout()
+	layout, err := ff.AVUtil_channel_layout_describe(&ch)
+	if err != nil {
+		return nil, err
+	}
+	waveform, err := NewWaveform(ch.NumChannels(), samplerate, samplesPerPixel)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the frames
+	samples := make([][]float32, ch.NumChannels())
+	if err := r.Decode(ctx, func(s int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if s == stream {
+			return ffmpeg.NewAudioPar("fltp", layout, samplerate)
+		}
+		return nil, nil
+	}, func(_ int, frame *ffmpeg.Frame) error {
+		for c := range samples {
+			samples[c] = frame.Float32(c)
+		}
+		return waveform.Write(samples)
+	}); err != nil {
+		return nil, err
+	}
+
+	// Add the last partial block
+	waveform.Flush()
+
+	// Return success
+	return waveform, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+// Return the waveform in the audiowaveform JSON data format, with the
+// minimum and maximum for each channel interleaved
+func (w *Waveform) MarshalJSON() ([]byte, error) {
+	var scale float32
+	switch w.Bits {
+	case 8:
+		scale = math.MaxInt8
+	case 16:
+		scale = math.MaxInt16
+	default:
+		return nil, ErrBadParameter.Withf("invalid bits %d", w.Bits)
+	}
+
+	// Interleave the peaks
+	length := w.Len()
+	data := make([]int, 0, length*len(w.Peaks)*2)
+	for i := 0; i < length; i++ {
+		for _, peaks := range w.Peaks {
+			data = append(data, quantize(peaks[i].Min, scale), quantize(peaks[i].Max, scale))
+		}
+	}
+
+	return json.Marshal(jsonWaveform{
+		Version:         waveformVersion,
+		Channels:        len(w.Peaks),
+		SampleRate:      w.SampleRate,
+		SamplesPerPixel: w.SamplesPerPixel,
+		Bits:            w.Bits,
+		Length:          length,
+		Data:            data,
+	})
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the number of peaks in each channel
+func (w *Waveform) Len() int {
+	if len(w.Peaks) == 0 {
+		return 0
+	}
+	return len(w.Peaks[0])
+}
+
+// Add planar samples for each channel, between -1 and 1. Every channel
+// should have the same number of samples.
+func (w *Waveform) Write(samples [][]float32) error {
+	if len(samples) != len(w.Peaks) {
+		return ErrBadParameter.Withf("expected %d channels, got %d", len(w.Peaks), len(samples))
+	}
+	n := len(samples[0])
+	for _, s := range samples[1:] {
+		if len(s) != n {
+			return ErrBadParameter.With("channels have different numbers of samples")
+		}
+	}
+
+	// Add samples to the current block, and complete the block when full
+	for i := 0; i < n; {
+		j := min(n, i+w.SamplesPerPixel-w.n)
+		for c, s := range samples {
+			w.acc[c] = w.acc[c].add(s[i:j], w.n == 0)
+		}
+		w.n += j - i
+		i = j
+		if w.n == w.SamplesPerPixel {
+			w.Flush()
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Complete the current block, if it contains any samples
+func (w *Waveform) Flush() {
+	if w.n == 0 {
+		return
+	}
+	for c, acc := range w.acc {
+		w.Peaks[c] = append(w.Peaks[c], Peak{
+			Min: acc.min,
+			Max: acc.max,
+			RMS: float32(math.Sqrt(acc.sum / float64(w.n))),
+		})
+	}
+	w.n = 0
+}
+
+// Render the waveform as an image, with each channel in a separate lane. The
+// RMS values are drawn in the foreground color, and the peaks in a color
+// halfway between the background and foreground.
+func (w *Waveform) Image(width, height int, bg, fg color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	peak := blend(bg, fg)
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+
+	// Draw each channel
+	length := w.Len()
+	if length == 0 || width <= 0 || len(w.Peaks) == 0 {
+		return img
+	}
+	lane := height / len(w.Peaks)
+	for c, peaks := range w.Peaks {
+		mid := c*lane + lane/2
+		scale := float32(lane) / 2
+		for x := 0; x < width; x++ {
+			// Combine the peaks for the column
+			i, j := x*length/width, max((x+1)*length/width, x*length/width+1)
+			p := combine(peaks[i:min(j, length)])
+
+			// Draw the peak and RMS values
+			vline(img, x, mid-int(clamp(p.Max)*scale), mid-int(clamp(p.Min)*scale), lane, c*lane, peak)
+			vline(img, x, mid-int(clamp(p.RMS)*scale), mid+int(clamp(p.RMS)*scale), lane, c*lane, fg)
+		}
+	}
+
+	// Return the image
+	return img
+}
+
+// Render the waveform as a PNG image
+func (w *Waveform) WritePNG(dest io.Writer, width, height int, bg, fg color.Color) error {
+	if width <= 0 || height <= 0 {
+		return ErrBadParameter.Withf("invalid size %dx%d", width, height)
+	}
+	return png.Encode(dest, w.Image(width, height, bg, fg))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add samples to a block, starting a new block if first is true
+func (p peak) add(samples []float32, first bool) peak {
+	if len(samples) == 0 {
+		return p
+	} else if first {
+		p = peak{min: samples[0], max: samples[0]}
+	}
+	for _, v := range samples {
+		p.min = min(p.min, v)
+		p.max = max(p.max, v)
+		p.sum += float64(v) * float64(v)
+	}
+	return p
+}
+
+// Combine peaks into one peak
+func combine(peaks []Peak) Peak {
+	result := peaks[0]
+	var sum float64
+	for _, p := range peaks {
+		result.Min = min(result.Min, p.Min)
+		result.Max = max(result.Max, p.Max)
+		sum += float64(p.RMS) * float64(p.RMS)
+	}
+	result.RMS = float32(math.Sqrt(sum / float64(len(peaks))))
+	return result
+}
+
+// Draw a vertical line between y0 and y1, within a lane
+func vline(img *image.RGBA, x, y0, y1, lane, top int, c color.Color) {
+	y0, y1 = max(min(y0, y1), top), min(max(y0, y1), top+lane-1)
+	for y := y0; y <= y1; y++ {
+		img.Set(x, y, c)
+	}
+}
+
+// Return a color halfway between two colors
+func blend(a, b color.Color) color.Color {
+	r0, g0, b0, a0 := a.RGBA()
+	r1, g1, b1, a1 := b.RGBA()
+	return color.RGBA64{
+		R: uint16((r0 + r1) / 2),
+		G: uint16((g0 + g1) / 2),
+		B: uint16((b0 + b1) / 2),
+		A: uint16((a0 + a1) / 2),
+	}
+}
+
+// Clamp a value between -1 and 1
+func clamp(v float32) float32 {
+	return max(-1, min(1, v))
+}
+
+// Quantize a value between -1 and 1
+func quantize(v, scale float32) int {
+	return int(math.Round(float64(clamp(v) * scale)))
+}
@@ -0,0 +1,114 @@
+package analysis_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+
+	// Packages
+	analysis "github.com/mutablelogic/go-media/pkg/analysis"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_waveform_001(t *testing.T) {
+	assert := assert.New(t)
+
+	waveform, err := analysis.NewWaveform(2, 1000, 100)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// A constant on the left, and a full-scale sine on the right, written in
+	// chunks which do not align with the blocks
+	left, right := make([]float32, 250), make([]float32, 250)
+	for i := 0; i < 1000; i += len(left) {
+		for j := range left {
+			left[j] = 0.5
+			right[j] = float32(math.Sin(2 * math.Pi * float64(i+j) / 100))
+		}
+		assert.NoError(waveform.Write([][]float32{left, right}))
+	}
+	assert.NoError(waveform.Write([][]float32{left[:50], right[:50]}))
+	waveform.Flush()
+
+	assert.Equal(11, waveform.Len())
+	for _, p := range waveform.Peaks[0] {
+		assert.Equal(analysis.Peak{Min: 0.5, Max: 0.5, RMS: 0.5}, p)
+	}
+	for _, p := range waveform.Peaks[1][:10] {
+		assert.InDelta(-1, p.Min, 0.01)
+		assert.InDelta(1, p.Max, 0.01)
+		assert.InDelta(math.Sqrt2/2, p.RMS, 0.01)
+	}
+
+	// Mismatched channels
+	assert.Error(waveform.Write([][]float32{left}))
+	assert.Error(waveform.Write([][]float32{left, right[:10]}))
+}
+
+func Test_waveform_002(t *testing.T) {
+	assert := assert.New(t)
+
+	waveform, err := analysis.NewWaveform(2, 1000, 100)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(waveform.Write([][]float32{{-1, 0.5}, {0, 1}}))
+	waveform.Flush()
+
+	// JSON in the audiowaveform format
+	data, err := json.Marshal(waveform)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	var v map[string]any
+	assert.NoError(json.Unmarshal(data, &v))
+	assert.EqualValues(2, v["version"])
+	assert.EqualValues(2, v["channels"])
+	assert.EqualValues(1000, v["sample_rate"])
+	assert.EqualValues(100, v["samples_per_pixel"])
+	assert.EqualValues(16, v["bits"])
+	assert.EqualValues(1, v["length"])
+	assert.Equal([]any{-32767.0, 16384.0, 0.0, 32767.0}, v["data"])
+
+	waveform.Bits = 8
+	data, err = json.Marshal(waveform)
+	assert.NoError(err)
+	assert.Contains(string(data), `"data":[-127,64,0,127]`)
+
+	waveform.Bits = 12
+	_, err = json.Marshal(waveform)
+	assert.Error(err)
+}
+
+func Test_waveform_003(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := ffmpeg.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	waveform, err := analysis.ReadWaveform(context.Background(), r, 22050, 512)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NotZero(waveform.Len())
+	assert.InDelta(r.Duration().Seconds()*22050/512, waveform.Len(), 100)
+
+	// Render as PNG
+	var buf bytes.Buffer
+	assert.NoError(waveform.WritePNG(&buf, 800, 200, color.White, color.Black))
+	img, err := png.Decode(&buf)
+	if assert.NoError(err) {
+		assert.Equal(800, img.Bounds().Dx())
+		assert.Equal(200, img.Bounds().Dy())
+	}
+	assert.Error(waveform.WritePNG(&buf, 0, 200, color.White, color.Black))
+}
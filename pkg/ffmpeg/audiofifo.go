@@ -0,0 +1,182 @@
+package ffmpeg
+
+import (
+	"errors"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// audioFifo buffers audio samples, and returns frames with a fixed number of
+// samples for encoders which require a fixed frame size
+type audioFifo struct {
+	fifo  *ff.AVAudioFifo
+	dest  *ff.AVFrame
+	pool  *FramePool
+	size  int   // Number of samples in each frame
+	small bool  // The last frame can be smaller than the frame size
+	pts   int64 // Timestamp of the first sample in the fifo
+	tb    ff.AVRational
+}
+
+// audioFifoFn is called for each frame read from the fifo
+type audioFifoFn func(*Frame) error
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a fifo for an encoder, or return nil if the encoder accepts any
+// number of samples in each frame
+func newAudioFifo(ctx *ff.AVCodecContext) (*audioFifo, error) {
+	codec := ctx.Codec()
+	if codec.Type() != ff.AVMEDIA_TYPE_AUDIO || ctx.FrameSize() <= 0 || codec.Capabilities().Is(ff.AV_CODEC_CAP_VARIABLE_FRAME_SIZE) {
+		return nil, nil
+	}
+
+	// Create the fifo
+	fifo := new(audioFifo)
+	fifo.pool = NewFramePool()
+	fifo.size = ctx.FrameSize()
+	fifo.small = codec.Capabilities().Is(ff.AV_CODEC_CAP_SMALL_LAST_FRAME)
+	fifo.pts = ff.AV_NOPTS_VALUE
+	fifo.tb = ctx.TimeBase()
+	ch := ctx.ChannelLayout()
+	if f := ff.AVUtil_audio_fifo_alloc(ctx.SampleFormat(), ch.NumChannels(), fifo.size); f == nil {
+		return nil, ErrInternalAppError.With("failed to allocate audio fifo")
+	} else {
+		fifo.fifo = f
+	}
+
+	// Create the destination frame
+	if dest := ff.AVUtil_frame_alloc(); dest == nil {
+		return nil, errors.Join(ErrInternalAppError.With("failed to allocate frame"), fifo.Close())
+	} else {
+		fifo.dest = dest
+	}
+	fifo.dest.SetSampleFormat(ctx.SampleFormat())
+	fifo.dest.SetSampleRate(ctx.SampleRate())
+	fifo.dest.SetNumSamples(fifo.size)
+	fifo.dest.SetTimeBase(ctx.TimeBase())
+	if err := fifo.dest.SetChannelLayout(ch); err != nil {
+		return nil, errors.Join(err, fifo.Close())
+	} else if err := fifo.pool.makeWritable((*Frame)(fifo.dest)); err != nil {
+		return nil, errors.Join(err, fifo.Close())
+	}
+
+	// Return success
+	return fifo, nil
+}
+
+// Release resources
+func (f *audioFifo) Close() error {
+	if f.fifo != nil {
+		ff.AVUtil_audio_fifo_free(f.fifo)
+	}
+	if f.dest != nil {
+		ff.AVUtil_frame_free(f.dest)
+	}
+	if f.pool != nil {
+		f.pool.Close()
+	}
+	f.fifo = nil
+	f.dest = nil
+	f.pool = nil
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Write a frame to the fifo, with the timestamp in the encoder timebase, and
+// call the audioFifoFn for each complete frame. If the frame is nil, then the
+// remaining samples are returned, padded with silence unless the encoder
+// accepts a smaller last frame.
+func (f *audioFifo) Frame(src *Frame, fn audioFifoFn) error {
+	if src == nil {
+		return f.flush(fn)
+	}
+
+	// Check the frame parameters
+	frame := (*ff.AVFrame)(src)
+	if frame.SampleFormat() != f.dest.SampleFormat() {
+		return ErrBadParameter.Withf("sample format %v does not match encoder %v", frame.SampleFormat(), f.dest.SampleFormat())
+	} else if frame.ChannelLayout().NumChannels() != f.dest.ChannelLayout().NumChannels() {
+		return ErrBadParameter.Withf("channels %v do not match encoder %v", frame.ChannelLayout().NumChannels(), f.dest.ChannelLayout().NumChannels())
+	}
+
+	// The timestamp of the fifo is set from the frame when the fifo is empty,
+	// otherwise it follows on from the samples already buffered
+	if size := ff.AVUtil_audio_fifo_size(f.fifo); frame.Pts() != ff.AV_NOPTS_VALUE && (size == 0 || f.pts == ff.AV_NOPTS_VALUE) {
+		f.pts = frame.Pts() - f.duration(size)
+	} else if f.pts == ff.AV_NOPTS_VALUE {
+		f.pts = 0
+	}
+
+	// Write the samples
+	if _, err := ff.AVUtil_audio_fifo_write(f.fifo, frame); err != nil {
+		return err
+	}
+
+	// Read complete frames
+	for ff.AVUtil_audio_fifo_size(f.fifo) >= f.size {
+		if err := f.read(f.size, fn); err != nil {
+			return err
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the remaining samples, then reset the fifo
+func (f *audioFifo) flush(fn audioFifoFn) error {
+	defer f.reset()
+	if size := ff.AVUtil_audio_fifo_size(f.fifo); size > 0 {
+		return f.read(size, fn)
+	}
+	return nil
+}
+
+// Read samples into the destination frame, and call the audioFifoFn. A partial
+// frame is padded with silence unless the encoder accepts a smaller last frame.
+func (f *audioFifo) read(size int, fn audioFifoFn) error {
+	// The encoder may hold a reference to the previous frame
+	f.dest.SetNumSamples(f.size)
+	if err := f.pool.makeWritable((*Frame)(f.dest)); err != nil {
+		return err
+	}
+
+	// Read the samples
+	n, err := ff.AVUtil_audio_fifo_read(f.fifo, f.dest, size)
+	if err != nil {
+		return err
+	}
+	if n < f.size && !f.small {
+		ff.AVUtil_frame_set_silence(f.dest, n, f.size-n)
+		f.dest.SetNumSamples(f.size)
+	}
+
+	// Set the timestamp
+	f.dest.SetPts(f.pts)
+	f.pts += f.duration(n)
+
+	// Pass the frame to the encoder
+	return fn((*Frame)(f.dest))
+}
+
+// Return the duration of a number of samples in the encoder timebase
+func (f *audioFifo) duration(n int) int64 {
+	return ff.AVUtil_rational_rescale_q(int64(n), ff.AVUtil_rational(1, f.dest.SampleRate()), f.tb)
+}
+
+// Discard any samples in the fifo
+func (f *audioFifo) reset() {
+	ff.AVUtil_audio_fifo_reset(f.fifo)
+	f.pts = ff.AV_NOPTS_VALUE
+}
@@ -0,0 +1,168 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// BitstreamFilter transforms packets without decoding them, for example
+// "h264_mp4toannexb" when remuxing from MP4 to MPEG-TS, or "aac_adtstoasc"
+// when remuxing from MPEG-TS to MP4. Filters can be chained by separating
+// them with commas.
+type BitstreamFilter struct {
+	ctx *ff.AVBSFContext
+	in  *ff.AVPacket
+	out *ff.AVPacket
+}
+
+// BitstreamFilterFn is called for each filtered packet. The packet is only
+// valid for the duration of the call. Return io.EOF to end filtering early.
+type BitstreamFilterFn func(*Packet) error
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a new bitstream filter from a filter description, with the codec
+// parameters and timebase of the input stream (for example, from Reader.Par)
+func NewBitstreamFilter(desc string, par *Par) (*BitstreamFilter, error) {
+	filter := new(BitstreamFilter)
+
+	// Check parameters
+	if desc = strings.TrimSpace(desc); desc == "" {
+		return nil, ErrBadParameter.With("empty bitstream filter description")
+	} else if par == nil {
+		return nil, ErrBadParameter.With("invalid parameters")
+	}
+
+	// Allocate the filter
+	if ctx, err := ff.AVCodec_bsf_list_parse_str(desc); err != nil {
+		return nil, ErrBadParameter.Withf("bitstream filter %q: %v", desc, err)
+	} else {
+		filter.ctx = ctx
+	}
+
+	// Set the input parameters and initialize
+	if err := ff.AVCodec_parameters_copy(filter.ctx.ParIn(), &par.AVCodecParameters); err != nil {
+		return nil, errors.Join(err, filter.Close())
+	} else {
+		filter.ctx.SetTimeBaseIn(par.timebase)
+	}
+	if err := ff.AVCodec_bsf_init(filter.ctx); err != nil {
+		return nil, errors.Join(ErrBadParameter.Withf("bitstream filter %q: %v", desc, err), filter.Close())
+	}
+
+	// Allocate packets
+	filter.in = ff.AVCodec_packet_alloc()
+	filter.out = ff.AVCodec_packet_alloc()
+	if filter.in == nil || filter.out == nil {
+		return nil, errors.Join(errors.New("failed to allocate packet"), filter.Close())
+	}
+
+	// Return success
+	return filter, nil
+}
+
+// Release resources
+func (f *BitstreamFilter) Close() error {
+	if f.ctx != nil {
+		ff.AVCodec_bsf_free(f.ctx)
+	}
+	if f.in != nil {
+		ff.AVCodec_packet_free(f.in)
+	}
+	if f.out != nil {
+		ff.AVCodec_packet_free(f.out)
+	}
+	f.ctx = nil
+	f.in = nil
+	f.out = nil
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f *BitstreamFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.ctx)
+}
+
+func (f *BitstreamFilter) String() string {
+	data, _ := json.MarshalIndent(f, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the codec parameters and timebase of the filtered packets, which
+// should be used for the output stream
+func (f *BitstreamFilter) Par() *Par {
+	return &Par{
+		AVCodecParameters: *f.ctx.ParOut(),
+		timebase:          f.ctx.TimeBaseOut(),
+	}
+}
+
+// Filter a packet, and call the BitstreamFilterFn for each filtered packet.
+// The source packet is not modified. If the source packet is nil, then the
+// filter is flushed, after which it can be used again.
+func (f *BitstreamFilter) Packet(src *Packet, fn BitstreamFilterFn) error {
+	if fn == nil {
+		return ErrBadParameter.With("BitstreamFilterFn is nil")
+	}
+
+	// Send a new reference to the packet to the filter, which takes
+	// ownership of the reference
+	var in *ff.AVPacket
+	if src != nil {
+		if err := ff.AVCodec_packet_ref(f.in, (*ff.AVPacket)(src)); err != nil {
+			return err
+		} else {
+			in = f.in
+		}
+	}
+	if err := ff.AVCodec_bsf_send_packet(f.ctx, in); err != nil {
+		ff.AVCodec_packet_unref(f.in)
+		return ErrInternalAppError.With("AVCodec_bsf_send_packet:", err)
+	}
+
+	// Receive the filtered packets
+	var result error
+	for {
+		if err := ff.AVCodec_bsf_receive_packet(f.ctx, f.out); errors.Is(err, syscall.EAGAIN) {
+			break
+		} else if errors.Is(err, io.EOF) {
+			// Flushed, so reset the filter for the next packet
+			ff.AVCodec_bsf_flush(f.ctx)
+			break
+		} else if err != nil {
+			return ErrInternalAppError.With("AVCodec_bsf_receive_packet:", err)
+		}
+
+		// Pass back to the caller
+		f.out.SetTimeBase(f.ctx.TimeBaseOut())
+		err := fn((*Packet)(f.out))
+		ff.AVCodec_packet_unref(f.out)
+		if errors.Is(err, io.EOF) {
+			result = io.EOF
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Return success or EOF
+	return result
+}
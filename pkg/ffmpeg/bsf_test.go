@@ -0,0 +1,70 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"testing"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_bsf_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Create a filter for the video stream
+	video := r.BestStream(media.VIDEO)
+	filter, err := ffmpeg.NewBitstreamFilter("h264_mp4toannexb", r.Par(video))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer filter.Close()
+	assert.Equal(r.Par(video).CodecID(), filter.Par().CodecID())
+	t.Log(filter)
+
+	// Filter the packets
+	var in, out int
+	fn := func(packet *ffmpeg.Packet) error {
+		out++
+		return nil
+	}
+	assert.NoError(r.Demux(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if stream == video {
+			return par, nil
+		}
+		return nil, nil
+	}, func(stream int, packet *ffmpeg.Packet) error {
+		in++
+		return filter.Packet(packet, fn)
+	}))
+	assert.NoError(filter.Packet(nil, fn))
+	assert.NotZero(in)
+	assert.Equal(in, out)
+}
+
+func Test_bsf_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Invalid filters
+	_, err = ffmpeg.NewBitstreamFilter("", r.Par(0))
+	assert.Error(err)
+	_, err = ffmpeg.NewBitstreamFilter("nonexistent", r.Par(0))
+	assert.Error(err)
+	_, err = ffmpeg.NewBitstreamFilter("null", nil)
+	assert.Error(err)
+}
@@ -0,0 +1,139 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type chapter struct {
+	Start    time.Duration `json:"start"`
+	End      time.Duration `json:"end"`
+	Metadata []*Metadata   `json:"metadata,omitempty"`
+}
+
+// Chapter is a marker in the media with a start and end time, and
+// metadata such as the chapter title
+type Chapter struct {
+	chapter
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a new chapter with start and end time, and optional metadata
+func NewChapter(start, end time.Duration, metadata ...*Metadata) *Chapter {
+	return &Chapter{
+		chapter: chapter{
+			Start:    start,
+			End:      end,
+			Metadata: metadata,
+		},
+	}
+}
+
+// Create a chapter from an AVChapter
+func newChapter(ctx *ff.AVChapter) *Chapter {
+	tb := ctx.TimeBase()
+	entries := ff.AVUtil_dict_entries(ctx.Metadata())
+	metadata := make([]*Metadata, 0, len(entries))
+	for _, entry := range entries {
+		metadata = append(metadata, NewMetadata(entry.Key(), entry.Value()))
+	}
+	return NewChapter(tsDuration(ctx.Start(), tb), tsDuration(ctx.End(), tb), metadata...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (c *Chapter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.chapter)
+}
+
+func (c *Chapter) String() string {
+	data, _ := json.MarshalIndent(c, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the start time of the chapter
+func (c *Chapter) Start() time.Duration {
+	return c.chapter.Start
+}
+
+// Return the end time of the chapter
+func (c *Chapter) End() time.Duration {
+	return c.chapter.End
+}
+
+// Return the chapter title, or an empty string
+func (c *Chapter) Title() string {
+	for _, entry := range c.chapter.Metadata {
+		if entry.Key() == MetaTitle {
+			return entry.Value()
+		}
+	}
+	return ""
+}
+
+// Return the metadata for the chapter, filtering by the specified keys
+// if there are any
+func (c *Chapter) Metadata(keys ...string) []*Metadata {
+	result := make([]*Metadata, 0, len(c.chapter.Metadata))
+	for _, entry := range c.chapter.Metadata {
+		if len(keys) == 0 || slices.Contains(keys, entry.Key()) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add a chapter to the output context, with a timebase of microseconds
+func (c *Chapter) write(ctx *ff.AVFormatContext, id int64) error {
+	tb := ff.AVUtil_rational(1, ff.AV_TIME_BASE)
+	start := ff.AVUtil_rational_rescale_q(int64(c.chapter.Start), ff.AVUtil_rational(1, int(time.Second)), tb)
+	end := ff.AVUtil_rational_rescale_q(int64(c.chapter.End), ff.AVUtil_rational(1, int(time.Second)), tb)
+
+	// Set the chapter metadata, ignoring artwork
+	metadata := ff.AVUtil_dict_alloc()
+	for _, entry := range c.chapter.Metadata {
+		if entry.Key() == MetaArtwork || entry.Key() == "" {
+			continue
+		}
+		if err := ff.AVUtil_dict_set(metadata, entry.Key(), entry.Value(), ff.AV_DICT_APPEND); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return err
+		}
+	}
+
+	// Create the chapter, which takes ownership of the metadata
+	chapter := ff.AVFormat_new_chapter(ctx, id, tb, start, end)
+	if chapter == nil {
+		ff.AVUtil_dict_free(metadata)
+		return ErrInternalAppError.With("could not allocate chapter")
+	} else {
+		chapter.SetMetadata(metadata)
+	}
+
+	// Return success
+	return nil
+}
+
+// Convert a timestamp in a timebase to a duration
+func tsDuration(ts int64, tb ff.AVRational) time.Duration {
+	return time.Duration(ff.AVUtil_rational_rescale_q(ts, tb, ff.AVUtil_rational(1, int(time.Second))))
+}
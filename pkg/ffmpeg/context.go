@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"syscall"
+	"time"
 
 	// Packages
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
@@ -78,7 +79,7 @@ func (decoder *Context) C(stream int) chan *Frame {
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
-func (decoder *Context) decode(ctx context.Context, fn DecoderFrameFn) error {
+func (decoder *Context) decode(ctx context.Context, fn DecoderFrameFn, subtitlefn DecoderSubtitleFn) error {
 	// Allocate a packet
 	packet := ff.AVCodec_packet_alloc()
 	if packet == nil {
@@ -101,7 +102,13 @@ FOR_LOOP:
 				return ErrInternalAppError.With("AVFormat_read_frame: ", err)
 			}
 			stream_index := packet.StreamIndex()
-			if decoder := decoder.decoders[stream_index]; decoder != nil {
+			if decoder := decoder.decoders[stream_index]; decoder != nil && decoder.isSubtitle() {
+				if err := decoder.decodeSubtitle(packet, subtitlefn); errors.Is(err, io.EOF) {
+					break FOR_LOOP
+				} else if err != nil {
+					return err
+				}
+			} else if decoder != nil {
 				if err := decoder.decode(packet, fn); errors.Is(err, io.EOF) {
 					break FOR_LOOP
 				} else if err != nil {
@@ -116,7 +123,9 @@ FOR_LOOP:
 
 	// Flush the decoders
 	for _, decoder := range decoder.decoders {
-		if err := decoder.decode(nil, fn); errors.Is(err, io.EOF) {
+		if decoder.isSubtitle() {
+			continue
+		} else if err := decoder.decode(nil, fn); errors.Is(err, io.EOF) {
 			// no-op
 		} else if err != nil {
 			return err
@@ -128,6 +137,18 @@ FOR_LOOP:
 	return ctx.Err()
 }
 
+// Flush the decoders after seeking. If accurate is true, then the decoders
+// discard frames which end before the timestamp.
+func (c *Context) flush(ts time.Duration, accurate bool) {
+	for _, decoder := range c.decoders {
+		pts := int64(ff.AV_NOPTS_VALUE)
+		if accurate {
+			pts = ff.AVUtil_rational_rescale_q(int64(ts), ff.AVUtil_rational(1, int(time.Second)), decoder.timeBase)
+		}
+		decoder.flush(pts)
+	}
+}
+
 // Map streams to decoders, and return the decoders
 func (c *Context) mapStreams(fn DecoderMapFunc, force bool) error {
 	// Standard decoder map function copies all streams
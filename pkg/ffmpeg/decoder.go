@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"syscall"
+	"time"
 
 	// Packages
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
@@ -21,8 +22,12 @@ type Decoder struct {
 	codec    *ff.AVCodecContext
 	par      *Par          // Destination parameters
 	re       *Re           // Resample/resize
+	filter   *Filter       // Filter graph, applied before resample/resize
 	timeBase ff.AVRational // Timebase for the stream
 	frame    *ff.AVFrame   // Destination frame
+	pool     *FramePool    // Buffers for decoded frames
+	seek     int64         // Discard frames before this timestamp after seeking
+	next     int64         // Timestamp for the next frame, for streams without timestamps
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -30,10 +35,22 @@ type Decoder struct {
 
 // Create a stream decoder which can decode packets from the input stream
 func NewDecoder(stream *ff.AVStream, dest *Par, force bool) (*Decoder, error) {
+	codec := ff.AVCodec_find_decoder(stream.CodecPar().CodecID())
+	if codec == nil {
+		return nil, fmt.Errorf("failed to find decoder for codec %q", stream.CodecPar().CodecID())
+	}
+	return newDecoder(stream.Index(), codec, stream.CodecPar(), stream.TimeBase(), dest, force)
+}
+
+// Create a decoder for a codec. The codec parameters are copied to the
+// codec context, if not nil.
+func newDecoder(index int, codec *ff.AVCodec, par *ff.AVCodecParameters, timeBase ff.AVRational, dest *Par, force bool) (*Decoder, error) {
 	decoder := new(Decoder)
-	decoder.stream = stream.Index()
+	decoder.stream = index
 	decoder.par = dest
-	decoder.timeBase = stream.TimeBase()
+	decoder.timeBase = timeBase
+	decoder.seek = ff.AV_NOPTS_VALUE
+	decoder.next = ff.AV_NOPTS_VALUE
 
 	// Create a frame for decoder output - before resize/resample
 	frame := ff.AVUtil_frame_alloc()
@@ -42,11 +59,7 @@ func NewDecoder(stream *ff.AVStream, dest *Par, force bool) (*Decoder, error) {
 	}
 
 	// Create a codec context for the decoder
-	codec := ff.AVCodec_find_decoder(stream.CodecPar().CodecID())
-	if codec == nil {
-		ff.AVUtil_frame_free(frame)
-		return nil, fmt.Errorf("failed to find decoder for codec %q", stream.CodecPar().CodecID())
-	} else if ctx := ff.AVCodec_alloc_context(codec); ctx == nil {
+	if ctx := ff.AVCodec_alloc_context(codec); ctx == nil {
 		ff.AVUtil_frame_free(frame)
 		return nil, fmt.Errorf("failed to allocate codec context for codec %q", codec.Name())
 	} else {
@@ -54,18 +67,35 @@ func NewDecoder(stream *ff.AVStream, dest *Par, force bool) (*Decoder, error) {
 		decoder.frame = frame
 	}
 
-	// If the destination codec parameters are not nil, then create a resample/resizer
-	if dest != nil {
+	// If the destination codec parameters are not nil, then create a resample/resizer.
+	// Subtitles are not resampled or resized
+	if dest != nil && codec.Type() != ff.AVMEDIA_TYPE_SUBTITLE {
 		if re, err := NewRe(dest, force); err != nil {
 			return nil, errors.Join(err, decoder.Close())
 		} else {
 			decoder.re = re
 		}
+		if dest.filter != "" {
+			if filter, err := NewFilter(dest.filter); err != nil {
+				return nil, errors.Join(err, decoder.Close())
+			} else {
+				decoder.filter = filter
+			}
+		}
 	}
 
 	// Copy codec parameters from input stream to output codec context
-	if err := ff.AVCodec_parameters_to_context(decoder.codec, stream.CodecPar()); err != nil {
-		return nil, errors.Join(decoder.Close(), fmt.Errorf("failed to copy codec parameters to decoder context for codec %q", codec.Name()))
+	if par != nil {
+		if err := ff.AVCodec_parameters_to_context(decoder.codec, par); err != nil {
+			return nil, errors.Join(decoder.Close(), fmt.Errorf("failed to copy codec parameters to decoder context for codec %q", codec.Name()))
+		}
+	}
+	decoder.codec.SetPktTimeBase(decoder.timeBase)
+
+	// Allocate the buffers for decoded audio and video frames from a pool
+	if t := codec.Type(); t == ff.AVMEDIA_TYPE_AUDIO || t == ff.AVMEDIA_TYPE_VIDEO {
+		decoder.pool = NewFramePool()
+		ff.AVCodec_set_get_buffer(decoder.codec, decoder.pool.getCodecBuffer)
 	}
 
 	// Init the decoder
@@ -81,13 +111,19 @@ func NewDecoder(stream *ff.AVStream, dest *Par, force bool) (*Decoder, error) {
 func (d *Decoder) Close() error {
 	var result error
 
-	// Free resampler/resizer
+	// Free resampler/resizer and filter
 	if d.re != nil {
 		result = errors.Join(result, d.re.Close())
 	}
+	if d.filter != nil {
+		result = errors.Join(result, d.filter.Close())
+	}
 
 	// Free the codec context
 	if d.codec != nil {
+		if d.pool != nil {
+			ff.AVCodec_set_get_buffer(d.codec, nil)
+		}
 		ff.AVCodec_free_context(d.codec)
 	}
 
@@ -96,10 +132,17 @@ func (d *Decoder) Close() error {
 		ff.AVUtil_frame_free(d.frame)
 	}
 
+	// Free the frame pool, buffers still referenced are freed when released
+	if d.pool != nil {
+		result = errors.Join(result, d.pool.Close())
+	}
+
 	// Reset fields
 	d.re = nil
+	d.filter = nil
 	d.codec = nil
 	d.frame = nil
+	d.pool = nil
 
 	// Return any errors
 	return result
@@ -111,7 +154,6 @@ func (d *Decoder) Close() error {
 // Decode a packet into a set of frames to pass back to the
 // DecoderFrameFn. If the packet is nil, then the decoder will
 // flush any remaining frames.
-// TODO: Optionally use the user defined packet function if they want to use AVParser
 func (d *Decoder) decode(packet *ff.AVPacket, fn DecoderFrameFn) error {
 	if fn == nil {
 		return ErrBadParameter.With("DecoderFrameFn is nil")
@@ -129,7 +171,6 @@ func (d *Decoder) decode(packet *ff.AVPacket, fn DecoderFrameFn) error {
 
 	// get all the available frames from the decoder
 	var result error
-	var dest *Frame
 	for {
 		// End early if we've received an EOF
 		if result != nil {
@@ -144,40 +185,175 @@ func (d *Decoder) decode(packet *ff.AVPacket, fn DecoderFrameFn) error {
 			return ErrInternalAppError.With("AVCodec_receive_frame:", err)
 		}
 
-		// Set the timebase for the frame
-		d.frame.SetTimeBase(packet.TimeBase())
-
-		// Obtain the output frame. If a new frame is returned, it is
-		// managed by the rescaler/resizer and no need to unreference it
-		// later.
-		if d.re != nil {
-			if frame, err := d.re.Frame((*Frame)(d.frame)); err != nil {
-				result = errors.Join(result, err)
-			} else {
-				dest = frame
-			}
-		} else {
-			dest = (*Frame)(d.frame)
+		// Set the timebase for the frame, and the timestamp if the stream
+		// has no timestamps
+		d.frame.SetTimeBase(d.timeBase)
+		if d.next != ff.AV_NOPTS_VALUE {
+			d.timestamp(d.frame)
 		}
 
-		// If we have a nil frame here, then don't pass back to the caller
-		if dest == nil {
+		// Discard frames before the seek timestamp
+		if d.discard(d.frame) {
 			ff.AVUtil_frame_unref(d.frame)
 			continue
 		}
 
-		// Pass back to the caller
-		if err := fn(d.stream, dest); errors.Is(err, io.EOF) {
-			// End early, return EOF
+		// Filter, resample or resize the frame and pass back to the caller
+		var err error
+		if d.filter != nil {
+			err = d.filter.Frame((*Frame)(d.frame), func(frame *Frame) error {
+				return d.output(frame, fn)
+			})
+		} else {
+			err = d.output((*Frame)(d.frame), fn)
+		}
+
+		// Re-allocate frame for next iteration
+		ff.AVUtil_frame_unref(d.frame)
+
+		// End early, return EOF
+		if errors.Is(err, io.EOF) {
 			result = io.EOF
 		} else if err != nil {
 			return err
 		}
+	}
 
-		// Re-allocate frame for next iteration
-		ff.AVUtil_frame_unref(d.frame)
+	// Flush the filter at the end of the stream
+	if packet == nil && d.filter != nil && result == nil {
+		if err := d.filter.Frame(nil, func(frame *Frame) error {
+			return d.output(frame, fn)
+		}); errors.Is(err, io.EOF) {
+			result = io.EOF
+		} else if err != nil {
+			return err
+		}
 	}
 
 	// Return success or EOF
 	return result
 }
+
+// Decode a packet into a subtitle to pass back to the DecoderSubtitleFn.
+// If the DecoderSubtitleFn is nil, then the packet is ignored.
+func (d *Decoder) decodeSubtitle(packet *ff.AVPacket, fn DecoderSubtitleFn) error {
+	if fn == nil || packet == nil {
+		return nil
+	}
+
+	// Set the timebase for the packet
+	packet.SetTimeBase(d.timeBase)
+
+	// Decode the subtitle
+	var sub ff.AVSubtitle
+	if got, err := ff.AVCodec_decode_subtitle(d.codec, &sub, packet); err != nil {
+		return ErrInternalAppError.With("AVCodec_decode_subtitle:", err)
+	} else if !got {
+		return nil
+	}
+	defer ff.AVCodec_subtitle_free(&sub)
+
+	// The subtitle timestamp is in AV_TIME_BASE units, or use the packet timestamp
+	pts := sub.Pts()
+	if pts == ff.AV_NOPTS_VALUE {
+		if pts = packet.Pts(); pts == ff.AV_NOPTS_VALUE {
+			pts = 0
+		} else {
+			pts = ff.AVUtil_rational_rescale_q(pts, d.timeBase, ff.AVUtil_rational(1, ff.AV_TIME_BASE))
+		}
+	}
+	subtitle := newSubtitle(&sub, pts)
+
+	// Discard subtitles which end before the seek timestamp
+	if d.seek != ff.AV_NOPTS_VALUE {
+		end := ff.AVUtil_rational_rescale_q(int64(subtitle.End()), ff.AVUtil_rational(1, int(time.Second)), d.timeBase)
+		if end < d.seek {
+			return nil
+		}
+		d.seek = ff.AV_NOPTS_VALUE
+	}
+
+	// Pass back to the caller
+	return fn(d.stream, subtitle)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return true if the decoder decodes subtitles
+func (d *Decoder) isSubtitle() bool {
+	return d.codec.Codec().Type() == ff.AVMEDIA_TYPE_SUBTITLE
+}
+
+// Resample or resize a frame, and pass it back to the caller. If a new frame
+// is returned by the resampler or resizer, it is managed by the resampler or
+// resizer and no need to unreference it later.
+func (d *Decoder) output(frame *Frame, fn DecoderFrameFn) error {
+	if d.re != nil {
+		if dest, err := d.re.Frame(frame); err != nil {
+			return err
+		} else if dest == nil {
+			return nil
+		} else {
+			frame = dest
+		}
+	}
+	return fn(d.stream, frame)
+}
+
+// Flush the decoder after seeking. If pts is not ff.AV_NOPTS_VALUE then
+// any frames decoded which end before this timestamp (in the stream timebase)
+// are discarded.
+func (d *Decoder) flush(pts int64) {
+	ff.AVCodec_flush_buffers(d.codec)
+	if d.re != nil {
+		d.re.reset()
+	}
+	if d.filter != nil {
+		d.filter.reset()
+	}
+	d.seek = pts
+}
+
+// Return true if the frame should be discarded, because it ends before the
+// seek timestamp. Once a frame is reached which passes the seek timestamp,
+// the seek timestamp is cleared.
+func (d *Decoder) discard(frame *ff.AVFrame) bool {
+	if d.seek == ff.AV_NOPTS_VALUE || frame.Pts() == ff.AV_NOPTS_VALUE {
+		return false
+	}
+
+	// Calculate the end of the frame. For audio this is the number of samples,
+	// for video we compare the start of the frame
+	end := frame.Pts()
+	if frame.SampleRate() > 0 && frame.NumSamples() > 0 {
+		end += ff.AVUtil_rational_rescale_q(int64(frame.NumSamples()), ff.AVUtil_rational(1, frame.SampleRate()), d.timeBase)
+		if end <= d.seek {
+			return true
+		}
+	} else if end < d.seek {
+		return true
+	}
+
+	// Reached the seek timestamp
+	d.seek = ff.AV_NOPTS_VALUE
+	return false
+}
+
+// Set the timestamp of a frame which has none, and advance the timestamp for
+// the next frame by the frame duration. Video without a frame rate is assumed
+// to be 25 frames per second.
+func (d *Decoder) timestamp(frame *ff.AVFrame) {
+	if frame.Pts() == ff.AV_NOPTS_VALUE {
+		frame.SetPts(d.next)
+	}
+	if frame.SampleRate() > 0 && frame.NumSamples() > 0 {
+		d.next = frame.Pts() + ff.AVUtil_rational_rescale_q(int64(frame.NumSamples()), ff.AVUtil_rational(1, frame.SampleRate()), d.timeBase)
+	} else {
+		framerate := d.codec.Framerate()
+		if framerate.Num() <= 0 || framerate.Den() <= 0 {
+			framerate = ff.AVUtil_rational(25, 1)
+		}
+		d.next = frame.Pts() + ff.AVUtil_rational_rescale_q(1, ff.AVUtil_rational_invert(framerate), d.timeBase)
+	}
+}
@@ -0,0 +1,53 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Disposition flags for a stream
+type Disposition ff.AVDisposition
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	DISPOSITION_NONE             Disposition = 0
+	DISPOSITION_DEFAULT          Disposition = Disposition(ff.AV_DISPOSITION_DEFAULT)          // Stream should be chosen by default
+	DISPOSITION_DUB              Disposition = Disposition(ff.AV_DISPOSITION_DUB)              // Stream is not in the original language
+	DISPOSITION_ORIGINAL         Disposition = Disposition(ff.AV_DISPOSITION_ORIGINAL)         // Stream is in the original language
+	DISPOSITION_COMMENT          Disposition = Disposition(ff.AV_DISPOSITION_COMMENT)          // Stream is a commentary track
+	DISPOSITION_LYRICS           Disposition = Disposition(ff.AV_DISPOSITION_LYRICS)           // Stream contains song lyrics
+	DISPOSITION_KARAOKE          Disposition = Disposition(ff.AV_DISPOSITION_KARAOKE)          // Stream contains karaoke audio
+	DISPOSITION_FORCED           Disposition = Disposition(ff.AV_DISPOSITION_FORCED)           // Subtitle stream should be displayed even if the user has not enabled subtitles
+	DISPOSITION_HEARING_IMPAIRED Disposition = Disposition(ff.AV_DISPOSITION_HEARING_IMPAIRED) // Stream is intended for hearing impaired audiences
+	DISPOSITION_VISUAL_IMPAIRED  Disposition = Disposition(ff.AV_DISPOSITION_VISUAL_IMPAIRED)  // Stream is intended for visually impaired audiences
+	DISPOSITION_CLEAN_EFFECTS    Disposition = Disposition(ff.AV_DISPOSITION_CLEAN_EFFECTS)    // Audio stream contains music and sound effects without voice
+	DISPOSITION_ATTACHED_PIC     Disposition = Disposition(ff.AV_DISPOSITION_ATTACHED_PIC)     // Stream is an attached picture
+	DISPOSITION_CAPTIONS         Disposition = Disposition(ff.AV_DISPOSITION_CAPTIONS)         // Subtitle stream contains captions
+	DISPOSITION_DESCRIPTIONS     Disposition = Disposition(ff.AV_DISPOSITION_DESCRIPTIONS)     // Subtitle stream contains textual descriptions of the video content
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (d Disposition) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d Disposition) String() string {
+	return ff.AVDisposition(d).String()
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return true if all the flags are set
+func (d Disposition) Is(flag Disposition) bool {
+	return d&flag == flag
+}
@@ -0,0 +1,194 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ElementaryDecoder decodes a raw elementary stream without a container,
+// such as Annex-B H.264 or HEVC, MPEG-1/2 video, ADTS AAC or MP3. The stream
+// is split into packets with a codec parser. Frames are passed back with
+// stream index zero, and timestamps in microseconds are generated from the
+// frame rate or sample rate of the stream.
+type ElementaryDecoder struct {
+	r       io.Reader
+	parser  *ff.AVCodecParserContext
+	decoder *Decoder
+	packet  *ff.AVPacket
+	buf     []byte
+	pos     int64
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	elementaryBufSize = 4096 // Size of each read from the elementary stream
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a decoder for an elementary stream read from r, with the name of
+// the decoder (for example, "h264", "hevc", "mpeg2video", "aac" or "mp3").
+// If dest is not nil, then frames are resampled or resized to the
+// destination parameters, as per the Reader.Decode method.
+func NewElementaryDecoder(r io.Reader, codec string, dest *Par, force bool) (*ElementaryDecoder, error) {
+	decoder := new(ElementaryDecoder)
+	if r == nil {
+		return nil, ErrBadParameter.With("nil reader")
+	} else {
+		decoder.r = r
+	}
+
+	// Find the decoder and the parser
+	c := ff.AVCodec_find_decoder_by_name(codec)
+	if c == nil {
+		return nil, ErrBadParameter.Withf("unknown decoder %q", codec)
+	} else if t := c.Type(); t != ff.AVMEDIA_TYPE_AUDIO && t != ff.AVMEDIA_TYPE_VIDEO {
+		return nil, ErrBadParameter.Withf("decoder %q is not an audio or video decoder", codec)
+	} else if parser := ff.AVCodec_parser_init(c.ID()); parser == nil {
+		return nil, ErrNotImplemented.Withf("no parser for codec %q", codec)
+	} else {
+		decoder.parser = parser
+	}
+
+	// Create the decoder, with timestamps generated from the start of the stream
+	if d, err := newDecoder(0, c, nil, ff.AVUtil_rational(1, ff.AV_TIME_BASE), dest, force); err != nil {
+		return nil, errors.Join(err, decoder.Close())
+	} else {
+		decoder.decoder = d
+		decoder.decoder.next = 0
+	}
+
+	// Allocate a packet, and the read buffer with padding
+	if packet := ff.AVCodec_packet_alloc(); packet == nil {
+		return nil, errors.Join(errors.New("failed to allocate packet"), decoder.Close())
+	} else {
+		decoder.packet = packet
+		decoder.buf = make([]byte, elementaryBufSize+ff.AV_INPUT_BUFFER_PADDING_SIZE)
+	}
+
+	// Return success
+	return decoder, nil
+}
+
+// Release resources
+func (d *ElementaryDecoder) Close() error {
+	var result error
+	if d.decoder != nil {
+		result = errors.Join(result, d.decoder.Close())
+	}
+	if d.parser != nil {
+		ff.AVCodec_parser_close(d.parser)
+	}
+	if d.packet != nil {
+		ff.AVCodec_packet_free(d.packet)
+	}
+
+	// Reset fields
+	d.decoder = nil
+	d.parser = nil
+	d.packet = nil
+	d.buf = nil
+
+	// Return any errors
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode the elementary stream into frames, until the end of the stream is
+// reached. The decodefn is called for each frame decoded from the stream.
+//
+// The decoding can be interrupted by cancelling the context, or by the decodefn
+// returning an error or io.EOF. The latter will end the decoding process early but
+// will not return an error.
+func (d *ElementaryDecoder) Decode(ctx context.Context, decodefn DecoderFrameFn) error {
+	if decodefn == nil {
+		return ErrBadParameter.With("DecoderFrameFn is nil")
+	} else if d.decoder == nil {
+		return ErrBadParameter.With("decoder is closed")
+	}
+
+FOR_LOOP:
+	for {
+		select {
+		case <-ctx.Done():
+			break FOR_LOOP
+		default:
+			// Read the next chunk of the stream, and clear the padding
+			n, err := io.ReadFull(d.r, d.buf[:elementaryBufSize])
+			clear(d.buf[n:])
+			if n > 0 {
+				if err := d.parse(d.buf[:n], decodefn); errors.Is(err, io.EOF) {
+					return nil
+				} else if err != nil {
+					return err
+				}
+			}
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break FOR_LOOP
+			} else if err != nil {
+				return err
+			}
+		}
+	}
+
+	// Return if the context was cancelled
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// Flush the parser and then the decoder
+	if err := d.parse(nil, decodefn); errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err := d.decoder.decode(nil, decodefn); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Split the data into packets and decode them. An empty buffer flushes the
+// parser at the end of the stream.
+func (d *ElementaryDecoder) parse(data []byte, fn DecoderFrameFn) error {
+	for {
+		n := ff.AVCodec_parser_parse(d.parser, d.decoder.codec, d.packet, data, ff.AV_NOPTS_VALUE, ff.AV_NOPTS_VALUE, d.pos)
+		if n < 0 {
+			return ErrInternalAppError.Withf("AVCodec_parser_parse: error %d", n)
+		}
+		data = data[n:]
+		d.pos += int64(n)
+
+		// Decode the packet, which is copied by the decoder
+		if d.packet.Size() > 0 {
+			if err := d.decoder.decode(d.packet, fn); err != nil {
+				return err
+			}
+		}
+
+		// Finish when all the data has been consumed, or after flushing
+		if len(data) == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package ffmpeg_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_elementary_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Convert the video stream to an Annex-B elementary stream
+	video := r.BestStream(media.VIDEO)
+	filter, err := ffmpeg.NewBitstreamFilter("h264_mp4toannexb", r.Par(video))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer filter.Close()
+
+	var es bytes.Buffer
+	var packets int
+	fn := func(packet *ffmpeg.Packet) error {
+		es.Write(packet.Bytes())
+		return nil
+	}
+	assert.NoError(r.Demux(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if stream == video {
+			return par, nil
+		}
+		return nil, nil
+	}, func(stream int, packet *ffmpeg.Packet) error {
+		packets++
+		return filter.Packet(packet, fn)
+	}))
+	assert.NoError(filter.Packet(nil, fn))
+
+	// Decode the elementary stream, resized
+	decoder, err := ffmpeg.NewElementaryDecoder(&es, "h264", ffmpeg.VideoPar("rgba", "320x240", 0), false)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer decoder.Close()
+
+	var frames int
+	var ts float64 = -1
+	assert.NoError(decoder.Decode(context.Background(), func(stream int, frame *ffmpeg.Frame) error {
+		assert.Equal(0, stream)
+		assert.Equal(media.VIDEO, frame.Type())
+		assert.Equal(320, frame.Width())
+		assert.Equal(240, frame.Height())
+		assert.Greater(frame.Ts(), ts)
+		ts = frame.Ts()
+		frames++
+		return nil
+	}))
+	assert.Equal(packets, frames)
+	t.Log("frames=", frames, "duration=", ts)
+}
+
+func Test_elementary_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// An MP3 file is an elementary stream
+	f, err := os.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer f.Close()
+
+	decoder, err := ffmpeg.NewElementaryDecoder(f, "mp3", nil, false)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer decoder.Close()
+
+	// Stop after the first second
+	var samples int
+	assert.NoError(decoder.Decode(context.Background(), func(stream int, frame *ffmpeg.Frame) error {
+		assert.Equal(media.AUDIO, frame.Type())
+		if frame.Ts() >= 1.0 {
+			return io.EOF
+		}
+		samples += frame.NumSamples()
+		return nil
+	}))
+	assert.NotZero(samples)
+}
+
+func Test_elementary_003(t *testing.T) {
+	assert := assert.New(t)
+
+	// Unknown decoder, and a decoder without a parser
+	_, err := ffmpeg.NewElementaryDecoder(new(bytes.Buffer), "nonexistent", nil, false)
+	assert.Error(err)
+	_, err = ffmpeg.NewElementaryDecoder(new(bytes.Buffer), "pcm_s16le", nil, false)
+	assert.Error(err)
+	_, err = ffmpeg.NewElementaryDecoder(nil, "h264", nil, false)
+	assert.Error(err)
+}
@@ -3,9 +3,10 @@ package ffmpeg
 import (
 	"encoding/json"
 	"errors"
-	"fmt"
 	"io"
+	"strings"
 	"syscall"
+	"time"
 
 	// Packages
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
@@ -27,34 +28,79 @@ type Encoder struct {
 
 	// The next presentation timestamp
 	next_pts int64
+
+	// The subtitle encoding buffer and the number of subtitle events encoded
+	buf       []byte
+	readorder int
+
+	// The encoding pass, and the first pass statistics
+	pass  int
+	stats strings.Builder
+
+	// Audio buffer for encoders with a fixed frame size
+	fifo *audioFifo
+}
+
+// Two-pass encoding parameters for an encoder
+type encoderPass struct {
+	pass  int    // Encoding pass, 1 or 2
+	stats string // First pass statistics, for the second pass
+	log   string // Statistics file, for encoders which write their own statistics
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	// The maximum size of an encoded subtitle
+	subtitleBufSize = 1024 * 1024
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
 // Create an encoder with the given parameters
 func NewEncoder(ctx *ff.AVFormatContext, stream int, par *Par) (*Encoder, error) {
+	return newEncoder(ctx, stream, par, nil)
+}
+
+// Create an encoder with the given parameters, and the encoding pass for
+// video streams, which may be nil
+func newEncoder(ctx *ff.AVFormatContext, stream int, par *Par, pass *encoderPass) (*Encoder, error) {
 	encoder := new(Encoder)
 
-	// Get codec
-	codec_id := ff.AV_CODEC_ID_NONE
-	switch par.CodecType() {
-	case ff.AVMEDIA_TYPE_AUDIO:
-		codec_id = ctx.Output().AudioCodec()
-	case ff.AVMEDIA_TYPE_VIDEO:
-		codec_id = ctx.Output().VideoCodec()
-	case ff.AVMEDIA_TYPE_SUBTITLE:
-		codec_id = ctx.Output().SubtitleCodec()
+	// Get codec, either by name or the default for the output format
+	var codec *ff.AVCodec
+	if name := par.Encoder(); name != "" {
+		if codec = ff.AVCodec_find_encoder_by_name(name); codec == nil {
+			return nil, ErrBadParameter.Withf("unknown encoder %q for stream %v", name, stream)
+		} else if codec.Type() != par.CodecType() {
+			return nil, ErrBadParameter.Withf("encoder %q is not a %v encoder for stream %v", name, par.Type(), stream)
+		}
+	} else {
+		codec_id := ff.AV_CODEC_ID_NONE
+		switch par.CodecType() {
+		case ff.AVMEDIA_TYPE_AUDIO:
+			codec_id = ctx.Output().AudioCodec()
+		case ff.AVMEDIA_TYPE_VIDEO:
+			codec_id = ctx.Output().VideoCodec()
+		case ff.AVMEDIA_TYPE_SUBTITLE:
+			codec_id = ctx.Output().SubtitleCodec()
+		}
+		if codec_id == ff.AV_CODEC_ID_NONE {
+			return nil, ErrBadParameter.Withf("no codec specified for stream %v", stream)
+		}
+		if codec = ff.AVCodec_find_encoder(codec_id); codec == nil {
+			return nil, ErrBadParameter.Withf("codec %q cannot encode", codec_id)
+		}
 	}
-	if codec_id == ff.AV_CODEC_ID_NONE {
-		return nil, ErrBadParameter.Withf("no codec specified for stream %v", stream)
+
+	// Check the codec can be stored in the output format
+	if !ff.AVFormat_query_codec(ctx.Output(), codec.ID()) {
+		return nil, ErrBadParameter.Withf("codec %q is not supported by format %q", codec.Name(), ctx.Output().Name())
 	}
 
 	// Allocate codec
-	codec := ff.AVCodec_find_encoder(codec_id)
-	if codec == nil {
-		return nil, ErrBadParameter.Withf("codec %q cannot encode", codec_id)
-	}
 	if codecctx := ff.AVCodec_alloc_context(codec); codecctx == nil {
 		return nil, ErrInternalAppError.With("could not allocate audio codec context")
 	} else {
@@ -86,6 +132,11 @@ func NewEncoder(ctx *ff.AVFormatContext, stream int, par *Par) (*Encoder, error)
 		encoder.ctx.SetFlags(encoder.ctx.Flags() | ff.AV_CODEC_FLAG_GLOBAL_HEADER)
 	}
 
+	// Set the encoding pass for video streams
+	if pass != nil && codec.Type() == ff.AVMEDIA_TYPE_VIDEO {
+		encoder.setPass(pass)
+	}
+
 	// Get the options
 	opts := par.newOpts()
 	if opts == nil {
@@ -116,6 +167,15 @@ func NewEncoder(ctx *ff.AVFormatContext, stream int, par *Par) (*Encoder, error)
 		return nil, err
 	}
 
+	// Audio frames are re-chunked when the encoder has a fixed frame size,
+	// which is known once the codec is opened
+	if fifo, err := newAudioFifo(encoder.ctx); err != nil {
+		ff.AVCodec_free_context(encoder.ctx)
+		return nil, err
+	} else {
+		encoder.fifo = fifo
+	}
+
 	// Hint what timebase we want to encode at. This will change when writing the
 	// headers for the encoding process
 	encoder.stream.SetTimeBase(par.timebase)
@@ -136,16 +196,22 @@ func NewEncoder(ctx *ff.AVFormatContext, stream int, par *Par) (*Encoder, error)
 func (encoder *Encoder) Close() error {
 	// Free respurces
 	if encoder.ctx != nil {
+		encoder.ctx.SetStatsIn("")
 		ff.AVCodec_free_context(encoder.ctx)
 	}
 	if encoder.packet != nil {
 		ff.AVCodec_packet_free(encoder.packet)
 	}
+	if encoder.fifo != nil {
+		encoder.fifo.Close()
+	}
 
 	// Release resources
 	encoder.packet = nil
 	encoder.stream = nil
 	encoder.ctx = nil
+	encoder.buf = nil
+	encoder.fifo = nil
 
 	// Return success
 	return nil
@@ -184,6 +250,71 @@ func (e *Encoder) Encode(frame *Frame, fn EncoderPacketFn) error {
 	return e.encode(frame, fn)
 }
 
+// Encode a subtitle and pass the packet to the EncoderPacketFn, with the
+// stream timebase. Text subtitles can be created with NewSubtitle or are
+// received from Reader.Decode. Bitmap subtitles are not supported.
+func (e *Encoder) EncodeSubtitle(subtitle *Subtitle, fn EncoderPacketFn) error {
+	if fn == nil {
+		return ErrBadParameter.With("nil fn")
+	} else if subtitle == nil {
+		return ErrBadParameter.With("nil subtitle")
+	} else if !e.isSubtitle() {
+		return ErrBadParameter.Withf("stream %v is not a subtitle stream", e.stream.Id())
+	}
+
+	// Create the subtitle, which starts at the pts with a start display
+	// time of zero
+	var sub ff.AVSubtitle
+	defer ff.AVCodec_subtitle_free(&sub)
+	sub.SetPts(int64(subtitle.Start()) * int64(ff.AV_TIME_BASE) / int64(time.Second))
+	sub.SetEndDisplayTime(uint32((subtitle.End() - subtitle.Start()) / time.Millisecond))
+	for _, rect := range subtitle.Rects() {
+		event := rect.assEvent(e.readorder)
+		if event == "" {
+			return ErrNotImplemented.Withf("subtitle type %v", rect.Type())
+		} else if err := ff.AVCodec_subtitle_add_rect(&sub, ff.SUBTITLE_ASS, event); err != nil {
+			return err
+		}
+	}
+	if sub.NumRects() == 0 {
+		return nil
+	}
+
+	// Encode the subtitle
+	if e.buf == nil {
+		e.buf = make([]byte, subtitleBufSize)
+	}
+	n, err := ff.AVCodec_encode_subtitle(e.ctx, e.buf, &sub)
+	if err != nil {
+		return err
+	} else {
+		e.readorder++
+	}
+	if err := ff.AVCodec_packet_from_bytes(e.packet, e.buf[:n]); err != nil {
+		return err
+	}
+	defer ff.AVCodec_packet_unref(e.packet)
+
+	// Set packet timestamps in the stream timebase
+	tb := ff.AVUtil_rational(1, ff.AV_TIME_BASE)
+	pts := ff.AVUtil_rational_rescale_q(sub.Pts(), tb, e.stream.TimeBase())
+	e.packet.SetPts(pts)
+	e.packet.SetDts(pts)
+	e.packet.SetDuration(ff.AVUtil_rational_rescale_q(int64(sub.EndDisplayTime()), ff.AVUtil_rational(1, 1000), e.stream.TimeBase()))
+	e.packet.SetFlags(ff.AV_PKT_FLAG_KEY)
+	e.packet.SetStreamIndex(e.stream.Index())
+	e.packet.SetTimeBase(e.stream.TimeBase())
+
+	// Pass back to the caller
+	return fn((*Packet)(e.packet))
+}
+
+// Return the statistics from the first pass of two-pass encoding, which
+// are complete once the encoder has been flushed
+func (e *Encoder) Stats() string {
+	return e.stats.String()
+}
+
 // Return the codec parameters
 func (e *Encoder) Par() *Par {
 	par := new(Par)
@@ -204,8 +335,8 @@ func (e *Encoder) nextPts(frame *Frame) int64 {
 	case ff.AVMEDIA_TYPE_VIDEO:
 		next_pts = ff.AVUtil_rational_rescale_q(1, frame.TimeBase(), e.stream.TimeBase())
 	default:
-		// Dunno what to do with subtitle and data streams yet
-		fmt.Println("TODO: next_pts for subtitle and data streams")
+		// Subtitles are encoded with EncodeSubtitle, and are interleaved
+		// by the muxer
 		return 0
 	}
 	return next_pts
@@ -214,7 +345,62 @@ func (e *Encoder) nextPts(frame *Frame) int64 {
 //////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// Set the flags and statistics for two-pass encoding, before the codec
+// is opened
+func (e *Encoder) setPass(pass *encoderPass) {
+	switch pass.pass {
+	case 1:
+		e.ctx.SetFlags(e.ctx.Flags() | ff.AV_CODEC_FLAG_PASS1)
+	case 2:
+		e.ctx.SetFlags(e.ctx.Flags() | ff.AV_CODEC_FLAG_PASS2)
+		e.ctx.SetStatsIn(pass.stats)
+	default:
+		return
+	}
+	e.pass = pass.pass
+
+	// Encoders which write their own statistics use a file, which is
+	// ignored if the encoder does not support the option
+	if pass.log != "" {
+		e.ctx.SetPrivDataKV("stats", pass.log)
+	}
+}
+
+// Return true if the encoder encodes subtitles
+func (e *Encoder) isSubtitle() bool {
+	return e.ctx.Codec().Type() == ff.AVMEDIA_TYPE_SUBTITLE
+}
+
+// Encode a decoded frame, rescaling the frame timestamp from the frame
+// timebase to the encoder timebase. The frame timestamp is restored afterwards.
+func (e *Encoder) transcode(frame *Frame, fn EncoderPacketFn) error {
+	pts := frame.Pts()
+	if tb := frame.TimeBase(); pts != ff.AV_NOPTS_VALUE && !tb.IsZero() {
+		frame.SetPts(ff.AVUtil_rational_rescale_q(pts, tb, e.ctx.TimeBase()))
+		defer frame.SetPts(pts)
+	}
+	return e.encode(frame, fn)
+}
+
+// Encode a frame, re-chunking audio frames to the encoder frame size when
+// required. A nil frame flushes any buffered samples and then the encoder.
 func (e *Encoder) encode(frame *Frame, fn EncoderPacketFn) error {
+	if e.fifo == nil {
+		return e.send(frame, fn)
+	}
+	if err := e.fifo.Frame(frame, func(frame *Frame) error {
+		return e.send(frame, fn)
+	}); err != nil {
+		return err
+	}
+	if frame == nil {
+		return e.send(nil, fn)
+	}
+	return nil
+}
+
+// Send a frame to the encoder, and pass the packets to the EncoderPacketFn
+func (e *Encoder) send(frame *Frame, fn EncoderPacketFn) error {
 	// Send the frame to the encoder
 	if err := ff.AVCodec_send_frame(e.ctx, (*ff.AVFrame)(frame)); err != nil {
 		return err
@@ -223,8 +409,13 @@ func (e *Encoder) encode(frame *Frame, fn EncoderPacketFn) error {
 	// Write out the packets
 	var result error
 	for {
-		// Receive the packet
-		if err := ff.AVCodec_receive_packet(e.ctx, e.packet); errors.Is(err, syscall.EAGAIN) || errors.Is(err, io.EOF) {
+		// Receive the packet, and collect the first pass statistics on
+		// success or at the end of the stream
+		err := ff.AVCodec_receive_packet(e.ctx, e.packet)
+		if e.pass == 1 && (err == nil || errors.Is(err, io.EOF)) {
+			e.stats.WriteString(e.ctx.StatsOut())
+		}
+		if errors.Is(err, syscall.EAGAIN) || errors.Is(err, io.EOF) {
 			// Finished receiving packet or EOF
 			break
 		} else if err != nil {
@@ -0,0 +1,246 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"syscall"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Filter processes audio or video frames with a filter graph, which is
+// described with the ffmpeg filter syntax, for example "yadif,crop=640:480"
+// or "atempo=1.5". The graph has a single input and a single output, and is
+// configured from the parameters of the first frame received.
+type Filter struct {
+	desc  string
+	t     media.Type
+	graph *ff.AVFilterGraph
+	src   *ff.AVFilterContext
+	sink  *ff.AVFilterContext
+	dest  *ff.AVFrame
+}
+
+// FilterFrameFn is called for each filtered frame. The frame is only
+// valid for the duration of the call. Return io.EOF to end filtering early.
+type FilterFrameFn func(*Frame) error
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a new filter from a filter description
+func NewFilter(desc string) (*Filter, error) {
+	filter := new(Filter)
+
+	// Check parameters
+	if desc = strings.TrimSpace(desc); desc == "" {
+		return nil, ErrBadParameter.With("empty filter description")
+	} else {
+		filter.desc = desc
+	}
+
+	// Create a destination frame
+	if frame := ff.AVUtil_frame_alloc(); frame == nil {
+		return nil, ErrInternalAppError.With("failed to allocate frame")
+	} else {
+		filter.dest = frame
+	}
+
+	// Return success
+	return filter, nil
+}
+
+// Release resources
+func (f *Filter) Close() error {
+	f.reset()
+	if f.dest != nil {
+		ff.AVUtil_frame_free(f.dest)
+	}
+	f.dest = nil
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (f *Filter) MarshalJSON() ([]byte, error) {
+	if f.graph == nil {
+		return json.Marshal(f.desc)
+	}
+	return json.Marshal(f.graph)
+}
+
+func (f *Filter) String() string {
+	if f.graph == nil {
+		return f.desc
+	}
+	return ff.AVFilterGraph_dump(f.graph)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Filter a frame, and call the FilterFrameFn for each filtered frame. If
+// the source frame is nil, then the filter is flushed, after which it can
+// be used again.
+func (f *Filter) Frame(src *Frame, fn FilterFrameFn) error {
+	if fn == nil {
+		return ErrBadParameter.With("FilterFrameFn is nil")
+	}
+
+	// Configure the filter graph on the first frame
+	if f.graph == nil {
+		if src == nil {
+			return nil
+		} else if err := f.init(src); err != nil {
+			return err
+		}
+	} else if src != nil && src.Type() != f.t {
+		return ErrBadParameter.Withf("frame type mismatch: %v", src.Type())
+	}
+
+	// Send the frame to the graph, keeping a reference so the caller
+	// retains ownership of the frame
+	if err := ff.AVBufferSrc_add_frame(f.src, (*ff.AVFrame)(src), ff.AV_BUFFERSRC_FLAG_KEEP_REF); err != nil {
+		return ErrInternalAppError.With("AVBufferSrc_add_frame:", err)
+	}
+
+	// Receive the filtered frames
+	var result error
+	for {
+		if err := ff.AVBufferSink_get_frame(f.sink, f.dest); errors.Is(err, syscall.EAGAIN) {
+			break
+		} else if errors.Is(err, io.EOF) {
+			// Flushed, so reset the graph for the next frame
+			f.reset()
+			break
+		} else if err != nil {
+			return ErrInternalAppError.With("AVBufferSink_get_frame:", err)
+		}
+
+		// Pass back to the caller
+		f.dest.SetTimeBase(ff.AVBufferSink_time_base(f.sink))
+		err := fn((*Frame)(f.dest))
+		ff.AVUtil_frame_unref(f.dest)
+		if errors.Is(err, io.EOF) {
+			result = io.EOF
+			break
+		} else if err != nil {
+			return err
+		}
+	}
+
+	// Return success or EOF
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Create the filter graph from the parameters of the source frame
+func (f *Filter) init(src *Frame) error {
+	var args, in, out string
+	frame := (*ff.AVFrame)(src)
+	tb := frame.TimeBase()
+	switch src.Type() {
+	case media.AUDIO:
+		if tb.IsZero() {
+			tb = ff.AVUtil_rational(1, frame.SampleRate())
+		}
+		ch := frame.ChannelLayout()
+		layout, err := ff.AVUtil_channel_layout_describe(&ch)
+		if err != nil {
+			return err
+		}
+		in, out = "abuffer", "abuffersink"
+		args = fmt.Sprintf("time_base=%d/%d:sample_rate=%d:sample_fmt=%s:channel_layout=%s",
+			tb.Num(), tb.Den(), frame.SampleRate(), ff.AVUtil_get_sample_fmt_name(frame.SampleFormat()), layout)
+	case media.VIDEO:
+		if tb.IsZero() {
+			tb = ff.AVUtil_rational(1, ff.AV_TIME_BASE)
+		}
+		sar := frame.SampleAspectRatio()
+		if sar.IsZero() {
+			sar = ff.AVUtil_rational(1, 1)
+		}
+		in, out = "buffer", "buffersink"
+		args = fmt.Sprintf("video_size=%dx%d:pix_fmt=%d:time_base=%d/%d:pixel_aspect=%d/%d",
+			frame.Width(), frame.Height(), int(frame.PixFmt()), tb.Num(), tb.Den(), sar.Num(), sar.Den())
+	default:
+		return ErrBadParameter.Withf("invalid filter type: %v", src.Type())
+	}
+
+	// Create the graph with a source and sink
+	graph := ff.AVFilterGraph_alloc()
+	if graph == nil {
+		return ErrInternalAppError.With("failed to allocate filter graph")
+	}
+	source, err := ff.AVFilterGraph_create_filter(graph, ff.AVFilter_get_by_name(in), "in", args)
+	if err != nil {
+		ff.AVFilterGraph_free(graph)
+		return ErrInternalAppError.With("AVFilterGraph_create_filter:", err)
+	}
+	sink, err := ff.AVFilterGraph_create_filter(graph, ff.AVFilter_get_by_name(out), "out", "")
+	if err != nil {
+		ff.AVFilterGraph_free(graph)
+		return ErrInternalAppError.With("AVFilterGraph_create_filter:", err)
+	}
+
+	// The source is connected to the input of the parsed graph, and
+	// the sink to the output
+	outputs := ff.AVFilterInOut_alloc()
+	inputs := ff.AVFilterInOut_alloc()
+	if outputs == nil || inputs == nil {
+		ff.AVFilterInOut_free(outputs)
+		ff.AVFilterInOut_free(inputs)
+		ff.AVFilterGraph_free(graph)
+		return ErrInternalAppError.With("failed to allocate filter inputs and outputs")
+	}
+	outputs.SetName("in")
+	outputs.SetFilterCtx(source)
+	inputs.SetName("out")
+	inputs.SetFilterCtx(sink)
+
+	// Parse and configure the graph
+	err = ff.AVFilterGraph_parse(graph, f.desc, &inputs, &outputs)
+	ff.AVFilterInOut_free(outputs)
+	ff.AVFilterInOut_free(inputs)
+	if err != nil {
+		ff.AVFilterGraph_free(graph)
+		return ErrBadParameter.Withf("filter %q: %v", f.desc, err)
+	} else if err := ff.AVFilterGraph_config(graph); err != nil {
+		ff.AVFilterGraph_free(graph)
+		return ErrBadParameter.Withf("filter %q: %v", f.desc, err)
+	}
+
+	// Set the graph
+	f.t = src.Type()
+	f.graph = graph
+	f.src = source
+	f.sink = sink
+
+	// Return success
+	return nil
+}
+
+// Free the filter graph, which discards any buffered frames. The graph
+// is created again when the next frame is received.
+func (f *Filter) reset() {
+	if f.graph != nil {
+		ff.AVFilterGraph_free(f.graph)
+	}
+	f.graph = nil
+	f.src = nil
+	f.sink = nil
+}
@@ -0,0 +1,109 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"image/png"
+	"os"
+	"testing"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_filter_001(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := os.Open("../../etc/test/sample.png")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+	img, err := png.Decode(r)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	frame, err := ffmpeg.NewFrame(nil)
+	if !assert.NoError(err) {
+		t.FailNow()
+	} else if err := frame.FromImage(img); !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+
+	// Create a filter
+	filter, err := ffmpeg.NewFilter("crop=100:50:0:0,hflip")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer filter.Close()
+
+	// Filter the frame, then flush
+	var n int
+	fn := func(frame *ffmpeg.Frame) error {
+		assert.Equal(100, frame.Width())
+		assert.Equal(50, frame.Height())
+		n++
+		return nil
+	}
+	assert.NoError(filter.Frame(frame, fn))
+	t.Log(filter)
+	assert.NoError(filter.Frame(nil, fn))
+	assert.Equal(1, n)
+}
+
+func Test_filter_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Invalid filter descriptions
+	_, err := ffmpeg.NewFilter("")
+	assert.Error(err)
+
+	filter, err := ffmpeg.NewFilter("nonexistent=1")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer filter.Close()
+
+	frame, err := ffmpeg.NewFrame(ffmpeg.AudioPar("fltp", "mono", 22050))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+	assert.Error(filter.Frame(frame, func(*ffmpeg.Frame) error {
+		return nil
+	}))
+}
+
+func Test_filter_003(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Decode the audio at double speed
+	var samples, samplerate int
+	if err := r.Decode(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() == media.AUDIO {
+			par.SetFilter("atempo=2.0")
+			return par, nil
+		}
+		return nil, nil
+	}, func(stream int, frame *ffmpeg.Frame) error {
+		samples += frame.NumSamples()
+		samplerate = frame.SampleRate()
+		return nil
+	}); !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Check the duration is halved
+	if assert.NotZero(samplerate) {
+		assert.InDelta(r.Duration().Seconds()/2, float64(samples)/float64(samplerate), 1.0)
+	}
+}
@@ -8,6 +8,9 @@ import (
 	// Packages
 	media "github.com/mutablelogic/go-media"
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -39,30 +42,11 @@ func NewFrame(par *Par) (*Frame, error) {
 	}
 
 	// Set parameters
-	switch par.CodecType() {
-	case ff.AVMEDIA_TYPE_AUDIO:
-		frame.SetSampleFormat(par.SampleFormat())
-		if err := frame.SetChannelLayout(par.ChannelLayout()); err != nil {
-			ff.AVUtil_frame_free(frame)
-			return nil, err
-		}
-		frame.SetSampleRate(par.Samplerate())
-		frame.SetNumSamples(par.FrameSize())
-		frame.SetTimeBase(ff.AVUtil_rational(1, par.Samplerate()))
-	case ff.AVMEDIA_TYPE_VIDEO:
-		frame.SetPixFmt(par.PixelFormat())
-		frame.SetWidth(par.Width())
-		frame.SetHeight(par.Height())
-		frame.SetSampleAspectRatio(par.SampleAspectRatio())
-		frame.SetTimeBase(par.timebase) // Also sets framerate
-	default:
+	if err := setFramePar(frame, par); err != nil {
 		ff.AVUtil_frame_free(frame)
-		return nil, errors.New("invalid codec type")
+		return nil, err
 	}
 
-	// Clear Pts
-	frame.SetPts(ff.AV_NOPTS_VALUE)
-
 	// Return success
 	return (*Frame)(frame), nil
 }
@@ -162,33 +146,96 @@ func (frame *Frame) Type() media.Type {
 	}
 }
 
-// Return plane data as a float32 slice
+// Return plane data as a uint8 slice, for u8 and u8p sample formats
+func (frame *Frame) Uint8(plane int) []uint8 {
+	return (*ff.AVFrame)(frame).Uint8(plane)
+}
+
+// Return plane data as an int16 slice, for s16 and s16p sample formats
+func (frame *Frame) Int16(plane int) []int16 {
+	return (*ff.AVFrame)(frame).Int16(plane)
+}
+
+// Return plane data as an int32 slice, for s32 and s32p sample formats
+func (frame *Frame) Int32(plane int) []int32 {
+	return (*ff.AVFrame)(frame).Int32(plane)
+}
+
+// Return plane data as an int64 slice, for s64 and s64p sample formats
+func (frame *Frame) Int64(plane int) []int64 {
+	return (*ff.AVFrame)(frame).Int64(plane)
+}
+
+// Return plane data as a float32 slice, for flt and fltp sample formats
 func (frame *Frame) Float32(plane int) []float32 {
-	ctx := (*ff.AVFrame)(frame)
-	return ctx.Float32(plane)
+	return (*ff.AVFrame)(frame).Float32(plane)
+}
+
+// Return plane data as a float64 slice, for dbl and dblp sample formats
+func (frame *Frame) Float64(plane int) []float64 {
+	return (*ff.AVFrame)(frame).Float64(plane)
+}
+
+// Set plane data from a uint8 slice. For packed formats the samples are
+// interleaved, and the number of samples is len(data) divided by the number
+// of channels. The frame is re-allocated if the number of samples changes.
+func (frame *Frame) SetUint8(plane int, data []uint8) error {
+	return setSamples(frame, ff.AV_SAMPLE_FMT_U8, plane, data, frame.Uint8)
+}
+
+// Set plane data from an int16 slice
+func (frame *Frame) SetInt16(plane int, data []int16) error {
+	return setSamples(frame, ff.AV_SAMPLE_FMT_S16, plane, data, frame.Int16)
+}
+
+// Set plane data from an int32 slice
+func (frame *Frame) SetInt32(plane int, data []int32) error {
+	return setSamples(frame, ff.AV_SAMPLE_FMT_S32, plane, data, frame.Int32)
 }
 
-// Set plane data from float32 slice
+// Set plane data from an int64 slice
+func (frame *Frame) SetInt64(plane int, data []int64) error {
+	return setSamples(frame, ff.AV_SAMPLE_FMT_S64, plane, data, frame.Int64)
+}
+
+// Set plane data from a float32 slice
 func (frame *Frame) SetFloat32(plane int, data []float32) error {
-	if frame.Type() != media.AUDIO {
-		return errors.New("frame is not an audio frame")
-	}
+	return setSamples(frame, ff.AV_SAMPLE_FMT_FLT, plane, data, frame.Float32)
+}
 
-	// If the number of samples is not the same, the re-allocate the frame
-	ctx := (*ff.AVFrame)(frame)
-	if len(data) != frame.NumSamples() {
-		ctx.SetNumSamples(len(data))
-		if err := ff.AVUtil_frame_get_buffer(ctx, false); err != nil {
-			ff.AVUtil_frame_unref(ctx)
-			return err
-		}
-	}
+// Set plane data from a float64 slice
+func (frame *Frame) SetFloat64(plane int, data []float64) error {
+	return setSamples(frame, ff.AV_SAMPLE_FMT_DBL, plane, data, frame.Float64)
+}
 
-	// Copy data
-	copy(ctx.Float32(plane), data)
+// Return a copy of the samples for each channel, for u8 and u8p sample formats
+func (frame *Frame) Uint8Channels() [][]uint8 {
+	return channels(frame, frame.Uint8)
+}
 
-	// Return success
-	return nil
+// Return a copy of the samples for each channel, for s16 and s16p sample formats
+func (frame *Frame) Int16Channels() [][]int16 {
+	return channels(frame, frame.Int16)
+}
+
+// Return a copy of the samples for each channel, for s32 and s32p sample formats
+func (frame *Frame) Int32Channels() [][]int32 {
+	return channels(frame, frame.Int32)
+}
+
+// Return a copy of the samples for each channel, for s64 and s64p sample formats
+func (frame *Frame) Int64Channels() [][]int64 {
+	return channels(frame, frame.Int64)
+}
+
+// Return a copy of the samples for each channel, for flt and fltp sample formats
+func (frame *Frame) Float32Channels() [][]float32 {
+	return channels(frame, frame.Float32)
+}
+
+// Return a copy of the samples for each channel, for dbl and dblp sample formats
+func (frame *Frame) Float64Channels() [][]float64 {
+	return channels(frame, frame.Float64)
 }
 
 // Return plane data as a byte slice
@@ -333,3 +380,131 @@ func (frame *Frame) matchesResampleResize(other *Frame) bool {
 		return false
 	}
 }
+
+// Returns true if the frame has the packed sample format, or its planar
+// equivalent
+func (frame *Frame) isSampleFormat(format ff.AVSampleFormat) bool {
+	return ff.AVUtil_get_packed_sample_fmt(frame.SampleFormat()) == format
+}
+
+// Replace the buffers of an audio frame with buffers for a number of
+// samples, keeping the frame properties and releasing any existing buffers
+func (frame *Frame) reallocSamples(samples int) error {
+	src := (*ff.AVFrame)(frame)
+	if !frame.IsAllocated() {
+		src.SetNumSamples(samples)
+		return ff.AVUtil_frame_get_buffer(src, false)
+	}
+
+	// Allocate a frame with the same parameters
+	dest := ff.AVUtil_frame_alloc()
+	if dest == nil {
+		return errors.New("failed to allocate frame")
+	}
+	defer ff.AVUtil_frame_free(dest)
+	dest.SetSampleFormat(src.SampleFormat())
+	dest.SetSampleRate(src.SampleRate())
+	dest.SetNumSamples(samples)
+	if err := dest.SetChannelLayout(src.ChannelLayout()); err != nil {
+		return err
+	} else if err := ff.AVUtil_frame_copy_props(dest, src); err != nil {
+		return err
+	} else if err := ff.AVUtil_frame_get_buffer(dest, false); err != nil {
+		return err
+	}
+	dest.SetTimeBase(src.TimeBase())
+
+	// Swap the frames, releasing the old buffers
+	ff.AVUtil_frame_unref(src)
+	ff.AVUtil_frame_move_ref(src, dest)
+
+	// Return success
+	return nil
+}
+
+// Set the samples in a plane, re-allocating the frame if the number of
+// samples changes
+func setSamples[T any](frame *Frame, format ff.AVSampleFormat, plane int, data []T, view func(int) []T) error {
+	if frame.Type() != media.AUDIO {
+		return ErrBadParameter.With("frame is not an audio frame")
+	} else if !frame.isSampleFormat(format) {
+		return ErrBadParameter.Withf("sample format %v does not match %v", frame.SampleFormat(), format)
+	}
+
+	// Packed formats interleave the channels in the first plane
+	planes, samples := frame.ChannelLayout().NumChannels(), len(data)
+	if !ff.AVUtil_sample_fmt_is_planar(frame.SampleFormat()) {
+		if samples%planes != 0 {
+			return ErrBadParameter.Withf("number of samples %v is not a multiple of %v channels", samples, planes)
+		}
+		planes, samples = 1, samples/planes
+	}
+	if plane < 0 || plane >= planes {
+		return ErrBadParameter.Withf("plane %v out of range", plane)
+	}
+
+	// If the number of samples is not the same, then re-allocate the frame
+	if samples != frame.NumSamples() || !frame.IsAllocated() {
+		if err := frame.reallocSamples(samples); err != nil {
+			return err
+		}
+	}
+
+	// Copy data
+	copy(view(plane), data)
+
+	// Return success
+	return nil
+}
+
+// Return a copy of the samples for each channel, de-interleaving packed
+// formats. Returns nil if the frame is not an audio frame.
+func channels[T any](frame *Frame, view func(int) []T) [][]T {
+	if frame.Type() != media.AUDIO || !frame.IsAllocated() {
+		return nil
+	}
+	n, samples := frame.ChannelLayout().NumChannels(), frame.NumSamples()
+	result := make([][]T, n)
+	if ff.AVUtil_sample_fmt_is_planar(frame.SampleFormat()) {
+		for ch := range result {
+			result[ch] = append(make([]T, 0, samples), view(ch)[:samples]...)
+		}
+	} else {
+		data := view(0)
+		for ch := range result {
+			result[ch] = make([]T, samples)
+			for i := range result[ch] {
+				result[ch][i] = data[i*n+ch]
+			}
+		}
+	}
+	return result
+}
+
+// Set the audio or video parameters of a frame, and clear the pts
+func setFramePar(frame *ff.AVFrame, par *Par) error {
+	switch par.CodecType() {
+	case ff.AVMEDIA_TYPE_AUDIO:
+		frame.SetSampleFormat(par.SampleFormat())
+		if err := frame.SetChannelLayout(par.ChannelLayout()); err != nil {
+			return err
+		}
+		frame.SetSampleRate(par.Samplerate())
+		frame.SetNumSamples(par.FrameSize())
+		frame.SetTimeBase(ff.AVUtil_rational(1, par.Samplerate()))
+	case ff.AVMEDIA_TYPE_VIDEO:
+		frame.SetPixFmt(par.PixelFormat())
+		frame.SetWidth(par.Width())
+		frame.SetHeight(par.Height())
+		frame.SetSampleAspectRatio(par.SampleAspectRatio())
+		frame.SetTimeBase(par.timebase) // Also sets framerate
+	default:
+		return errors.New("invalid codec type")
+	}
+
+	// Clear Pts
+	frame.SetPts(ff.AV_NOPTS_VALUE)
+
+	// Return success
+	return nil
+}
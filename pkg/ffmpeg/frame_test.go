@@ -5,6 +5,7 @@ import (
 
 	// Packages
 	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
 	assert "github.com/stretchr/testify/assert"
 
 	// Namespace imports
@@ -110,3 +111,82 @@ func Test_frame_006(t *testing.T) {
 		assert.Equal(float64(i), frame.Ts())
 	}
 }
+
+func Test_frame_007(t *testing.T) {
+	assert := assert.New(t)
+
+	// Packed stereo samples are interleaved in the first plane
+	frame, err := ffmpeg.NewFrame(ffmpeg.AudioPar("s16", "stereo", 44100))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+
+	data := make([]int16, 200)
+	for i := range data {
+		data[i] = int16(i)
+	}
+	if !assert.NoError(frame.SetInt16(0, data)) {
+		t.FailNow()
+	}
+	assert.Equal(100, frame.NumSamples())
+	assert.Equal(data, frame.Int16(0))
+	assert.Nil(frame.Int16(1))
+
+	channels := frame.Int16Channels()
+	if assert.Len(channels, 2) {
+		assert.Len(channels[0], 100)
+		assert.Len(channels[1], 100)
+		assert.Equal(int16(0), channels[0][0])
+		assert.Equal(int16(1), channels[1][0])
+		assert.Equal(int16(198), channels[0][99])
+		assert.Equal(int16(199), channels[1][99])
+	}
+
+	// Odd number of samples, wrong plane and wrong sample format
+	assert.Error(frame.SetInt16(0, data[:199]))
+	assert.Error(frame.SetInt16(1, data))
+	assert.Error(frame.SetFloat32(0, make([]float32, 200)))
+}
+
+func Test_frame_008(t *testing.T) {
+	assert := assert.New(t)
+
+	// Planar samples have one plane per channel
+	for _, format := range []string{"u8p", "s16p", "s32p", "s64p", "fltp", "dblp"} {
+		frame, err := ffmpeg.NewFrame(ffmpeg.AudioPar(format, "stereo", 44100))
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+
+		switch format {
+		case "u8p":
+			assert.NoError(frame.SetUint8(0, []uint8{1, 2, 3}))
+			assert.NoError(frame.SetUint8(1, []uint8{4, 5, 6}))
+			assert.Equal([][]uint8{{1, 2, 3}, {4, 5, 6}}, frame.Uint8Channels())
+		case "s16p":
+			assert.NoError(frame.SetInt16(0, []int16{1, 2, 3}))
+			assert.NoError(frame.SetInt16(1, []int16{4, 5, 6}))
+			assert.Equal([][]int16{{1, 2, 3}, {4, 5, 6}}, frame.Int16Channels())
+		case "s32p":
+			assert.NoError(frame.SetInt32(0, []int32{1, 2, 3}))
+			assert.NoError(frame.SetInt32(1, []int32{4, 5, 6}))
+			assert.Equal([][]int32{{1, 2, 3}, {4, 5, 6}}, frame.Int32Channels())
+		case "s64p":
+			assert.NoError(frame.SetInt64(0, []int64{1, 2, 3}))
+			assert.NoError(frame.SetInt64(1, []int64{4, 5, 6}))
+			assert.Equal([][]int64{{1, 2, 3}, {4, 5, 6}}, frame.Int64Channels())
+		case "fltp":
+			assert.NoError(frame.SetFloat32(0, []float32{1, 2, 3}))
+			assert.NoError(frame.SetFloat32(1, []float32{4, 5, 6}))
+			assert.Equal([][]float32{{1, 2, 3}, {4, 5, 6}}, frame.Float32Channels())
+		case "dblp":
+			assert.NoError(frame.SetFloat64(0, []float64{1, 2, 3}))
+			assert.NoError(frame.SetFloat64(1, []float64{4, 5, 6}))
+			assert.Equal([][]float64{{1, 2, 3}, {4, 5, 6}}, frame.Float64Channels())
+		}
+		assert.Equal(3, frame.NumSamples())
+		assert.Len(frame.Bytes(0), 3*ff.AVUtil_get_bytes_per_sample(frame.SampleFormat()))
+		assert.NoError(frame.Close())
+	}
+}
@@ -0,0 +1,263 @@
+package ffmpeg
+
+import (
+	"sync"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// FramePool allocates audio and video frames with buffers from a set of
+// pools, one pool for each combination of sample format, number of channels
+// and number of samples (for audio) or pixel format, width and height (for
+// video). Buffers are returned to the pool when frames are released.
+type FramePool struct {
+	sync.Mutex
+	pools  map[framePoolKey]*ff.AVBufferPool
+	frames []*ff.AVFrame // Released frames, which are re-used
+	stats  FramePoolStats
+}
+
+// FramePoolStats are the number of frames and buffers requested from a
+// frame pool, and the number which were allocated rather than re-used
+type FramePoolStats struct {
+	Frames       int `json:"frames"`
+	FrameAllocs  int `json:"frame_allocs"`
+	Buffers      int `json:"buffers"`
+	BufferAllocs int `json:"buffer_allocs"`
+}
+
+type framePoolKey struct {
+	format   int // Sample or pixel format
+	width    int
+	height   int
+	channels int
+	samples  int
+	align    int
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	framePoolAlign  = 64 // Alignment of data planes, in bytes
+	framePoolFrames = 32 // Maximum number of released frames kept for re-use
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a new frame pool
+func NewFramePool() *FramePool {
+	return &FramePool{
+		pools: make(map[framePoolKey]*ff.AVBufferPool),
+	}
+}
+
+// Release resources. Buffers which are still referenced by frames are freed
+// when the frames are released.
+func (p *FramePool) Close() error {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, pool := range p.pools {
+		p.stats.BufferAllocs += ff.AVUtil_buffer_pool_allocs(pool)
+		ff.AVUtil_buffer_pool_uninit(pool)
+	}
+	for _, frame := range p.frames {
+		ff.AVUtil_frame_free(frame)
+	}
+	p.pools = nil
+	p.frames = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return the number of frames and buffers requested from the pool, and the
+// number which were allocated
+func (p *FramePool) Stats() FramePoolStats {
+	p.Lock()
+	defer p.Unlock()
+	stats := p.stats
+	for _, pool := range p.pools {
+		stats.BufferAllocs += ff.AVUtil_buffer_pool_allocs(pool)
+	}
+	return stats
+}
+
+// Return a frame with the audio or video parameters and buffers allocated
+// from the pool. For audio, the frame size of the parameters sets the number
+// of samples. The frame should be released with Put, or with Close.
+func (p *FramePool) Get(par *Par) (*Frame, error) {
+	if par == nil {
+		return nil, ErrBadParameter.With("nil parameters")
+	}
+
+	// Get a frame and set the parameters
+	frame := p.frame()
+	if frame == nil {
+		return nil, ErrInternalAppError.With("failed to allocate frame")
+	} else if err := setFramePar(frame, par); err != nil {
+		p.Put((*Frame)(frame))
+		return nil, err
+	}
+
+	// Allocate the buffers
+	if err := p.getBuffer(frame, (*Frame)(frame).Type(), framePoolAlign); err != nil {
+		p.Put((*Frame)(frame))
+		return nil, err
+	}
+
+	// Return success
+	return (*Frame)(frame), nil
+}
+
+// Release a frame, returning the buffers to the pool. The frame should not
+// be used after it has been released.
+func (p *FramePool) Put(frame *Frame) {
+	if frame == nil {
+		return
+	}
+	ff.AVUtil_frame_unref((*ff.AVFrame)(frame))
+
+	p.Lock()
+	defer p.Unlock()
+	if p.pools == nil || len(p.frames) >= framePoolFrames {
+		ff.AVUtil_frame_free((*ff.AVFrame)(frame))
+	} else {
+		p.frames = append(p.frames, (*ff.AVFrame)(frame))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a released frame, or allocate a new frame
+func (p *FramePool) frame() *ff.AVFrame {
+	p.Lock()
+	defer p.Unlock()
+	p.stats.Frames++
+	if n := len(p.frames); n > 0 {
+		frame := p.frames[n-1]
+		p.frames = p.frames[:n-1]
+		return frame
+	}
+	p.stats.FrameAllocs++
+	return ff.AVUtil_frame_alloc()
+}
+
+// Allocate buffers for a frame decoded by a codec. Video buffers are enlarged
+// to the dimensions the codec requires, but the frame keeps its own width and
+// height.
+func (p *FramePool) getCodecBuffer(ctx *ff.AVCodecContext, frame *ff.AVFrame) error {
+	switch ctx.Codec().Type() {
+	case ff.AVMEDIA_TYPE_AUDIO:
+		return p.getBuffer(frame, media.AUDIO, framePoolAlign)
+	case ff.AVMEDIA_TYPE_VIDEO:
+		width, height := frame.Width(), frame.Height()
+		w, h, align := ff.AVCodec_align_dimensions(ctx, width, height)
+		frame.SetWidth(w)
+		frame.SetHeight(h)
+		err := p.getBuffer(frame, media.VIDEO, max(align, framePoolAlign))
+		frame.SetWidth(width)
+		frame.SetHeight(height)
+		return err
+	default:
+		return ErrBadParameter.With("invalid codec type")
+	}
+}
+
+// Allocate buffers for a frame from the pool for the frame parameters, with
+// data planes aligned to align bytes
+func (p *FramePool) getBuffer(frame *ff.AVFrame, t media.Type, align int) error {
+	var key framePoolKey
+	switch t {
+	case media.AUDIO:
+		if frame.NumSamples() <= 0 {
+			return ErrBadParameter.With("number of samples is not set")
+		}
+		ch := frame.ChannelLayout()
+		key = framePoolKey{format: int(frame.SampleFormat()), channels: ch.NumChannels(), samples: frame.NumSamples(), align: align}
+	case media.VIDEO:
+		key = framePoolKey{format: int(frame.PixFmt()), width: frame.Width(), height: frame.Height(), align: align}
+	default:
+		return ErrBadParameter.With("invalid frame type")
+	}
+
+	// Get or create the pool, which is held locked so it cannot be closed
+	// while the buffers are allocated
+	p.Lock()
+	defer p.Unlock()
+	pool, exists := p.pools[key]
+	if !exists {
+		if p.pools == nil {
+			return ErrInternalAppError.With("frame pool is closed")
+		} else if size, err := ff.AVUtil_frame_get_buffer_size(frame, align); err != nil {
+			return err
+		} else if pool = ff.AVUtil_buffer_pool_init(size); pool == nil {
+			return ErrInternalAppError.With("failed to allocate buffer pool")
+		}
+		p.pools[key] = pool
+	}
+
+	// Allocate the buffers
+	p.stats.Buffers++
+	return ff.AVUtil_frame_get_pool_buffer(frame, pool, align)
+}
+
+// Make sure a frame has buffers which are not referenced elsewhere, by
+// replacing the buffers from the pool when they are shared or not allocated.
+// The frame properties are kept, but the contents of the buffers are not.
+func (p *FramePool) makeWritable(frame *Frame) error {
+	if frame.IsAllocated() && ff.AVUtil_frame_is_writable((*ff.AVFrame)(frame)) {
+		return nil
+	}
+	return p.realloc(frame)
+}
+
+// Replace the buffers of a frame from the pool, keeping the frame properties
+func (p *FramePool) realloc(frame *Frame) error {
+	src := (*ff.AVFrame)(frame)
+	dest := p.frame()
+	if dest == nil {
+		return ErrInternalAppError.With("failed to allocate frame")
+	}
+	defer p.Put((*Frame)(dest))
+
+	// Set the parameters and allocate the buffers
+	switch frame.Type() {
+	case media.AUDIO:
+		dest.SetSampleFormat(src.SampleFormat())
+		dest.SetNumSamples(src.NumSamples())
+		if err := dest.SetChannelLayout(src.ChannelLayout()); err != nil {
+			return err
+		}
+	case media.VIDEO:
+		dest.SetPixFmt(src.PixFmt())
+		dest.SetWidth(src.Width())
+		dest.SetHeight(src.Height())
+	}
+	if err := ff.AVUtil_frame_copy_props(dest, src); err != nil {
+		return err
+	} else if err := p.getBuffer(dest, frame.Type(), framePoolAlign); err != nil {
+		return err
+	}
+
+	// Swap the frames
+	ff.AVUtil_frame_unref(src)
+	ff.AVUtil_frame_move_ref(src, dest)
+
+	// Return success
+	return nil
+}
@@ -0,0 +1,236 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"testing"
+
+	// Packages
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+	assert "github.com/stretchr/testify/assert"
+
+	// Namespace imports
+	. "github.com/mutablelogic/go-media"
+)
+
+func Test_framepool_001(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := ffmpeg.NewFramePool()
+	defer pool.Close()
+
+	// Get video frames, and release them
+	par := ffmpeg.VideoPar("yuv420p", "1280x720", 25)
+	for i := 0; i < 10; i++ {
+		frame, err := pool.Get(par)
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		assert.Equal(VIDEO, frame.Type())
+		assert.True(frame.IsAllocated())
+		assert.Equal(1280, frame.Width())
+		assert.Equal(720, frame.Height())
+		assert.GreaterOrEqual(frame.Stride(0), 1280)
+		assert.Len(frame.Bytes(0), frame.Stride(0)*720)
+		pool.Put(frame)
+	}
+
+	// Released frames and buffers are re-used
+	assert.Equal(ffmpeg.FramePoolStats{Frames: 10, FrameAllocs: 1, Buffers: 10, BufferAllocs: 1}, pool.Stats())
+}
+
+func Test_framepool_002(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := ffmpeg.NewFramePool()
+	defer pool.Close()
+
+	// Audio frames need a frame size
+	_, err := pool.Get(ffmpeg.AudioPar("fltp", "stereo", 44100))
+	assert.Error(err)
+
+	par := ffmpeg.AudioPar("fltp", "stereo", 44100)
+	par.SetFrameSize(1024)
+
+	// Hold more than one frame at the same time
+	var frames []*ffmpeg.Frame
+	for i := 0; i < 4; i++ {
+		frame, err := pool.Get(par)
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		assert.Equal(AUDIO, frame.Type())
+		assert.Equal(1024, frame.NumSamples())
+		assert.Len(frame.Float32(0), 1024)
+		assert.Len(frame.Float32(1), 1024)
+		frames = append(frames, frame)
+	}
+	for _, frame := range frames {
+		pool.Put(frame)
+	}
+
+	// Frames can be released after the pool is closed
+	frame, err := pool.Get(par)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(pool.Close())
+	assert.NoError(frame.Close())
+}
+
+func Test_framepool_003(t *testing.T) {
+	assert := assert.New(t)
+
+	pool := ffmpeg.NewPacketPool()
+	defer pool.Close()
+
+	for _, size := range []int{0, 1, 100, 4096, 100000} {
+		packet, err := pool.Get(size)
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		assert.Equal(size, (*ff.AVPacket)(packet).Size())
+		pool.Put(packet)
+	}
+
+	_, err := pool.Get(-1)
+	assert.Error(err)
+}
+
+func Test_framepool_005(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Decoded frames have buffers from the decoder pool, and keep the stream
+	// dimensions
+	var video, audio int
+	assert.NoError(r.Decode(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		return par, nil
+	}, func(stream int, frame *ffmpeg.Frame) error {
+		assert.True(frame.IsAllocated())
+		switch frame.Type() {
+		case VIDEO:
+			par := r.Par(stream)
+			assert.Equal(par.Width(), frame.Width())
+			assert.Equal(par.Height(), frame.Height())
+			assert.GreaterOrEqual(frame.Stride(0), frame.Width())
+			assert.Len(frame.Bytes(0), frame.Stride(0)*frame.Height())
+			video++
+		case AUDIO:
+			assert.Greater(frame.NumSamples(), 0)
+			audio++
+		}
+		return nil
+	}))
+	assert.Greater(video, 0)
+	assert.Greater(audio, 0)
+}
+
+// Allocation of a frame and its buffers for each frame
+func Benchmark_framepool_001(b *testing.B) {
+	par := ffmpeg.VideoPar("yuv420p", "1280x720", 25)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frame, err := ffmpeg.NewFrame(par)
+		if err != nil {
+			b.Fatal(err)
+		} else if err := frame.AllocateBuffers(); err != nil {
+			b.Fatal(err)
+		}
+		frame.Close()
+	}
+}
+
+// Allocation of a frame and its buffers from a pool
+func Benchmark_framepool_002(b *testing.B) {
+	pool := ffmpeg.NewFramePool()
+	defer pool.Close()
+
+	par := ffmpeg.VideoPar("yuv420p", "1280x720", 25)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		frame, err := pool.Get(par)
+		if err != nil {
+			b.Fatal(err)
+		}
+		pool.Put(frame)
+	}
+}
+
+// Decode and resize a file, copying each frame into a frame from a pool as
+// a job which queues frames would, and report the C allocations for each
+// decoded frame. The unpooled run uses a new pool for each frame, which
+// allocates a frame and its buffers as NewFrame and AllocateBuffers do.
+func Benchmark_framepool_003(b *testing.B) {
+	b.Run("pooled", func(b *testing.B) {
+		benchmarkDecode(b, true)
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		benchmarkDecode(b, false)
+	})
+}
+
+func benchmarkDecode(b *testing.B, pooled bool) {
+	par := ffmpeg.VideoPar("rgba", "320x240", 25)
+	mapfn := func(stream int, in *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if in.Type() == VIDEO {
+			return par, nil
+		}
+		return nil, nil
+	}
+
+	var frames int
+	var stats ffmpeg.FramePoolStats
+	pool := ffmpeg.NewFramePool()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if err := r.Decode(context.Background(), mapfn, func(stream int, frame *ffmpeg.Frame) error {
+			if !pooled {
+				pool = ffmpeg.NewFramePool()
+			}
+			dest, err := pool.Get(par)
+			if err != nil {
+				return err
+			}
+			err = ff.AVUtil_frame_copy((*ff.AVFrame)(dest), (*ff.AVFrame)(frame))
+			pool.Put(dest)
+			if !pooled {
+				stats = addStats(stats, pool.Stats())
+				pool.Close()
+			}
+			frames++
+			return err
+		}); err != nil {
+			b.Fatal(err)
+		}
+		r.Close()
+	}
+	if pooled {
+		stats = pool.Stats()
+	}
+	pool.Close()
+
+	if frames > 0 {
+		b.ReportMetric(float64(frames)/float64(b.N), "frames/op")
+		b.ReportMetric(float64(stats.FrameAllocs)/float64(frames), "frame-allocs/frame")
+		b.ReportMetric(float64(stats.BufferAllocs)/float64(frames), "buffer-allocs/frame")
+	}
+}
+
+func addStats(a, b ffmpeg.FramePoolStats) ffmpeg.FramePoolStats {
+	return ffmpeg.FramePoolStats{
+		Frames:       a.Frames + b.Frames,
+		FrameAllocs:  a.FrameAllocs + b.FrameAllocs,
+		Buffers:      a.Buffers + b.Buffers,
+		BufferAllocs: a.BufferAllocs + b.BufferAllocs,
+	}
+}
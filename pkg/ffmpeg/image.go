@@ -1,7 +1,10 @@
 package ffmpeg
 
 import (
+	"encoding/binary"
 	"image"
+	"image/color"
+	"image/draw"
 
 	// Packages
 	media "github.com/mutablelogic/go-media"
@@ -17,12 +20,17 @@ import (
 
 var (
 	yuvSubsampleRatio = map[ff.AVPixelFormat]image.YCbCrSubsampleRatio{
-		ff.AV_PIX_FMT_YUV410P: image.YCbCrSubsampleRatio410,
-		ff.AV_PIX_FMT_YUV411P: image.YCbCrSubsampleRatio411,
-		ff.AV_PIX_FMT_YUV420P: image.YCbCrSubsampleRatio420,
-		ff.AV_PIX_FMT_YUV422P: image.YCbCrSubsampleRatio422,
-		ff.AV_PIX_FMT_YUV440P: image.YCbCrSubsampleRatio420,
-		ff.AV_PIX_FMT_YUV444P: image.YCbCrSubsampleRatio444,
+		ff.AV_PIX_FMT_YUV410P:  image.YCbCrSubsampleRatio410,
+		ff.AV_PIX_FMT_YUV411P:  image.YCbCrSubsampleRatio411,
+		ff.AV_PIX_FMT_YUV420P:  image.YCbCrSubsampleRatio420,
+		ff.AV_PIX_FMT_YUV422P:  image.YCbCrSubsampleRatio422,
+		ff.AV_PIX_FMT_YUV440P:  image.YCbCrSubsampleRatio440,
+		ff.AV_PIX_FMT_YUV444P:  image.YCbCrSubsampleRatio444,
+		ff.AV_PIX_FMT_YUVJ411P: image.YCbCrSubsampleRatio411,
+		ff.AV_PIX_FMT_YUVJ420P: image.YCbCrSubsampleRatio420,
+		ff.AV_PIX_FMT_YUVJ422P: image.YCbCrSubsampleRatio422,
+		ff.AV_PIX_FMT_YUVJ440P: image.YCbCrSubsampleRatio440,
+		ff.AV_PIX_FMT_YUVJ444P: image.YCbCrSubsampleRatio444,
 	}
 	pixfmtYCbCr = map[image.YCbCrSubsampleRatio]ff.AVPixelFormat{
 		image.YCbCrSubsampleRatio410: ff.AV_PIX_FMT_YUV410P,
@@ -53,13 +61,33 @@ func (frame *Frame) FromImage(src image.Image) error {
 		if pixfmt, exists := pixfmtYCbCr[src.SubsampleRatio]; exists {
 			return frame.fromYUVP(src, pixfmt)
 		}
+	case *image.Gray16: // AV_PIX_FMT_GRAY16BE
+		return frame.fromPix(src.Pix, src.Stride, 2, src.Rect, ff.AV_PIX_FMT_GRAY16BE)
+	case *image.RGBA64: // AV_PIX_FMT_RGBA64BE, with alpha un-premultiplied
+		dest := image.NewNRGBA64(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+		draw.Draw(dest, dest.Rect, src, src.Bounds().Min, draw.Src)
+		return frame.fromPix(dest.Pix, dest.Stride, 8, dest.Rect, ff.AV_PIX_FMT_RGBA64BE)
+	case *image.NRGBA64: // AV_PIX_FMT_RGBA64BE
+		return frame.fromPix(src.Pix, src.Stride, 8, src.Rect, ff.AV_PIX_FMT_RGBA64BE)
+	case *image.Paletted: // AV_PIX_FMT_PAL8
+		return frame.fromPaletted(src)
 	}
-	return ErrNotImplemented.Withf("unsupported image format: %T", src)
+	if src == nil {
+		return ErrBadParameter.With("nil image")
+	}
+
+	// Convert any other image to NRGBA
+	dest := image.NewNRGBA(image.Rect(0, 0, src.Bounds().Dx(), src.Bounds().Dy()))
+	draw.Draw(dest, dest.Rect, src, src.Bounds().Min, draw.Src)
+	return frame.fromNRGBA(dest, ff.AV_PIX_FMT_RGBA)
 }
 
-// Create an image from a frame. The frame should not be unreferenced
-// until the image is no longer required, but the image can be discarded
-// TODO: Add a copy flag which copies the memory?
+// Create an image from a frame. For RGBA, GRAY8, GRAY16BE, RGB24, RGBA64BE,
+// PAL8 and planar 8-bit YUV formats the image shares memory with the frame,
+// and the frame should not be unreferenced until the image is no longer
+// required. NV12, NV21 and GRAY16LE frames are copied, and any other pixel
+// format is converted with swscale to NRGBA, or to NRGBA64 when any component
+// has more than eight bits.
 func (frame *Frame) Image() (image.Image, error) {
 	if frame.Type() != media.VIDEO {
 		return nil, ErrBadParameter.With("unsupported frame type: ", frame.Type())
@@ -83,6 +111,31 @@ func (frame *Frame) Image() (image.Image, error) {
 			Stride: frame.Stride(0),
 			Rect:   image.Rect(0, 0, frame.Width(), frame.Height()),
 		}, nil
+	case ff.AV_PIX_FMT_GRAY16BE:
+		return &image.Gray16{
+			Pix:    frame.Bytes(0),
+			Stride: frame.Stride(0),
+			Rect:   image.Rect(0, 0, frame.Width(), frame.Height()),
+		}, nil
+	case ff.AV_PIX_FMT_RGBA64BE:
+		return &image.NRGBA64{
+			Pix:    frame.Bytes(0),
+			Stride: frame.Stride(0),
+			Rect:   image.Rect(0, 0, frame.Width(), frame.Height()),
+		}, nil
+	case ff.AV_PIX_FMT_PAL8:
+		return &image.Paletted{
+			Pix:     frame.Bytes(0),
+			Stride:  frame.Stride(0),
+			Rect:    image.Rect(0, 0, frame.Width(), frame.Height()),
+			Palette: frame.palette(),
+		}, nil
+	case ff.AV_PIX_FMT_GRAY16LE:
+		return frame.imageGray16LE(), nil
+	case ff.AV_PIX_FMT_NV12:
+		return frame.imageNV(false), nil
+	case ff.AV_PIX_FMT_NV21:
+		return frame.imageNV(true), nil
 	}
 
 	// Check planar yuv formats
@@ -98,7 +151,8 @@ func (frame *Frame) Image() (image.Image, error) {
 		}, nil
 	}
 
-	return nil, ErrNotImplemented.With("unsupported pixel format: ", frame.PixelFormat())
+	// Convert any other pixel format with swscale
+	return frame.imageScale()
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -205,15 +259,154 @@ func (frame *Frame) fromYUVP(src *image.YCbCr, pixfmt ff.AVPixelFormat) error {
 		copy(frame.Bytes(1), src.Cb)
 		copy(frame.Bytes(2), src.Cr)
 	} else {
-		for y := 0; y < src.Rect.Dy(); y++ {
-			copy(frame.Bytes(0)[y*frame.Stride(0):], src.Y[y*src.YStride:])
-			if y < src.Rect.Dy()/2 {
-				copy(frame.Bytes(1)[y*frame.Stride(1):], src.Cb[y*src.CStride:])
-				copy(frame.Bytes(2)[y*frame.Stride(2):], src.Cr[y*src.CStride:])
-			}
+		w, h := src.Rect.Dx(), src.Rect.Dy()
+		cw, ch := w, h
+		if desc := ff.AVUtil_get_pix_fmt_desc(pixfmt); desc != nil {
+			cw, ch = -((-w) >> desc.Log2ChromaW()), -((-h) >> desc.Log2ChromaH())
+		}
+		copyPlane(frame.Bytes(0), frame.Stride(0), src.Y, src.YStride, w, h)
+		copyPlane(frame.Bytes(1), frame.Stride(1), src.Cb, src.CStride, cw, ch)
+		copyPlane(frame.Bytes(2), frame.Stride(2), src.Cr, src.CStride, cw, ch)
+	}
+
+	// Return success
+	return nil
+}
+
+func (frame *Frame) fromPix(pix []byte, stride, bpp int, rect image.Rectangle, pixfmt ff.AVPixelFormat) error {
+	if err := frame.fromImage(pixfmt, rect.Dx(), rect.Dy(), ff.AVUtil_rational(1, 1)); err != nil {
+		return err
+	}
+	copyPlane(frame.Bytes(0), frame.Stride(0), pix, stride, rect.Dx()*bpp, rect.Dy())
+
+	// Return success
+	return nil
+}
+
+func (frame *Frame) fromPaletted(src *image.Paletted) error {
+	if len(src.Palette) > ff.AVPALETTE_SIZE>>2 {
+		return ErrBadParameter.Withf("palette has %d colors", len(src.Palette))
+	} else if err := frame.fromPix(src.Pix, src.Stride, 1, src.Rect, ff.AV_PIX_FMT_PAL8); err != nil {
+		return err
+	}
+
+	// The palette is stored as native-endian uint32 ARGB values
+	palette := (*ff.AVFrame)(frame).Uint32(1)
+	for i := range palette {
+		palette[i] = 0
+		if i < len(src.Palette) {
+			c := color.NRGBAModel.Convert(src.Palette[i]).(color.NRGBA)
+			palette[i] = uint32(c.A)<<24 | uint32(c.R)<<16 | uint32(c.G)<<8 | uint32(c.B)
 		}
 	}
 
 	// Return success
 	return nil
 }
+
+// Return the palette for a PAL8 frame
+func (frame *Frame) palette() color.Palette {
+	data := (*ff.AVFrame)(frame).Uint32(1)
+	palette := make(color.Palette, len(data))
+	for i, v := range data {
+		palette[i] = color.NRGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: uint8(v >> 24)}
+	}
+	return palette
+}
+
+// Return a copy of a GRAY16LE frame
+func (frame *Frame) imageGray16LE() image.Image {
+	w, h := frame.Width(), frame.Height()
+	dest := image.NewGray16(image.Rect(0, 0, w, h))
+	src := frame.Bytes(0)
+	for y := 0; y < h; y++ {
+		row := src[y*frame.Stride(0):]
+		for x := 0; x < w; x++ {
+			binary.BigEndian.PutUint16(dest.Pix[y*dest.Stride+x*2:], binary.LittleEndian.Uint16(row[x*2:]))
+		}
+	}
+	return dest
+}
+
+// Return a copy of an NV12 frame, or an NV21 frame when swap is true
+func (frame *Frame) imageNV(swap bool) image.Image {
+	w, h := frame.Width(), frame.Height()
+	dest := image.NewYCbCr(image.Rect(0, 0, w, h), image.YCbCrSubsampleRatio420)
+	copyPlane(dest.Y, dest.YStride, frame.Bytes(0), frame.Stride(0), w, h)
+
+	// De-interleave the chroma plane
+	cb, cr := dest.Cb, dest.Cr
+	if swap {
+		cb, cr = cr, cb
+	}
+	src := frame.Bytes(1)
+	for y := 0; y < (h+1)>>1; y++ {
+		row := src[y*frame.Stride(1):]
+		for x := 0; x < (w+1)>>1; x++ {
+			cb[y*dest.CStride+x] = row[x*2]
+			cr[y*dest.CStride+x] = row[x*2+1]
+		}
+	}
+	return dest
+}
+
+// Convert the frame with swscale, to NRGBA64 when any component has more
+// than eight bits, or NRGBA otherwise
+func (frame *Frame) imageScale() (image.Image, error) {
+	desc := ff.AVUtil_get_pix_fmt_desc(frame.PixelFormat())
+	if desc == nil || desc.Flags().Is(ff.AV_PIX_FMT_FLAG_HWACCEL) {
+		return nil, ErrNotImplemented.With("unsupported pixel format: ", frame.PixelFormat())
+	}
+	pixfmt := ff.AV_PIX_FMT_RGBA
+	for i := 0; i < desc.NumComponents(); i++ {
+		if desc.Depth(i) > 8 {
+			pixfmt = ff.AV_PIX_FMT_RGBA64BE
+		}
+	}
+
+	// Allocate the destination frame
+	w, h := frame.Width(), frame.Height()
+	dest := ff.AVUtil_frame_alloc()
+	if dest == nil {
+		return nil, ErrInternalAppError.With("failed to allocate frame")
+	}
+	defer ff.AVUtil_frame_free(dest)
+	dest.SetPixFmt(pixfmt)
+	dest.SetWidth(w)
+	dest.SetHeight(h)
+	if err := ff.AVUtil_frame_get_buffer(dest, false); err != nil {
+		return nil, err
+	}
+
+	// Convert the frame
+	ctx := ff.SWScale_get_context(w, h, frame.PixelFormat(), w, h, pixfmt, ff.SWS_BILINEAR, nil, nil, nil)
+	if ctx == nil {
+		return nil, ErrNotImplemented.With("unsupported pixel format: ", frame.PixelFormat())
+	}
+	defer ff.SWScale_free_context(ctx)
+	if err := ff.SWScale_scale_frame(ctx, dest, (*ff.AVFrame)(frame), false); err != nil {
+		return nil, err
+	}
+
+	// Copy the destination frame into the image
+	rect := image.Rect(0, 0, w, h)
+	if pixfmt == ff.AV_PIX_FMT_RGBA64BE {
+		img := image.NewNRGBA64(rect)
+		copyPlane(img.Pix, img.Stride, dest.Bytes(0), dest.Linesize(0), w*8, h)
+		return img, nil
+	}
+	img := image.NewNRGBA(rect)
+	copyPlane(img.Pix, img.Stride, dest.Bytes(0), dest.Linesize(0), w*4, h)
+	return img, nil
+}
+
+// Copy rows of n bytes between planes with different strides
+func copyPlane(dst []byte, dstStride int, src []byte, srcStride int, n, rows int) {
+	if dstStride == srcStride && len(dst) >= rows*dstStride && len(src) >= rows*srcStride {
+		copy(dst[:rows*dstStride], src)
+		return
+	}
+	for y := 0; y < rows; y++ {
+		copy(dst[y*dstStride:y*dstStride+n], src[y*srcStride:y*srcStride+n])
+	}
+}
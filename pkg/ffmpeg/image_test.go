@@ -1,6 +1,7 @@
 package ffmpeg_test
 
 import (
+	"encoding/binary"
 	"image"
 	"image/color"
 	"image/jpeg"
@@ -197,3 +198,159 @@ func Test_image_004(t *testing.T) {
 		}
 	}
 }
+
+func Test_image_005(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create 16-bit and paletted images
+	rect := image.Rect(0, 0, 31, 17)
+	gray16 := image.NewGray16(rect)
+	nrgba64 := image.NewNRGBA64(rect)
+	paletted := image.NewPaletted(rect, color.Palette{color.Black, color.White, color.NRGBA{0xFF, 0, 0, 0x80}})
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			gray16.SetGray16(x, y, color.Gray16{uint16(x * y * 100)})
+			nrgba64.SetNRGBA64(x, y, color.NRGBA64{uint16(x * 1000), uint16(y * 1000), uint16(x * y), 0xFFFF})
+			paletted.SetColorIndex(x, y, uint8((x+y)%3))
+		}
+	}
+
+	for _, source := range []image.Image{gray16, nrgba64, paletted} {
+		frame, err := ffmpeg.NewFrame(nil)
+		if !assert.NoError(err) {
+			t.FailNow()
+		} else if err := frame.FromImage(source); !assert.NoError(err) {
+			t.FailNow()
+		}
+		defer frame.Close()
+		t.Log(frame)
+
+		// Create a new image from the frame
+		dest, err := frame.Image()
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		assert.IsType(source, dest)
+
+		// Compare the two images
+		for y := 0; y < frame.Height(); y++ {
+			for x := 0; x < frame.Width(); x++ {
+				r1, g1, b1, a1 := source.At(x, y).RGBA()
+				r2, g2, b2, a2 := dest.At(x, y).RGBA()
+				assert.Equal([]uint32{r1, g1, b1, a1}, []uint32{r2, g2, b2, a2})
+			}
+		}
+	}
+}
+
+func Test_image_006(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create an NV12 frame
+	frame, err := ffmpeg.NewFrame(ffmpeg.VideoPar("nv12", "64x48", 25))
+	if !assert.NoError(err) {
+		t.FailNow()
+	} else if err := frame.AllocateBuffers(); !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+
+	// Set luma, and interleaved chroma
+	for i := range frame.Bytes(0) {
+		frame.Bytes(0)[i] = 100
+	}
+	for i := range frame.Bytes(1) {
+		frame.Bytes(1)[i] = uint8(50 + (i&1)*100)
+	}
+
+	// Convert to an image
+	dest, err := frame.Image()
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	if assert.IsType(&image.YCbCr{}, dest) {
+		c := dest.(*image.YCbCr).YCbCrAt(10, 10)
+		assert.Equal(color.YCbCr{100, 50, 150}, c)
+	}
+}
+
+func Test_image_007(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create a 10-bit frame
+	frame, err := ffmpeg.NewFrame(ffmpeg.VideoPar("yuv420p10le", "64x48", 25))
+	if !assert.NoError(err) {
+		t.FailNow()
+	} else if err := frame.AllocateBuffers(); !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+
+	// Set to white, which is 940 in limited range, with neutral chroma
+	for plane, value := range []uint16{940, 512, 512} {
+		data := frame.Bytes(plane)
+		for i := 0; i+1 < len(data); i += 2 {
+			binary.LittleEndian.PutUint16(data[i:], value)
+		}
+	}
+
+	// Convert to an image, which is converted with swscale
+	dest, err := frame.Image()
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	if assert.IsType(&image.NRGBA64{}, dest) {
+		assert.Equal(image.Rect(0, 0, 64, 48), dest.Bounds())
+		r, g, b, a := dest.At(32, 24).RGBA()
+		assert.Greater(r, uint32(0xF000))
+		assert.Greater(g, uint32(0xF000))
+		assert.Greater(b, uint32(0xF000))
+		assert.Equal(uint32(0xFFFF), a)
+	}
+}
+
+func Test_image_008(t *testing.T) {
+	assert := assert.New(t)
+
+	// Create a 16-bit image with premultiplied partial alpha
+	rect := image.Rect(0, 0, 31, 17)
+	rgba64 := image.NewRGBA64(rect)
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			a := uint16(x * 2000)
+			rgba64.SetRGBA64(x, y, color.RGBA64{uint16(uint32(y*3000) * uint32(a) / 0xFFFF), a / 2, a / 4, a})
+		}
+	}
+
+	frame, err := ffmpeg.NewFrame(nil)
+	if !assert.NoError(err) {
+		t.FailNow()
+	} else if err := frame.FromImage(rgba64); !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+
+	// The frame is not premultiplied
+	dest, err := frame.Image()
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	if assert.IsType(&image.NRGBA64{}, dest) {
+		c := dest.(*image.NRGBA64).NRGBA64At(16, 0)
+		assert.Equal(uint16(32000), c.A)
+		assert.InDelta(0x7FFF, c.G, 1)
+		assert.InDelta(0x3FFF, c.B, 1)
+	}
+
+	// Compare the two images, allowing for rounding when un-premultiplying
+	for y := 0; y < rect.Dy(); y++ {
+		for x := 0; x < rect.Dx(); x++ {
+			r1, g1, b1, a1 := rgba64.At(x, y).RGBA()
+			r2, g2, b2, a2 := dest.At(x, y).RGBA()
+			assert.Equal(a1, a2)
+			assert.InDelta(r1, r2, 1)
+			assert.InDelta(g1, g2, 1)
+			assert.InDelta(b1, b2, 1)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"syscall"
+	"time"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// Index of the packets in each stream, built by scanning the packets without
+// decoding. The index can be serialized to JSON, and loaded into a reader
+// for the same media to speed up seeking.
+type Index struct {
+	Streams []*StreamIndex `json:"streams"`
+}
+
+// Packet counts and keyframes for a stream
+type StreamIndex struct {
+	Stream    int           `json:"stream"`
+	TimeBase  ff.AVRational `json:"timebase"`
+	Packets   int           `json:"packets"`
+	Bytes     int64         `json:"bytes"`
+	Keyframes []IndexEntry  `json:"keyframes,omitempty"`
+}
+
+// Keyframe packet number within the stream, timestamps in the stream
+// timebase, byte offset and size
+type IndexEntry struct {
+	Packet int   `json:"packet"`
+	Pts    int64 `json:"pts"`
+	Dts    int64 `json:"dts"`
+	Pos    int64 `json:"pos"`
+	Size   int   `json:"size"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (index *Index) String() string {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - READER
+
+// Scan the packets in each stream without decoding, and return the index.
+// The scanning can be interrupted by cancelling the context. If the input is
+// seekable, the reader is rewound to the start afterwards.
+func (r *Reader) Index(ctx context.Context) (*Index, error) {
+	index := new(Index)
+	streams := make(map[int]*StreamIndex, r.input.NumStreams())
+	for _, stream := range r.input.Streams() {
+		s := &StreamIndex{
+			Stream:   stream.Index(),
+			TimeBase: stream.TimeBase(),
+		}
+		index.Streams = append(index.Streams, s)
+		streams[s.Stream] = s
+	}
+
+	// Allocate a packet
+	packet := ff.AVCodec_packet_alloc()
+	if packet == nil {
+		return nil, errors.New("failed to allocate packet")
+	}
+	defer ff.AVCodec_packet_free(packet)
+
+	// Read packets
+FOR_LOOP:
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			if err := ff.AVFormat_read_frame(r.input, packet); errors.Is(err, io.EOF) {
+				break FOR_LOOP
+			} else if errors.Is(err, syscall.EAGAIN) {
+				continue FOR_LOOP
+			} else if err != nil {
+				return nil, ErrInternalAppError.With("AVFormat_read_frame: ", err)
+			}
+			if s, exists := streams[packet.StreamIndex()]; exists {
+				if packet.Flags().Is(ff.AV_PKT_FLAG_KEY) {
+					s.Keyframes = append(s.Keyframes, IndexEntry{
+						Packet: s.Packets,
+						Pts:    packet.Pts(),
+						Dts:    packet.Dts(),
+						Pos:    packet.Pos(),
+						Size:   packet.Size(),
+					})
+				}
+				s.Packets++
+				s.Bytes += int64(packet.Size())
+			}
+		}
+
+		// Unreference the packet
+		ff.AVCodec_packet_unref(packet)
+	}
+
+	// Rewind to the start, ignoring errors for inputs which are not seekable
+	ff.AVFormat_seek_frame(r.input, -1, 0, ff.AVSEEK_FLAG_BACKWARD)
+
+	// Return success
+	return index, nil
+}
+
+// Load the keyframes from an index into the demuxer, to speed up seeking in
+// media which has no index of its own. The index should have been created
+// from the same media.
+func (r *Reader) LoadIndex(index *Index) error {
+	if index == nil {
+		return ErrBadParameter.With("nil index")
+	}
+	for _, s := range index.Streams {
+		stream := r.input.Stream(s.Stream)
+		if stream == nil {
+			return ErrBadParameter.Withf("invalid stream %v", s.Stream)
+		}
+		for _, entry := range s.Keyframes {
+			ts := entry.pts()
+			if ts == ff.AV_NOPTS_VALUE || entry.Pos < 0 {
+				continue
+			}
+			if !s.TimeBase.IsZero() && !ff.AVUtil_rational_equal(s.TimeBase, stream.TimeBase()) {
+				ts = ff.AVUtil_rational_rescale_q(ts, s.TimeBase, stream.TimeBase())
+			}
+			if err := ff.AVFormat_add_index_entry(stream, entry.Pos, ts, entry.Size, 0, ff.AVINDEX_KEYFRAME); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - STREAM INDEX
+
+// Return the timestamp of an entry
+func (s *StreamIndex) Ts(entry IndexEntry) time.Duration {
+	ts := entry.pts()
+	if ts == ff.AV_NOPTS_VALUE || s.TimeBase.IsZero() {
+		return -1
+	}
+	return time.Duration(ff.AVUtil_rational_rescale_q(ts, s.TimeBase, ff.AVUtil_rational(1, int(time.Second))))
+}
+
+// Return the last keyframe at or before the timestamp, or false if there is
+// no keyframe before the timestamp
+func (s *StreamIndex) Keyframe(ts time.Duration) (IndexEntry, bool) {
+	var result IndexEntry
+	var found bool
+	for _, entry := range s.Keyframes {
+		if t := s.Ts(entry); t < 0 {
+			continue
+		} else if t > ts {
+			break
+		}
+		result, found = entry, true
+	}
+	return result, found
+}
+
+// Return the largest interval between consecutive keyframes, which can be
+// used to detect media with sparse keyframes. Returns zero if there are fewer
+// than two keyframes.
+func (s *StreamIndex) MaxKeyframeInterval() time.Duration {
+	var result time.Duration
+	last := time.Duration(-1)
+	for _, entry := range s.Keyframes {
+		t := s.Ts(entry)
+		if t < 0 {
+			continue
+		} else if last >= 0 {
+			result = max(result, t-last)
+		}
+		last = t
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return the presentation timestamp, or the decoding timestamp if unset
+func (entry IndexEntry) pts() int64 {
+	if entry.Pts == ff.AV_NOPTS_VALUE {
+		return entry.Dts
+	}
+	return entry.Pts
+}
@@ -0,0 +1,103 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_index_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Index the packets
+	index, err := r.Index(context.Background())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	video := r.BestStream(media.VIDEO)
+	if !assert.Greater(len(index.Streams), video) {
+		t.FailNow()
+	}
+	s := index.Streams[video]
+	assert.Equal(video, s.Stream)
+	assert.NotZero(s.Packets)
+	assert.NotZero(s.Bytes)
+	assert.NotEmpty(s.Keyframes)
+	for _, entry := range s.Keyframes {
+		assert.GreaterOrEqual(entry.Pos, int64(0))
+		assert.NotZero(entry.Size)
+	}
+	t.Log("max keyframe interval=", s.MaxKeyframeInterval())
+
+	// Find the keyframe at the start
+	entry, found := s.Keyframe(0)
+	assert.True(found)
+	assert.Equal(s.Keyframes[0], entry)
+	entry, found = s.Keyframe(time.Hour)
+	assert.True(found)
+	assert.Equal(s.Keyframes[len(s.Keyframes)-1], entry)
+
+	// The reader is rewound, so the packets can be read again
+	var n int
+	assert.NoError(r.Demux(context.Background(), nil, func(stream int, packet *ffmpeg.Packet) error {
+		if stream == video {
+			n++
+		}
+		return nil
+	}))
+	assert.Equal(s.Packets, n)
+}
+
+func Test_index_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Index the packets, and serialize to JSON
+	index, err := r.Index(context.Background())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	data, err := json.Marshal(index)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Reload the index
+	var index2 ffmpeg.Index
+	if !assert.NoError(json.Unmarshal(data, &index2)) {
+		t.FailNow()
+	}
+	assert.Equal(index, &index2)
+
+	// Load the index into another reader and seek
+	r2, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+	assert.NoError(r2.LoadIndex(&index2))
+	assert.NoError(r2.Seek(time.Second, -1, ffmpeg.SEEK_BACKWARD))
+
+	// Invalid index
+	assert.Error(r2.LoadIndex(nil))
+	assert.Error(r2.LoadIndex(&ffmpeg.Index{Streams: []*ffmpeg.StreamIndex{{Stream: 99}}}))
+}
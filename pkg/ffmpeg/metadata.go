@@ -21,15 +21,47 @@ type meta struct {
 
 type Metadata struct {
 	meta
+	picture PictureType
 }
 
+// PictureType is the type of artwork, which is stored as the "comment"
+// on attached picture streams for formats which support it.
+type PictureType string
+
 var _ media.Metadata = (*Metadata)(nil)
 
 ////////////////////////////////////////////////////////////////////////////////
 // GLOBALS
 
 const (
-	MetaArtwork = "artwork" // Metadata key for artwork, set the value as []byte
+	MetaArtwork  = "artwork"  // Metadata key for artwork, set the value as []byte
+	MetaTitle    = "title"    // Metadata key for the title
+	MetaLanguage = "language" // Metadata key for the stream language, as an ISO 639-2 code
+)
+
+// Picture types, as defined by the ID3v2 APIC frame
+const (
+	PictureOther             PictureType = "Other"
+	PictureFileIcon          PictureType = "32x32 pixels 'file icon'"
+	PictureOtherFileIcon     PictureType = "Other file icon"
+	PictureCoverFront        PictureType = "Cover (front)"
+	PictureCoverBack         PictureType = "Cover (back)"
+	PictureLeaflet           PictureType = "Leaflet page"
+	PictureMedia             PictureType = "Media (e.g. label side of CD)"
+	PictureLeadArtist        PictureType = "Lead artist/lead performer/soloist"
+	PictureArtist            PictureType = "Artist/performer"
+	PictureConductor         PictureType = "Conductor"
+	PictureBand              PictureType = "Band/Orchestra"
+	PictureComposer          PictureType = "Composer"
+	PictureLyricist          PictureType = "Lyricist/text writer"
+	PictureRecordingLocation PictureType = "Recording Location"
+	PictureDuringRecording   PictureType = "During recording"
+	PictureDuringPerformance PictureType = "During performance"
+	PictureScreenCapture     PictureType = "Movie/video screen capture"
+	PictureFish              PictureType = "A bright coloured fish"
+	PictureIllustration      PictureType = "Illustration"
+	PictureBandLogo          PictureType = "Band/artist logotype"
+	PicturePublisherLogo     PictureType = "Publisher/Studio logotype"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -45,6 +77,21 @@ func NewMetadata(key string, value any) *Metadata {
 	}
 }
 
+// Artwork implementation, with image data and a picture type. If the picture
+// type is empty, then the artwork is the front cover.
+func NewArtwork(data []byte, picture PictureType) *Metadata {
+	if picture == "" {
+		picture = PictureCoverFront
+	}
+	return &Metadata{
+		meta: meta{
+			Key:   MetaArtwork,
+			Value: data,
+		},
+		picture: picture,
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -92,6 +139,17 @@ func (m *Metadata) Bytes() []byte {
 	return nil
 }
 
+// Returns the picture type for artwork, or an empty string if the
+// metadata is not artwork
+func (m *Metadata) PictureType() PictureType {
+	if m.meta.Key != MetaArtwork {
+		return ""
+	} else if m.picture == "" {
+		return PictureCoverFront
+	}
+	return m.picture
+}
+
 // Returns the value as an image
 func (m *Metadata) Image() image.Image {
 	if m.meta.Value == nil {
@@ -1,6 +1,8 @@
 package ffmpeg
 
 import (
+	"time"
+
 	// Package imports
 	media "github.com/mutablelogic/go-media"
 	ffmpeg "github.com/mutablelogic/go-media/sys/ffmpeg61"
@@ -27,9 +29,22 @@ type opts struct {
 	force bool
 
 	// Writer options
-	oformat  *ffmpeg.AVOutputFormat
-	streams  map[int]*Par
-	metadata []*Metadata
+	oformat     *ffmpeg.AVOutputFormat
+	streams     map[int]*Par
+	copies      map[int]bool   // Streams which are copied rather than encoded
+	bsf         map[int]string // Bitstream filters for copied streams
+	metadata    []*Metadata
+	chapters    []*Chapter
+	inputchaps  []*Chapter          // Chapters of the input media, when remuxing or transcoding
+	nochapters  bool                // Do not write the chapters of the input media
+	streammeta  map[int][]*Metadata // Metadata for output streams
+	disposition map[int]Disposition // Disposition for output streams
+	muxopts     []string            // These are key=value pairs
+	fragment    WriterFragmentFn
+	pass        int            // Encoding pass for video streams, or zero
+	stats       map[int]string // First pass statistics for the second pass
+	passlog     string         // Directory for encoder statistics files
+	twopass     bool           // Transcode with two passes
 
 	// Reader options
 	t       media.Type
@@ -42,7 +57,11 @@ type opts struct {
 
 func newOpts() *opts {
 	return &opts{
-		streams: make(map[int]*Par),
+		streams:     make(map[int]*Par),
+		copies:      make(map[int]bool),
+		bsf:         make(map[int]string),
+		streammeta:  make(map[int][]*Metadata),
+		disposition: make(map[int]Disposition),
 	}
 }
 
@@ -109,24 +128,90 @@ func OptInputOpt(opt ...string) Opt {
 	}
 }
 
+// Output format options, which are passed to the muxer when writing
+// the header, for example "movflags=frag_keyframe+empty_moov"
+func OptOutputOpt(opt ...string) Opt {
+	return func(o *opts) error {
+		o.muxopts = append(o.muxopts, opt...)
+		return nil
+	}
+}
+
+// Set a function which is called at each fragment boundary when writing
+// fragmented output, before the fragment is written
+func OptFragment(fn WriterFragmentFn) Opt {
+	return func(o *opts) error {
+		o.fragment = fn
+		return nil
+	}
+}
+
 // New stream with parameters
 func OptStream(stream int, par *Par) Opt {
 	return func(o *opts) error {
-		if par == nil {
-			return ErrBadParameter.With("invalid parameters")
+		_, err := o.addStream(stream, par)
+		return err
+	}
+}
+
+// New stream which copies packets from an input stream without re-encoding,
+// using the codec parameters of the input stream (for example, from Reader.Par)
+func OptStreamCopy(stream int, par *Par) Opt {
+	return func(o *opts) error {
+		stream, err := o.addStream(stream, par)
+		if err != nil {
+			return err
 		}
-		if stream == 0 {
-			stream = len(o.streams) + 1
+		o.copies[stream] = true
+		return nil
+	}
+}
+
+// Apply a bitstream filter (for example, "h264_mp4toannexb") to packets
+// written to a copied stream with WritePacket. The codec parameters of the
+// output stream are set from the output of the filter.
+func OptBitstreamFilter(stream int, desc string) Opt {
+	return func(o *opts) error {
+		if stream <= 0 {
+			return ErrBadParameter.Withf("invalid stream %v", stream)
 		}
-		if _, exists := o.streams[stream]; exists {
-			return ErrDuplicateEntry.Withf("stream %v", stream)
+		if _, exists := o.bsf[stream]; exists {
+			return ErrDuplicateEntry.Withf("bitstream filter for stream %v", stream)
 		}
-		if stream < 0 {
-			return ErrBadParameter.Withf("invalid stream %v", stream)
+		o.bsf[stream] = desc
+		return nil
+	}
+}
+
+// Set the encoding pass for video streams, which is 1 or 2. After the first
+// pass, the statistics for each stream are returned by Writer.Stats, and are
+// passed to the second pass with the same stream identifiers.
+func OptPass(pass int, stats map[int]string) Opt {
+	return func(o *opts) error {
+		if pass != 1 && pass != 2 {
+			return ErrBadParameter.Withf("invalid pass %v", pass)
 		}
-		o.streams[stream] = par
+		o.pass = pass
+		o.stats = stats
+		return nil
+	}
+}
 
-		// Return success
+// Transcode video streams with two passes, for a predictable bitrate. The
+// input is read twice, so must be seekable. Use with Reader.Transcode and
+// Reader.TranscodeFile.
+func OptTwoPass() Opt {
+	return func(o *opts) error {
+		o.twopass = true
+		return nil
+	}
+}
+
+// Set the directory for statistics files, for encoders (for example,
+// libx264) which write their own statistics
+func optPassLog(dir string) Opt {
+	return func(o *opts) error {
+		o.passlog = dir
 		return nil
 	}
 }
@@ -162,6 +247,80 @@ func OptForce() Opt {
 	}
 }
 
+// Append a chapter to the output file, with start and end time and
+// optional metadata such as the chapter title
+func OptChapter(start, end time.Duration, metadata ...*Metadata) Opt {
+	return OptChapters(NewChapter(start, end, metadata...))
+}
+
+// Append chapters to the output file. When transcoding, or when the input
+// chapters are set with OptInputChapters, the chapters of the input media
+// are written unless chapters are set with this option or OptNoChapters is
+// used.
+func OptChapters(chapters ...*Chapter) Opt {
+	return func(o *opts) error {
+		for _, chapter := range chapters {
+			if chapter == nil {
+				return ErrBadParameter.With("nil chapter")
+			} else if chapter.Start() < 0 || chapter.End() < chapter.Start() {
+				return ErrBadParameter.Withf("invalid chapter %v-%v", chapter.Start(), chapter.End())
+			}
+		}
+		o.chapters = append(o.chapters, chapters...)
+		return nil
+	}
+}
+
+// Do not write the chapters of the input media when remuxing or transcoding
+func OptNoChapters() Opt {
+	return func(o *opts) error {
+		o.nochapters = true
+		return nil
+	}
+}
+
+// Set the chapters of the input media (for example, from Reader.Chapters)
+// when remuxing, which are written unless other chapters are set
+func OptInputChapters(chapters ...*Chapter) Opt {
+	return func(o *opts) error {
+		o.inputchaps = chapters
+		return nil
+	}
+}
+
+// Append metadata to an output stream
+func OptStreamMetadata(stream int, entry ...*Metadata) Opt {
+	return func(o *opts) error {
+		o.streammeta[stream] = append(o.streammeta[stream], entry...)
+		return nil
+	}
+}
+
+// Set the language of an output stream, as a three-letter ISO 639-2 code
+// (for example, "eng" or "fra")
+func OptStreamLanguage(stream int, language string) Opt {
+	return func(o *opts) error {
+		if !isLanguageCode(language) {
+			return ErrBadParameter.Withf("invalid ISO 639-2 language code %q", language)
+		}
+		return OptStreamMetadata(stream, NewMetadata(MetaLanguage, language))(o)
+	}
+}
+
+// Set the title of an output stream
+func OptStreamTitle(stream int, title string) Opt {
+	return OptStreamMetadata(stream, NewMetadata(MetaTitle, title))
+}
+
+// Set the disposition flags of an output stream, for example
+// DISPOSITION_DEFAULT, DISPOSITION_FORCED or DISPOSITION_HEARING_IMPAIRED
+func OptStreamDisposition(stream int, flags Disposition) Opt {
+	return func(o *opts) error {
+		o.disposition[stream] |= flags
+		return nil
+	}
+}
+
 // Append metadata to the output file, including artwork
 func OptMetadata(entry ...*Metadata) Opt {
 	return func(o *opts) error {
@@ -169,3 +328,40 @@ func OptMetadata(entry ...*Metadata) Opt {
 		return nil
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Add a stream with parameters, and return the stream identifier. If the
+// stream is zero, the next identifier is used.
+func (o *opts) addStream(stream int, par *Par) (int, error) {
+	if par == nil {
+		return 0, ErrBadParameter.With("invalid parameters")
+	}
+	if stream == 0 {
+		stream = len(o.streams) + 1
+	}
+	if _, exists := o.streams[stream]; exists {
+		return 0, ErrDuplicateEntry.Withf("stream %v", stream)
+	}
+	if stream < 0 {
+		return 0, ErrBadParameter.Withf("invalid stream %v", stream)
+	}
+	o.streams[stream] = par
+
+	// Return success
+	return stream, nil
+}
+
+// Return true if the language is a three-letter lowercase code
+func isLanguageCode(language string) bool {
+	if len(language) != 3 {
+		return false
+	}
+	for _, c := range language {
+		if c < 'a' || c > 'z' {
+			return false
+		}
+	}
+	return true
+}
@@ -5,6 +5,9 @@ import (
 
 	// Packages
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
 )
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -12,6 +15,46 @@ import (
 
 type Packet ff.AVPacket
 
+///////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a new packet with a copy of the data and the timestamps, which
+// should be released with Close. Use PTS_UNDEFINED when a timestamp is not
+// known.
+func NewPacket(data []byte, pts, dts int64) (*Packet, error) {
+	packet := ff.AVCodec_packet_alloc()
+	if packet == nil {
+		return nil, ErrInternalAppError.With("failed to allocate packet")
+	}
+	if len(data) > 0 {
+		if err := ff.AVCodec_packet_from_bytes(packet, data); err != nil {
+			ff.AVCodec_packet_free(packet)
+			return nil, err
+		}
+	}
+	packet.SetPts(pts)
+	packet.SetDts(dts)
+
+	// Return success
+	return (*Packet)(packet), nil
+}
+
+// Release packet resources
+func (packet *Packet) Close() error {
+	ff.AVCodec_packet_free((*ff.AVPacket)(packet))
+	return nil
+}
+
+// Return a new packet which references the same data and has the same
+// properties, which should be released with Close
+func (packet *Packet) Clone() (*Packet, error) {
+	clone := ff.AVCodec_packet_clone((*ff.AVPacket)(packet))
+	if clone == nil {
+		return nil, ErrInternalAppError.With("failed to clone packet")
+	}
+	return (*Packet)(clone), nil
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -37,3 +80,138 @@ func (packet *Packet) Ts() float64 {
 		return ff.AVUtil_rational_q2d(tb) * float64(pts)
 	}
 }
+
+// Return a copy of the packet data
+func (packet *Packet) Bytes() []byte {
+	return (*ff.AVPacket)(packet).Bytes()
+}
+
+// Return the size of the packet data in bytes
+func (packet *Packet) Size() int {
+	return (*ff.AVPacket)(packet).Size()
+}
+
+// Return the presentation timestamp in the packet timebase, or PTS_UNDEFINED
+func (packet *Packet) Pts() int64 {
+	return (*ff.AVPacket)(packet).Pts()
+}
+
+// Set the presentation timestamp in the packet timebase
+func (packet *Packet) SetPts(pts int64) {
+	(*ff.AVPacket)(packet).SetPts(pts)
+}
+
+// Return the decoding timestamp in the packet timebase, or PTS_UNDEFINED
+func (packet *Packet) Dts() int64 {
+	return (*ff.AVPacket)(packet).Dts()
+}
+
+// Set the decoding timestamp in the packet timebase
+func (packet *Packet) SetDts(dts int64) {
+	(*ff.AVPacket)(packet).SetDts(dts)
+}
+
+// Return the duration in the packet timebase, or zero if unknown
+func (packet *Packet) Duration() int64 {
+	return (*ff.AVPacket)(packet).Duration()
+}
+
+// Set the duration in the packet timebase
+func (packet *Packet) SetDuration(duration int64) {
+	(*ff.AVPacket)(packet).SetDuration(duration)
+}
+
+// Return the timebase of the timestamps and duration
+func (packet *Packet) TimeBase() ff.AVRational {
+	return (*ff.AVPacket)(packet).TimeBase()
+}
+
+// Set the timebase of the timestamps and duration, without changing them
+func (packet *Packet) SetTimeBase(tb ff.AVRational) {
+	(*ff.AVPacket)(packet).SetTimeBase(tb)
+}
+
+// Return the stream index
+func (packet *Packet) StreamIndex() int {
+	return (*ff.AVPacket)(packet).StreamIndex()
+}
+
+// Set the stream index
+func (packet *Packet) SetStreamIndex(index int) {
+	(*ff.AVPacket)(packet).SetStreamIndex(index)
+}
+
+// Return the byte position in the input, or -1 if unknown
+func (packet *Packet) Pos() int64 {
+	return (*ff.AVPacket)(packet).Pos()
+}
+
+// Return the packet flags
+func (packet *Packet) Flags() ff.AVPacketFlag {
+	return (*ff.AVPacket)(packet).Flags()
+}
+
+// Set the packet flags
+func (packet *Packet) SetFlags(flags ff.AVPacketFlag) {
+	(*ff.AVPacket)(packet).SetFlags(flags)
+}
+
+// Return true if the packet contains a keyframe
+func (packet *Packet) IsKeyFrame() bool {
+	return packet.Flags().Is(ff.AV_PKT_FLAG_KEY)
+}
+
+// Set or clear the keyframe flag
+func (packet *Packet) SetKeyFrame(key bool) {
+	if key {
+		packet.SetFlags(packet.Flags() | ff.AV_PKT_FLAG_KEY)
+	} else {
+		packet.SetFlags(packet.Flags() &^ ff.AV_PKT_FLAG_KEY)
+	}
+}
+
+// Return true if the packet content is corrupted
+func (packet *Packet) IsCorrupt() bool {
+	return packet.Flags().Is(ff.AV_PKT_FLAG_CORRUPT)
+}
+
+// Return true if the packet is required to maintain the decoder state, but
+// is not required for output
+func (packet *Packet) IsDiscard() bool {
+	return packet.Flags().Is(ff.AV_PKT_FLAG_DISCARD)
+}
+
+// Return the types of side data in the packet
+func (packet *Packet) SideDataTypes() []ff.AVPacketSideDataType {
+	return (*ff.AVPacket)(packet).SideDataTypes()
+}
+
+// Return a copy of the side data of a type, or nil if the packet does not
+// contain the side data
+func (packet *Packet) SideData(t ff.AVPacketSideDataType) []byte {
+	if data := ff.AVCodec_packet_get_side_data((*ff.AVPacket)(packet), t); data != nil {
+		return append([]byte{}, data...)
+	}
+	return nil
+}
+
+// Set the side data of a type, replacing any existing side data of the
+// same type
+func (packet *Packet) SetSideData(t ff.AVPacketSideDataType, data []byte) error {
+	return ff.AVCodec_packet_add_side_data((*ff.AVPacket)(packet), t, data)
+}
+
+// Rescale the timestamps and duration from the packet timebase to another
+// timebase, and set the packet timebase
+func (packet *Packet) Rescale(tb ff.AVRational) error {
+	pkt := (*ff.AVPacket)(packet)
+	if src := pkt.TimeBase(); src.Num() == 0 || src.Den() == 0 {
+		return ErrBadParameter.With("packet timebase is not set")
+	} else if tb.Num() == 0 || tb.Den() == 0 {
+		return ErrBadParameter.With("invalid timebase: ", tb)
+	} else {
+		ff.AVCodec_packet_rescale_ts(pkt, src, tb)
+	}
+	pkt.SetTimeBase(tb)
+	return nil
+}
@@ -0,0 +1,90 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"testing"
+
+	// Packages
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_packet_001(t *testing.T) {
+	assert := assert.New(t)
+
+	data := []byte("hello, world")
+	packet, err := ffmpeg.NewPacket(data, 100, 90)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer packet.Close()
+
+	assert.Equal(data, packet.Bytes())
+	assert.Equal(len(data), packet.Size())
+	assert.Equal(int64(100), packet.Pts())
+	assert.Equal(int64(90), packet.Dts())
+	assert.False(packet.IsKeyFrame())
+
+	packet.SetKeyFrame(true)
+	packet.SetDuration(10)
+	packet.SetStreamIndex(2)
+	assert.True(packet.IsKeyFrame())
+	assert.False(packet.IsCorrupt())
+	assert.False(packet.IsDiscard())
+	assert.Equal(int64(10), packet.Duration())
+	assert.Equal(2, packet.StreamIndex())
+
+	// Side data
+	assert.Nil(packet.SideData(ff.AV_PKT_DATA_STRINGS_METADATA))
+	assert.NoError(packet.SetSideData(ff.AV_PKT_DATA_STRINGS_METADATA, []byte("key\x00value\x00")))
+	assert.Equal([]byte("key\x00value\x00"), packet.SideData(ff.AV_PKT_DATA_STRINGS_METADATA))
+	assert.Equal([]ff.AVPacketSideDataType{ff.AV_PKT_DATA_STRINGS_METADATA}, packet.SideDataTypes())
+
+	// Clone the packet
+	clone, err := packet.Clone()
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer clone.Close()
+	assert.Equal(packet.Bytes(), clone.Bytes())
+	assert.Equal(packet.Pts(), clone.Pts())
+	assert.True(clone.IsKeyFrame())
+	assert.Equal(packet.SideData(ff.AV_PKT_DATA_STRINGS_METADATA), clone.SideData(ff.AV_PKT_DATA_STRINGS_METADATA))
+
+	// Rescale the timestamps
+	assert.Error(packet.Rescale(ff.AVUtil_rational(1, 1000)))
+	packet.SetTimeBase(ff.AVUtil_rational(1, 100))
+	if assert.NoError(packet.Rescale(ff.AVUtil_rational(1, 1000))) {
+		assert.Equal(int64(1000), packet.Pts())
+		assert.Equal(int64(900), packet.Dts())
+		assert.Equal(int64(100), packet.Duration())
+		assert.Equal(ff.AVUtil_rational(1, 1000), packet.TimeBase())
+	}
+	assert.Equal(1.0, packet.Ts())
+}
+
+func Test_packet_002(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Index the keyframes in each stream
+	keyframes := make(map[int][]float64)
+	assert.NoError(r.Demux(context.Background(), nil, func(stream int, packet *ffmpeg.Packet) error {
+		assert.Equal(stream, packet.StreamIndex())
+		assert.NotZero(packet.Size())
+		if packet.IsKeyFrame() {
+			keyframes[stream] = append(keyframes[stream], packet.Ts())
+		}
+		return nil
+	}))
+	assert.NotEmpty(keyframes)
+	for stream, ts := range keyframes {
+		t.Logf("stream %d: %d keyframes", stream, len(ts))
+	}
+}
@@ -0,0 +1,143 @@
+package ffmpeg
+
+import (
+	"math/bits"
+	"sync"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// PacketPool allocates packets with payloads from a set of pools, one pool
+// for each power-of-two payload size. Buffers are returned to the pool when
+// packets are released.
+type PacketPool struct {
+	sync.Mutex
+	pools   map[int]*ff.AVBufferPool
+	packets []*ff.AVPacket // Released packets, which are re-used
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	packetPoolMinSize = 4096 // Smallest buffer size, in bytes
+	packetPoolPackets = 32   // Maximum number of released packets kept for re-use
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a new packet pool
+func NewPacketPool() *PacketPool {
+	return &PacketPool{
+		pools: make(map[int]*ff.AVBufferPool),
+	}
+}
+
+// Release resources. Buffers which are still referenced by packets are freed
+// when the packets are released.
+func (p *PacketPool) Close() error {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, pool := range p.pools {
+		ff.AVUtil_buffer_pool_uninit(pool)
+	}
+	for _, packet := range p.packets {
+		ff.AVCodec_packet_free(packet)
+	}
+	p.pools = nil
+	p.packets = nil
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return a packet with a payload of size bytes allocated from the pool, or
+// an empty packet if size is zero. The packet should be released with Put.
+func (p *PacketPool) Get(size int) (*Packet, error) {
+	if size < 0 {
+		return nil, ErrBadParameter.Withf("invalid packet size %d", size)
+	}
+
+	// Get a packet
+	packet := p.packet()
+	if packet == nil {
+		return nil, ErrInternalAppError.With("failed to allocate packet")
+	} else if size == 0 {
+		return (*Packet)(packet), nil
+	}
+
+	// Allocate the payload
+	if err := p.getBuffer(packet, size); err != nil {
+		p.Put((*Packet)(packet))
+		return nil, err
+	}
+
+	// Return success
+	return (*Packet)(packet), nil
+}
+
+// Release a packet, returning the payload to the pool. The packet should
+// not be used after it has been released.
+func (p *PacketPool) Put(packet *Packet) {
+	if packet == nil {
+		return
+	}
+	ff.AVCodec_packet_unref((*ff.AVPacket)(packet))
+
+	p.Lock()
+	defer p.Unlock()
+	if p.pools == nil || len(p.packets) >= packetPoolPackets {
+		ff.AVCodec_packet_free((*ff.AVPacket)(packet))
+	} else {
+		p.packets = append(p.packets, (*ff.AVPacket)(packet))
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a released packet, or allocate a new packet
+func (p *PacketPool) packet() *ff.AVPacket {
+	p.Lock()
+	defer p.Unlock()
+	if n := len(p.packets); n > 0 {
+		packet := p.packets[n-1]
+		p.packets = p.packets[:n-1]
+		return packet
+	}
+	return ff.AVCodec_packet_alloc()
+}
+
+// Allocate a payload of size bytes from the pool for the size, including
+// the padding. The pool is held locked so it cannot be closed while the
+// payload is allocated.
+func (p *PacketPool) getBuffer(packet *ff.AVPacket, size int) error {
+	bufsize := max(size+ff.AV_INPUT_BUFFER_PADDING_SIZE, packetPoolMinSize)
+	bufsize = 1 << bits.Len(uint(bufsize-1))
+
+	p.Lock()
+	defer p.Unlock()
+	if p.pools == nil {
+		return ErrInternalAppError.With("packet pool is closed")
+	}
+	pool, exists := p.pools[bufsize]
+	if !exists {
+		if pool = ff.AVUtil_buffer_pool_init(bufsize); pool == nil {
+			return ErrInternalAppError.With("failed to allocate buffer pool")
+		}
+		p.pools[bufsize] = pool
+	}
+	return ff.AVCodec_packet_get_pool_buffer(packet, pool, size)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 
 	// Packages
 	media "github.com/mutablelogic/go-media"
@@ -20,12 +22,30 @@ type Par struct {
 	ff.AVCodecParameters
 	opts     []media.Metadata
 	timebase ff.AVRational
+	filter   string
+	encoder  string
+	rc       RateControl
+}
+
+// RateControl are the typed encoder settings for a stream. Zero values
+// use the encoder defaults.
+type RateControl struct {
+	Profile string  `json:"profile,omitempty"`  // Profile name, for example "high" or "main"
+	BitRate int64   `json:"bit_rate,omitempty"` // Target bitrate, in bits per second
+	MaxRate int64   `json:"max_rate,omitempty"` // Maximum bitrate, in bits per second
+	BufSize int     `json:"buf_size,omitempty"` // Rate control buffer size, in bits
+	CRF     float64 `json:"crf,omitempty"`      // Constant rate factor, for encoders which support it
+	GopSize int     `json:"gop_size,omitempty"` // Number of frames between keyframes
+	BFrames int     `json:"b_frames,omitempty"` // Maximum number of B-frames, or -1 for none
 }
 
 type jsonPar struct {
 	ff.AVCodecParameters
-	Timebase ff.AVRational    `json:"timebase"`
-	Opts     []media.Metadata `json:"options"`
+	Timebase    ff.AVRational    `json:"timebase"`
+	Opts        []media.Metadata `json:"options"`
+	Filter      string           `json:"filter,omitempty"`
+	Encoder     string           `json:"encoder,omitempty"`
+	RateControl *RateControl     `json:"rate_control,omitempty"`
 }
 
 ///////////////////////////////////////////////////////////////////////////////
@@ -100,6 +120,19 @@ func NewVideoPar(pixfmt string, size string, framerate float64, opts ...media.Me
 	return par, nil
 }
 
+// Create new subtitle parameters, plus any additional options which is used
+// for creating a stream. The subtitle codec is determined by the output format,
+// and the timestamps are in milliseconds.
+func NewSubtitlePar(opts ...media.Metadata) (*Par, error) {
+	par := new(Par)
+	par.SetCodecType(ff.AVMEDIA_TYPE_SUBTITLE)
+	par.opts = opts
+	par.timebase = ff.AVUtil_rational(1, 1000)
+
+	// Return success
+	return par, nil
+}
+
 // Create audio parameters. If there is an error, then this function will panic
 func AudioPar(samplefmt string, channellayout string, samplerate int, opts ...media.Metadata) *Par {
 	if par, err := NewAudioPar(samplefmt, channellayout, samplerate, opts...); err != nil {
@@ -118,6 +151,15 @@ func VideoPar(pixfmt string, size string, framerate float64, opts ...media.Metad
 	}
 }
 
+// Create subtitle parameters. If there is an error, then this function will panic
+func SubtitlePar(opts ...media.Metadata) *Par {
+	if par, err := NewSubtitlePar(opts...); err != nil {
+		panic(err)
+	} else {
+		return par
+	}
+}
+
 ///////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -126,6 +168,9 @@ func (ctx *Par) MarshalJSON() ([]byte, error) {
 		AVCodecParameters: ctx.AVCodecParameters,
 		Timebase:          ctx.timebase,
 		Opts:              ctx.opts,
+		Encoder:           ctx.encoder,
+		RateControl:       ctx.rateControl(),
+		Filter:            ctx.filter,
 	})
 }
 
@@ -167,7 +212,73 @@ func (ctx *Par) FrameRate() float64 {
 	return ff.AVUtil_rational_q2d(ff.AVUtil_rational_invert(ctx.timebase))
 }
 
+// Return the filter description which is applied when decoding
+func (ctx *Par) Filter() string {
+	return ctx.filter
+}
+
+// Set a filter description, for example "yadif" or "atempo=1.5", which is
+// applied to decoded frames before they are resampled or resized to these
+// parameters. Set to an empty string to remove the filter.
+func (ctx *Par) SetFilter(desc string) {
+	ctx.filter = desc
+}
+
+// Return the name of the encoder, or an empty string if the default
+// encoder for the output format is used
+func (ctx *Par) Encoder() string {
+	return ctx.encoder
+}
+
+// Set the encoder by name, for example "libx264" or "libopus". Set to an
+// empty string to use the default encoder for the output format.
+func (ctx *Par) SetEncoder(name string) error {
+	if name == "" {
+		ctx.encoder = ""
+		return nil
+	}
+	codec := ff.AVCodec_find_encoder_by_name(name)
+	if codec == nil {
+		return ErrBadParameter.Withf("unknown encoder %q", name)
+	} else if t := ctx.CodecType(); t != ff.AVMEDIA_TYPE_UNKNOWN && t != codec.Type() {
+		return ErrBadParameter.Withf("encoder %q is not a %v encoder", name, ctx.Type())
+	}
+
+	// Set the encoder
+	ctx.encoder = codec.Name()
+	ctx.SetCodecID(codec.ID())
+
+	// Return success
+	return nil
+}
+
+// Return the rate control settings
+func (ctx *Par) RateControl() RateControl {
+	return ctx.rc
+}
+
+// Set the rate control settings, which are validated against the encoder
+// when the stream is created
+func (ctx *Par) SetRateControl(rc RateControl) error {
+	if rc.BitRate < 0 || rc.MaxRate < 0 || rc.BufSize < 0 {
+		return ErrBadParameter.With("negative bitrate or buffer size")
+	} else if rc.MaxRate > 0 && rc.BitRate > rc.MaxRate {
+		return ErrBadParameter.Withf("bitrate %v exceeds maximum bitrate %v", rc.BitRate, rc.MaxRate)
+	} else if rc.CRF < 0 {
+		return ErrBadParameter.Withf("negative crf %v", rc.CRF)
+	} else if rc.GopSize < 0 {
+		return ErrBadParameter.Withf("negative gop size %v", rc.GopSize)
+	} else if rc.BFrames < -1 {
+		return ErrBadParameter.Withf("invalid b-frames %v", rc.BFrames)
+	}
+	ctx.rc = rc
+	return nil
+}
+
 func (ctx *Par) ValidateFromCodec(codec *ff.AVCodec) error {
+	if err := ctx.validateRateControl(codec); err != nil {
+		return err
+	}
 	switch codec.Type() {
 	case ff.AVMEDIA_TYPE_AUDIO:
 		return ctx.validateAudioCodec(codec)
@@ -178,11 +289,16 @@ func (ctx *Par) ValidateFromCodec(codec *ff.AVCodec) error {
 }
 
 func (ctx *Par) CopyToCodecContext(codec *ff.AVCodecContext) error {
+	if err := ctx.copyRateControl(codec); err != nil {
+		return err
+	}
 	switch codec.Codec().Type() {
 	case ff.AVMEDIA_TYPE_AUDIO:
 		return ctx.copyAudioCodec(codec)
 	case ff.AVMEDIA_TYPE_VIDEO:
 		return ctx.copyVideoCodec(codec)
+	case ff.AVMEDIA_TYPE_SUBTITLE:
+		return ctx.copySubtitleCodec(codec)
 	}
 	return nil
 }
@@ -203,6 +319,76 @@ func (ctx *Par) newOpts() *ff.AVDictionary {
 	return dict
 }
 
+// Return the rate control settings, or nil if none are set
+func (ctx *Par) rateControl() *RateControl {
+	if ctx.rc == (RateControl{}) {
+		return nil
+	}
+	return &ctx.rc
+}
+
+// Check the profile against the profiles supported by the codec. Codecs
+// which do not list profiles (for example, libx264) may accept the profile
+// as a codec option instead.
+func (ctx *Par) validateRateControl(codec *ff.AVCodec) error {
+	if ctx.rc.Profile == "" || len(codec.Profiles()) == 0 {
+		return nil
+	} else if _, exists := findProfile(codec, ctx.rc.Profile); !exists {
+		return ErrBadParameter.Withf("unsupported profile %q for codec %q", ctx.rc.Profile, codec.Name())
+	}
+	return nil
+}
+
+func (ctx *Par) copyRateControl(codec *ff.AVCodecContext) error {
+	if ctx.rc.BitRate > 0 {
+		codec.SetBitRate(ctx.rc.BitRate)
+	}
+	if ctx.rc.MaxRate > 0 {
+		codec.SetRcMaxRate(ctx.rc.MaxRate)
+	}
+	if ctx.rc.BufSize > 0 {
+		codec.SetRcBufferSize(ctx.rc.BufSize)
+	}
+	if ctx.rc.GopSize > 0 {
+		codec.SetGopSize(ctx.rc.GopSize)
+	}
+	if ctx.rc.BFrames > 0 {
+		codec.SetMaxBFrames(ctx.rc.BFrames)
+	} else if ctx.rc.BFrames < 0 {
+		codec.SetMaxBFrames(0)
+	}
+
+	// Set the profile, either from the list of codec profiles or as a
+	// codec option
+	if ctx.rc.Profile != "" {
+		if profile, exists := findProfile(codec.Codec(), ctx.rc.Profile); exists {
+			codec.SetProfile(profile)
+		} else if err := codec.SetPrivDataKV("profile", ctx.rc.Profile); err != nil {
+			return ErrBadParameter.Withf("unsupported profile %q for codec %q", ctx.rc.Profile, codec.Codec().Name())
+		}
+	}
+
+	// Set the constant rate factor as a codec option
+	if ctx.rc.CRF > 0 {
+		if err := codec.SetPrivDataKV("crf", strconv.FormatFloat(ctx.rc.CRF, 'f', -1, 64)); err != nil {
+			return ErrBadParameter.Withf("codec %q does not support crf", codec.Codec().Name())
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the profile identifier for a profile name, ignoring case
+func findProfile(codec *ff.AVCodec, name string) (int, bool) {
+	for _, profile := range codec.Profiles() {
+		if strings.EqualFold(profile.Name(), name) {
+			return profile.ID(), true
+		}
+	}
+	return ff.FF_PROFILE_UNKNOWN, false
+}
+
 func (ctx *Par) copyAudioCodec(codec *ff.AVCodecContext) error {
 	codec.SetSampleFormat(ctx.SampleFormat())
 	codec.SetSampleRate(ctx.Samplerate())
@@ -280,6 +466,19 @@ func (ctx *Par) copyVideoCodec(codec *ff.AVCodecContext) error {
 	return nil
 }
 
+func (ctx *Par) copySubtitleCodec(codec *ff.AVCodecContext) error {
+	if ctx.timebase.Num() == 0 || ctx.timebase.Den() == 0 {
+		codec.SetTimeBase(ff.AVUtil_rational(1, 1000))
+	} else {
+		codec.SetTimeBase(ctx.timebase)
+	}
+	codec.SetWidth(ctx.Width())
+	codec.SetHeight(ctx.Height())
+
+	// Text subtitle encoders require an ASS header with the default style
+	return codec.SetSubtitleHeader(assHeader)
+}
+
 func (ctx *Par) validateVideoCodec(codec *ff.AVCodec) error {
 	pixelformats := codec.PixelFormats()
 	framerates := codec.SupportedFramerates()
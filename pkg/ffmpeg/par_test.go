@@ -27,3 +27,28 @@ func Test_par_002(t *testing.T) {
 	}
 	t.Log(par)
 }
+
+func Test_par_003(t *testing.T) {
+	assert := assert.New(t)
+
+	par, err := ffmpeg.NewVideoPar("yuv420p", "1280x720", 25)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Set the encoder
+	assert.NoError(par.SetEncoder("mpeg4"))
+	assert.Equal("mpeg4", par.Encoder())
+	assert.Error(par.SetEncoder("aac"))
+	assert.Error(par.SetEncoder("not-an-encoder"))
+	assert.NoError(par.SetEncoder(""))
+	assert.Equal("", par.Encoder())
+
+	// Set the rate control
+	assert.NoError(par.SetRateControl(ffmpeg.RateControl{BitRate: 1000000, MaxRate: 2000000, GopSize: 50, BFrames: -1}))
+	assert.Equal(int64(1000000), par.RateControl().BitRate)
+	assert.Error(par.SetRateControl(ffmpeg.RateControl{BitRate: 2000000, MaxRate: 1000000}))
+	assert.Error(par.SetRateControl(ffmpeg.RateControl{GopSize: -1}))
+	assert.Error(par.SetRateControl(ffmpeg.RateControl{BFrames: -2}))
+	t.Log(par)
+}
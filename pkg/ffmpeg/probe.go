@@ -0,0 +1,277 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ProbeReport describes the container format and streams of media. Times are
+// in seconds and bitrates are in bits per second. Fields which are unknown
+// are omitted, and times which are unknown are nil.
+type ProbeReport struct {
+	Format  ProbeFormat    `json:"format"`
+	Streams []*ProbeStream `json:"streams"`
+}
+
+// ProbeFormat describes the container format
+type ProbeFormat struct {
+	Name       string            `json:"name"`
+	LongName   string            `json:"long_name,omitempty"`
+	Duration   *float64          `json:"duration,omitempty"`
+	StartTime  *float64          `json:"start_time,omitempty"`
+	BitRate    int64             `json:"bit_rate,omitempty"`
+	NumStreams int               `json:"num_streams"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+}
+
+// ProbeStream describes a stream within the container
+type ProbeStream struct {
+	Index         int               `json:"index"`
+	Type          string            `json:"type"`
+	Codec         string            `json:"codec"`
+	CodecLongName string            `json:"codec_long_name,omitempty"`
+	Profile       string            `json:"profile,omitempty"`
+	Level         int               `json:"level,omitempty"`
+	BitRate       int64             `json:"bit_rate,omitempty"`
+	Duration      *float64          `json:"duration,omitempty"`
+	StartTime     *float64          `json:"start_time,omitempty"`
+	NumFrames     int64             `json:"num_frames,omitempty"`
+	Language      string            `json:"language,omitempty"`
+	Disposition   []string          `json:"disposition,omitempty"`
+	Video         *ProbeVideo       `json:"video,omitempty"`
+	Audio         *ProbeAudio       `json:"audio,omitempty"`
+	Metadata      map[string]string `json:"metadata,omitempty"`
+}
+
+// ProbeVideo describes the parameters of a video stream
+type ProbeVideo struct {
+	Width              int     `json:"width"`
+	Height             int     `json:"height"`
+	PixelFormat        string  `json:"pixel_format,omitempty"`
+	SampleAspectRatio  string  `json:"sample_aspect_ratio,omitempty"`
+	DisplayAspectRatio string  `json:"display_aspect_ratio,omitempty"`
+	FrameRate          float64 `json:"frame_rate,omitempty"`
+}
+
+// ProbeAudio describes the parameters of an audio stream
+type ProbeAudio struct {
+	SampleFormat  string `json:"sample_format,omitempty"`
+	SampleRate    int    `json:"sample_rate"`
+	Channels      int    `json:"channels"`
+	ChannelLayout string `json:"channel_layout,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// Names of the disposition flags in the report
+var probeDispositions = []struct {
+	ff.AVDisposition
+	name string
+}{
+	{ff.AV_DISPOSITION_DEFAULT, "default"},
+	{ff.AV_DISPOSITION_DUB, "dub"},
+	{ff.AV_DISPOSITION_ORIGINAL, "original"},
+	{ff.AV_DISPOSITION_COMMENT, "comment"},
+	{ff.AV_DISPOSITION_LYRICS, "lyrics"},
+	{ff.AV_DISPOSITION_KARAOKE, "karaoke"},
+	{ff.AV_DISPOSITION_FORCED, "forced"},
+	{ff.AV_DISPOSITION_HEARING_IMPAIRED, "hearing_impaired"},
+	{ff.AV_DISPOSITION_VISUAL_IMPAIRED, "visual_impaired"},
+	{ff.AV_DISPOSITION_CLEAN_EFFECTS, "clean_effects"},
+	{ff.AV_DISPOSITION_ATTACHED_PIC, "attached_pic"},
+	{ff.AV_DISPOSITION_TIMED_THUMBNAILS, "timed_thumbnails"},
+	{ff.AV_DISPOSITION_CAPTIONS, "captions"},
+	{ff.AV_DISPOSITION_DESCRIPTIONS, "descriptions"},
+	{ff.AV_DISPOSITION_METADATA, "metadata"},
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Probe media from a url, file path or device, and return a report
+func Probe(url string, opt ...Opt) (*ProbeReport, error) {
+	reader, err := Open(url, opt...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return reader.Probe(), nil
+}
+
+// Probe media from an io.Reader, and return a report
+func ProbeReader(r io.Reader, opt ...Opt) (*ProbeReport, error) {
+	reader, err := NewReader(r, opt...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return reader.Probe(), nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (r *ProbeReport) String() string {
+	data, _ := json.MarshalIndent(r, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return a report of the container format and streams
+func (r *Reader) Probe() *ProbeReport {
+	report := new(ProbeReport)
+
+	// Format
+	tb := ff.AVUtil_rational(1, ff.AV_TIME_BASE)
+	if input := r.input.Input(); input != nil {
+		report.Format.Name = input.Name()
+		report.Format.LongName = input.LongName()
+	}
+	report.Format.Duration = probeTime(r.input.Duration(), tb)
+	report.Format.StartTime = probeTime(r.input.StartTime(), tb)
+	report.Format.BitRate = r.input.BitRate()
+	report.Format.NumStreams = int(r.input.NumStreams())
+	report.Format.Metadata = probeMetadata(r.input.Metadata())
+
+	// Streams
+	report.Streams = make([]*ProbeStream, 0, r.input.NumStreams())
+	for _, stream := range r.input.Streams() {
+		report.Streams = append(report.Streams, probeStream(stream))
+	}
+
+	// Return the report
+	return report
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+func probeStream(stream *ff.AVStream) *ProbeStream {
+	par := stream.CodecPar()
+	tb := stream.TimeBase()
+	result := &ProbeStream{
+		Index:         stream.Index(),
+		Type:          probeType(par.CodecType()),
+		Codec:         par.CodecID().Name(),
+		CodecLongName: par.CodecID().LongName(),
+		Profile:       ff.AVCodec_profile_name(par.CodecID(), par.Profile()),
+		BitRate:       par.BitRate(),
+		Duration:      probeTime(stream.Duration(), tb),
+		StartTime:     probeTime(stream.StartTime(), tb),
+		NumFrames:     stream.NumFrames(),
+		Metadata:      probeMetadata(stream.Metadata()),
+	}
+	if level := par.Level(); level > 0 {
+		result.Level = level
+	}
+	if language, exists := result.Metadata[MetaLanguage]; exists {
+		result.Language = language
+	}
+	result.Disposition = probeDisposition(stream.Disposition())
+
+	switch par.CodecType() {
+	case ff.AVMEDIA_TYPE_VIDEO:
+		result.Video = &ProbeVideo{
+			Width:  par.Width(),
+			Height: par.Height(),
+		}
+		if pixfmt := par.PixelFormat(); pixfmt != ff.AV_PIX_FMT_NONE {
+			result.Video.PixelFormat = ff.AVUtil_get_pix_fmt_name(pixfmt)
+		}
+		sar := stream.SampleAspectRatio()
+		if sar.IsZero() {
+			sar = par.SampleAspectRatio()
+		}
+		if !sar.IsZero() && par.Width() > 0 && par.Height() > 0 {
+			dar, _ := ff.AVUtil_reduce(int64(par.Width())*int64(sar.Num()), int64(par.Height())*int64(sar.Den()), 1024*1024)
+			result.Video.SampleAspectRatio = probeRatio(sar)
+			result.Video.DisplayAspectRatio = probeRatio(dar)
+		}
+		if fr := stream.AvgFrameRate(); !fr.IsZero() {
+			result.Video.FrameRate = ff.AVUtil_rational_q2d(fr)
+		} else if fr := stream.RFrameRate(); !fr.IsZero() {
+			result.Video.FrameRate = ff.AVUtil_rational_q2d(fr)
+		}
+	case ff.AVMEDIA_TYPE_AUDIO:
+		ch := par.ChannelLayout()
+		result.Audio = &ProbeAudio{
+			SampleRate: par.Samplerate(),
+			Channels:   ch.NumChannels(),
+		}
+		if samplefmt := par.SampleFormat(); samplefmt != ff.AV_SAMPLE_FMT_NONE {
+			result.Audio.SampleFormat = ff.AVUtil_get_sample_fmt_name(samplefmt)
+		}
+		if layout, err := ff.AVUtil_channel_layout_describe(&ch); err == nil {
+			result.Audio.ChannelLayout = layout
+		}
+	}
+
+	// Return the stream
+	return result
+}
+
+// Return a timestamp in seconds, or nil if the timestamp is undefined
+func probeTime(ts int64, tb ff.AVRational) *float64 {
+	if ts == ff.AV_NOPTS_VALUE || tb.IsZero() {
+		return nil
+	}
+	secs := ff.AVUtil_rational_q2d(tb) * float64(ts)
+	return &secs
+}
+
+// Return the name of a media type
+func probeType(t ff.AVMediaType) string {
+	switch t {
+	case ff.AVMEDIA_TYPE_VIDEO:
+		return "video"
+	case ff.AVMEDIA_TYPE_AUDIO:
+		return "audio"
+	case ff.AVMEDIA_TYPE_DATA:
+		return "data"
+	case ff.AVMEDIA_TYPE_SUBTITLE:
+		return "subtitle"
+	case ff.AVMEDIA_TYPE_ATTACHMENT:
+		return "attachment"
+	default:
+		return "unknown"
+	}
+}
+
+// Return the names of the disposition flags, ignoring unknown flags
+func probeDisposition(disposition ff.AVDisposition) []string {
+	var result []string
+	for _, flag := range probeDispositions {
+		if disposition.Is(flag.AVDisposition) {
+			result = append(result, flag.name)
+		}
+	}
+	return result
+}
+
+// Return a ratio as a string, for example "16:9"
+func probeRatio(r ff.AVRational) string {
+	return fmt.Sprintf("%d:%d", r.Num(), r.Den())
+}
+
+// Return a dictionary as a map
+func probeMetadata(dict *ff.AVDictionary) map[string]string {
+	entries := ff.AVUtil_dict_entries(dict)
+	if len(entries) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		result[entry.Key()] = entry.Value()
+	}
+	return result
+}
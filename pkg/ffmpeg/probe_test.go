@@ -0,0 +1,100 @@
+package ffmpeg_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	// Packages
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_probe_001(t *testing.T) {
+	assert := assert.New(t)
+
+	report, err := ffmpeg.Probe("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NotEmpty(report.Format.Name)
+	if assert.NotNil(report.Format.Duration) {
+		assert.Greater(*report.Format.Duration, 0.0)
+	}
+	assert.Equal(report.Format.NumStreams, len(report.Streams))
+
+	var video, audio bool
+	for _, stream := range report.Streams {
+		switch stream.Type {
+		case "video":
+			video = true
+			assert.NotNil(stream.Video)
+			assert.Greater(stream.Video.Width, 0)
+			assert.Greater(stream.Video.Height, 0)
+			assert.Greater(stream.Video.FrameRate, 0.0)
+		case "audio":
+			audio = true
+			assert.NotNil(stream.Audio)
+			assert.Greater(stream.Audio.SampleRate, 0)
+			assert.Greater(stream.Audio.Channels, 0)
+		}
+	}
+	assert.True(video)
+	assert.True(audio)
+
+	// Check the report serializes
+	_, err = json.Marshal(report)
+	assert.NoError(err)
+	t.Log(report)
+}
+
+func Test_probe_002(t *testing.T) {
+	assert := assert.New(t)
+
+	r, err := os.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	report, err := ffmpeg.ProbeReader(r)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.Equal("mp3", report.Format.Name)
+	if assert.NotEmpty(report.Streams) {
+		assert.Equal("audio", report.Streams[0].Type)
+		assert.Equal("mp3", report.Streams[0].Codec)
+	}
+	t.Log(report)
+}
+
+func Test_probe_003(t *testing.T) {
+	assert := assert.New(t)
+
+	report, err := ffmpeg.Probe("../../etc/test/jfk.wav")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// A start time of zero is reported, rather than omitted
+	if assert.NotNil(report.Format.StartTime) {
+		assert.Equal(0.0, *report.Format.StartTime)
+	}
+	data, err := json.Marshal(report)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	var result struct {
+		Format  map[string]any   `json:"format"`
+		Streams []map[string]any `json:"streams"`
+	}
+	if !assert.NoError(json.Unmarshal(data, &result)) {
+		t.FailNow()
+	}
+	assert.Equal(0.0, result.Format["start_time"])
+	if assert.Len(result.Streams, 1) {
+		assert.Equal("audio", result.Streams[0]["type"])
+		assert.Equal(0.0, result.Streams[0]["start_time"])
+	}
+}
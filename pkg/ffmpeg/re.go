@@ -84,3 +84,14 @@ func (re *Re) Frame(src *Frame) (*Frame, error) {
 		return src, nil
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Discard any buffered samples, for example after seeking. The rescaler
+// does not buffer frames so nothing needs to be done for video.
+func (re *Re) reset() {
+	if re.audio != nil {
+		re.audio.reset()
+	}
+}
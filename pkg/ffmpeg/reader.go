@@ -4,14 +4,21 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"io/fs"
+	"os"
 	"slices"
 	"strings"
+	"syscall"
 	"time"
 
 	// Packages
 	media "github.com/mutablelogic/go-media"
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -24,6 +31,8 @@ type Reader struct {
 	avio    *ff.AVIOContextEx
 	force   bool
 	context *Context
+	seek    time.Duration // Pending frame-accurate seek, or -1
+	closer  io.Closer     // Closed with the reader, when opened from a file system
 }
 
 type reader_callback struct {
@@ -39,6 +48,24 @@ type DecoderMapFunc func(int, *Par) (*Par, error)
 // return nil to continue decoding or io.EOF to stop.
 type DecoderFrameFn func(int, *Frame) error
 
+// DemuxerPacketFn is a function which is called for each packet read from
+// the media, with the stream index. The packet timestamps are in the
+// stream timebase. It should return nil to continue or io.EOF to stop.
+type DemuxerPacketFn func(int, *Packet) error
+
+// SeekFlag determines how the reader seeks within the media
+type SeekFlag uint
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	SEEK_NONE     SeekFlag = 0
+	SEEK_BACKWARD SeekFlag = 1 << 0 // Seek to the keyframe at or before the timestamp
+	SEEK_ANY      SeekFlag = 1 << 1 // Seek to any frame, not just keyframes
+	SEEK_ACCURATE SeekFlag = 1 << 2 // Decode and discard frames until the timestamp is reached
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
@@ -113,6 +140,46 @@ func NewReader(r io.Reader, opt ...Opt) (*Reader, error) {
 	return reader.open(options)
 }
 
+// Create a new reader from an io.ReaderAt with the size of the media in
+// bytes, which supports seeking within the media
+func NewReaderAt(r io.ReaderAt, size int64, opt ...Opt) (*Reader, error) {
+	if size < 0 {
+		return nil, ErrBadParameter.Withf("negative size %v", size)
+	}
+	return NewReader(io.NewSectionReader(r, 0, size), opt...)
+}
+
+// Open media from a file system, such as an embed.FS, zip archive or
+// os.DirFS. Seeking is supported when the file implements io.Seeker or
+// io.ReaderAt. The file is closed when the reader is closed.
+func OpenFS(fsys fs.FS, name string, opt ...Opt) (*Reader, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+
+	// Determine how to read the file
+	var r io.Reader = f
+	if _, ok := f.(io.Seeker); !ok {
+		if at, ok := f.(io.ReaderAt); ok {
+			if info, err := f.Stat(); err == nil {
+				r = io.NewSectionReader(at, 0, info.Size())
+			}
+		}
+	}
+
+	// Create the reader
+	reader, err := NewReader(r, opt...)
+	if err != nil {
+		return nil, errors.Join(err, f.Close())
+	} else {
+		reader.closer = f
+	}
+
+	// Return success
+	return reader, nil
+}
+
 func (r *Reader) open(options *opts) (*Reader, error) {
 	// Find stream information
 	if err := ff.AVFormat_find_stream_info(r.input, nil); err != nil {
@@ -124,6 +191,7 @@ func (r *Reader) open(options *opts) (*Reader, error) {
 	// Set force flag and type
 	r.force = options.force
 	r.t = options.t | media.INPUT
+	r.seek = -1
 
 	// Return success
 	return r, nil
@@ -144,10 +212,16 @@ func (r *Reader) Close() error {
 		ff.AVFormat_avio_context_free(r.avio)
 	}
 
+	// Close the file
+	if r.closer != nil {
+		result = errors.Join(result, r.closer.Close())
+	}
+
 	// Release resources
 	r.context = nil
 	r.input = nil
 	r.avio = nil
+	r.closer = nil
 
 	// Return any errors
 	return result
@@ -212,6 +286,19 @@ func (r *Reader) BestStream(t media.Type) int {
 	return -1
 }
 
+// Return the codec parameters for a stream, or nil if the stream
+// does not exist
+func (r *Reader) Par(stream int) *Par {
+	if s := r.input.Stream(stream); s == nil {
+		return nil
+	} else {
+		return &Par{
+			AVCodecParameters: *s.CodecPar(),
+			timebase:          s.TimeBase(),
+		}
+	}
+}
+
 // Return the metadata for the media stream, filtering by the specified keys
 // if there are any. Artwork is returned with the "artwork" key.
 func (r *Reader) Metadata(keys ...string) []*Metadata {
@@ -226,8 +313,21 @@ func (r *Reader) Metadata(keys ...string) []*Metadata {
 	// Obtain any artwork from the streams
 	if slices.Contains(keys, MetaArtwork) {
 		for _, stream := range r.input.Streams() {
+			var picture PictureType
+			if entry := ff.AVUtil_dict_get(stream.Metadata(), "comment", nil, 0); entry != nil {
+				picture = PictureType(entry.Value())
+			}
 			if packet := stream.AttachedPic(); packet != nil {
-				result = append(result, NewMetadata(MetaArtwork, packet.Bytes()))
+				result = append(result, NewArtwork(packet.Bytes(), picture))
+			} else if stream.CodecPar().CodecType() == ff.AVMEDIA_TYPE_ATTACHMENT {
+				// Matroska attachments which are images, with the picture type as the title
+				if entry := ff.AVUtil_dict_get(stream.Metadata(), "mimetype", nil, 0); entry == nil || !strings.HasPrefix(entry.Value(), "image/") {
+					continue
+				}
+				if entry := ff.AVUtil_dict_get(stream.Metadata(), "title", nil, 0); entry != nil {
+					picture = PictureType(entry.Value())
+				}
+				result = append(result, NewArtwork(stream.CodecPar().ExtraData(), picture))
 			}
 		}
 	}
@@ -236,7 +336,110 @@ func (r *Reader) Metadata(keys ...string) []*Metadata {
 	return result
 }
 
-func (r *Reader) Decode(ctx context.Context, mapfn DecoderMapFunc, decodefn DecoderFrameFn) error {
+// Return the metadata for a stream, filtering by the specified keys
+// if there are any. Returns nil if the stream does not exist.
+func (r *Reader) StreamMetadata(stream int, keys ...string) []*Metadata {
+	s := r.input.Stream(stream)
+	if s == nil {
+		return nil
+	}
+	entries := ff.AVUtil_dict_entries(s.Metadata())
+	result := make([]*Metadata, 0, len(entries))
+	for _, entry := range entries {
+		if len(keys) == 0 || slices.Contains(keys, entry.Key()) {
+			result = append(result, NewMetadata(entry.Key(), entry.Value()))
+		}
+	}
+	return result
+}
+
+// Return the language for a stream as an ISO 639-2 code, or an empty
+// string if the language is not set
+func (r *Reader) Language(stream int) string {
+	if entries := r.StreamMetadata(stream, MetaLanguage); len(entries) > 0 {
+		return entries[0].Value()
+	}
+	return ""
+}
+
+// Return the disposition flags for a stream, or DISPOSITION_NONE if the
+// stream does not exist
+func (r *Reader) Disposition(stream int) Disposition {
+	if s := r.input.Stream(stream); s == nil {
+		return DISPOSITION_NONE
+	} else {
+		return Disposition(s.Disposition())
+	}
+}
+
+// Return the chapters for the media, in the order they are stored
+func (r *Reader) Chapters() []*Chapter {
+	chapters := r.input.Chapters()
+	result := make([]*Chapter, 0, len(chapters))
+	for _, chapter := range chapters {
+		result = append(result, newChapter(chapter))
+	}
+	return result
+}
+
+// Seek to a timestamp in the media. The stream is the stream index to use
+// for the timestamp, or -1 to use the default stream. The flags determine
+// how the seek is performed. When SEEK_ACCURATE is set, the reader seeks
+// to the keyframe before the timestamp, and the decoders discard frames
+// until the timestamp is reached.
+//
+// Seek can be called before decoding, or from within the DecoderFrameFn
+// during decoding, in which case the decoders are flushed.
+func (r *Reader) Seek(ts time.Duration, stream int, flags SeekFlag) error {
+	if ts < 0 {
+		return ErrBadParameter.Withf("negative timestamp %v", ts)
+	}
+
+	// Convert the timestamp into the stream timebase
+	tb := ff.AVUtil_rational(1, ff.AV_TIME_BASE)
+	if stream >= 0 {
+		if s := r.input.Stream(stream); s == nil {
+			return ErrBadParameter.Withf("invalid stream %v", stream)
+		} else {
+			tb = s.TimeBase()
+		}
+	}
+	pts := ff.AVUtil_rational_rescale_q(int64(ts), ff.AVUtil_rational(1, int(time.Second)), tb)
+
+	// Set the seek flags
+	seekflags := ff.AVSEEK_FLAG_NONE
+	if flags&(SEEK_BACKWARD|SEEK_ACCURATE) != 0 {
+		seekflags |= ff.AVSEEK_FLAG_BACKWARD
+	}
+	if flags&SEEK_ANY != 0 && flags&SEEK_ACCURATE == 0 {
+		seekflags |= ff.AVSEEK_FLAG_ANY
+	}
+
+	// Seek the demuxer
+	if err := ff.AVFormat_seek_frame(r.input, stream, pts, seekflags); err != nil {
+		return err
+	}
+
+	// Flush the decoders if we are decoding, or else set the pending seek
+	// which is applied when decoding starts
+	accurate := flags&SEEK_ACCURATE != 0
+	if r.context != nil {
+		r.context.flush(ts, accurate)
+	} else if accurate {
+		r.seek = ts
+	} else {
+		r.seek = -1
+	}
+
+	// Return success
+	return nil
+}
+
+// Decode the media stream into frames. The map function determines which
+// streams are decoded, and the decodefn is called for each frame decoded.
+// If a subtitlefn is provided, it is called for each subtitle decoded from
+// mapped subtitle streams, otherwise subtitles are ignored.
+func (r *Reader) Decode(ctx context.Context, mapfn DecoderMapFunc, decodefn DecoderFrameFn, subtitlefn ...DecoderSubtitleFn) error {
 	// Create a decoding context
 	decoders, err := newContext(r, mapfn)
 	if err != nil {
@@ -245,7 +448,79 @@ func (r *Reader) Decode(ctx context.Context, mapfn DecoderMapFunc, decodefn Deco
 	defer decoders.Close()
 
 	// Do the decoding
-	return decoders.decode(ctx, decodefn)
+	return r.DecodeWithContext(ctx, decoders, decodefn, subtitlefn...)
+}
+
+// Demux the media stream into packets, without decoding. The map function is
+// called for each stream and should return nil to ignore the stream, or
+// any other parameters to receive packets for the stream. The packet
+// function is called for each packet read.
+//
+// The demuxing can be interrupted by cancelling the context, or by the
+// packetfn returning an error or io.EOF. The latter will end the demuxing
+// process early but will not return an error.
+func (r *Reader) Demux(ctx context.Context, mapfn DecoderMapFunc, packetfn DemuxerPacketFn) error {
+	if packetfn == nil {
+		return ErrBadParameter.With("DemuxerPacketFn is nil")
+	}
+
+	// Map the streams
+	streams := make(map[int]ff.AVRational, r.input.NumStreams())
+	for _, stream := range r.input.Streams() {
+		if mapfn != nil {
+			if par, err := mapfn(stream.Index(), &Par{
+				AVCodecParameters: *stream.CodecPar(),
+				timebase:          stream.TimeBase(),
+			}); err != nil {
+				return err
+			} else if par == nil {
+				continue
+			}
+		}
+		streams[stream.Index()] = stream.TimeBase()
+	}
+	if len(streams) == 0 {
+		return ErrBadParameter.With("no streams to demux")
+	}
+
+	// Allocate a packet
+	packet := ff.AVCodec_packet_alloc()
+	if packet == nil {
+		return errors.New("failed to allocate packet")
+	}
+	defer ff.AVCodec_packet_free(packet)
+
+	// Read packets
+FOR_LOOP:
+	for {
+		select {
+		case <-ctx.Done():
+			break FOR_LOOP
+		default:
+			if err := ff.AVFormat_read_frame(r.input, packet); errors.Is(err, io.EOF) {
+				break FOR_LOOP
+			} else if errors.Is(err, syscall.EAGAIN) {
+				continue FOR_LOOP
+			} else if err != nil {
+				return ErrInternalAppError.With("AVFormat_read_frame: ", err)
+			}
+			if tb, exists := streams[packet.StreamIndex()]; exists {
+				packet.SetTimeBase(tb)
+				if err := packetfn(packet.StreamIndex(), (*Packet)(packet)); errors.Is(err, io.EOF) {
+					break FOR_LOOP
+				} else if err != nil {
+					return err
+				}
+			}
+		}
+
+		// Unreference the packet
+		ff.AVCodec_packet_unref(packet)
+	}
+
+	// Return the context error - will be cancelled, perhaps, or nil if the
+	// demuxer finished successfully without cancellation
+	return ctx.Err()
 }
 
 // Map streams to decoders, and return the decoding context
@@ -257,86 +532,184 @@ func (r *Reader) Map(fn DecoderMapFunc) (*Context, error) {
 }
 
 // Decode the media stream into frames. The decodefn is called for each
-// frame decoded from the stream.
+// frame decoded from the stream, and the optional subtitlefn is called for
+// each subtitle decoded.
 //
 // The decoding can be interrupted by cancelling the context, or by the decodefn
 // returning an error or io.EOF. The latter will end the decoding process early but
 // will not return an error.
-func (r *Reader) DecodeWithContext(ctx context.Context, decoders *Context, decodefn DecoderFrameFn) error {
-	return decoders.decode(ctx, decodefn)
+func (r *Reader) DecodeWithContext(ctx context.Context, decoders *Context, decodefn DecoderFrameFn, subtitlefn ...DecoderSubtitleFn) error {
+	if len(subtitlefn) > 1 {
+		return ErrBadParameter.With("too many subtitle functions")
+	}
+
+	// Set the active decoding context, so that seeking can flush the decoders
+	r.context = decoders
+	defer func() {
+		r.context = nil
+	}()
+
+	// Apply any pending frame-accurate seek
+	if r.seek >= 0 {
+		decoders.flush(r.seek, true)
+		r.seek = -1
+	}
+
+	// Decode the frames and subtitles
+	var subfn DecoderSubtitleFn
+	if len(subtitlefn) > 0 {
+		subfn = subtitlefn[0]
+	}
+	return decoders.decode(ctx, decodefn, subfn)
 }
 
-// Transcode the media stream to a writer
-// As per the decode method, the map function is called for each stream and should return the
-// parameters for the destination. If the map function returns nil for a stream, then
-// the stream is ignored.
-/*
+// Transcode the media stream to a writer. As per the decode method, the map
+// function is called for each stream and should return the parameters for
+// the destination, or nil to ignore the stream. The options are passed to
+// NewWriter, and an output stream is added for each mapped stream, with the
+// same identifier as the input stream index.
+//
+// Decoded frames are encoded and written to the output interleaved by
+// timestamp. The transcoding can be interrupted by cancelling the context,
+// in which case the encoders are flushed and the output is finalized before
+// the context error is returned.
+//
+// With OptTwoPass, video streams are encoded twice. The first pass collects
+// the encoder statistics and discards the output, then the input is read
+// again from the start for the second pass, which writes the output.
 func (r *Reader) Transcode(ctx context.Context, w io.Writer, mapfn DecoderMapFunc, opt ...Opt) error {
-	// Map streams to decoders
-	decoders, err := r.mapStreams(mapfn)
-	if err != nil {
+	if w == nil {
+		return ErrBadParameter.With("nil writer")
+	}
+	var format string
+	if f, ok := w.(*os.File); ok {
+		format = f.Name()
+	}
+	return r.transcode(ctx, mapfn, format, func(opt ...Opt) (*Writer, error) {
+		return NewWriter(w, opt...)
+	}, opt...)
+}
+
+// Transcode the media stream to a file or url, which is created as per
+// the Create method. Otherwise, this is the same as the Transcode method.
+func (r *Reader) TranscodeFile(ctx context.Context, url string, mapfn DecoderMapFunc, opt ...Opt) error {
+	return r.transcode(ctx, mapfn, url, func(opt ...Opt) (*Writer, error) {
+		return Create(url, opt...)
+	}, opt...)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - TRANSCODE
+
+// Transcode with one or two passes. The format is used to guess the output
+// format for the first pass, which is discarded.
+func (r *Reader) transcode(ctx context.Context, mapfn DecoderMapFunc, format string, create func(...Opt) (*Writer, error), opt ...Opt) error {
+	options := newOpts()
+	for _, opt := range opt {
+		if err := opt(options); err != nil {
+			return err
+		}
+	}
+	if !options.twopass {
+		_, err := r.transcodePass(ctx, mapfn, create, opt...)
 		return err
 	}
-	defer decoders.Close()
 
-	// Add streams to the output
-	for _, decoder := range decoders {
-		opt = append(opt, OptStream(decoder.stream, decoder.par))
+	// Encoders which write their own statistics use a temporary directory
+	dir, err := os.MkdirTemp("", "ffmpeg2pass")
+	if err != nil {
+		return err
 	}
+	defer os.RemoveAll(dir)
 
-	// Create an output
-	output, err := NewWriter(w, opt...)
+	// First pass, which discards the output
+	discard := func(opt ...Opt) (*Writer, error) {
+		if format != "" {
+			opt = append([]Opt{OptOutputFormat(format)}, opt...)
+		}
+		return NewWriter(io.Discard, opt...)
+	}
+	stats, err := r.transcodePass(ctx, mapfn, discard, append(opt, OptPass(1, nil), optPassLog(dir))...)
 	if err != nil {
 		return err
 	}
-	defer output.Close()
 
-	// One go-routine for decoding, one for encoding
-	var wg sync.WaitGroup
-	var result error
+	// Rewind the input, and run the second pass
+	if err := r.Seek(0, -1, SEEK_BACKWARD); err != nil {
+		return fmt.Errorf("two-pass transcoding requires a seekable input: %w", err)
+	}
+	_, err = r.transcodePass(ctx, mapfn, create, append(opt, OptPass(2, stats), optPassLog(dir))...)
+	return err
+}
+
+// Map streams to decoders, create the output with an encoder for each
+// decoder, then decode and encode the frames. Returns the first pass
+// statistics for each video stream, when encoding the first pass.
+func (r *Reader) transcodePass(ctx context.Context, mapfn DecoderMapFunc, create func(...Opt) (*Writer, error), opt ...Opt) (map[int]string, error) {
+	// Map streams to decoders
+	decoders, err := newContext(r, mapfn)
+	if err != nil {
+		return nil, err
+	}
+	defer decoders.Close()
+
+	// Create the output, with a stream for each decoder and the chapters
+	// of the input
+	writer, err := create(append([]Opt{OptContext(decoders), OptInputChapters(r.Chapters()...)}, opt...)...)
+	if err != nil {
+		return nil, err
+	}
 
-	// Make a channel for transcoding frames. The decoder should
-	// be ahead of the encoder, so there is probably no need to
-	// create a buffered channel.
-	ch := make(chan *Frame)
-
-	// Decoding
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		if err := r.decode(ctx, decoders, func(stream int, frame *Frame) error {
-			ch <- frame
+	// Packets are interleaved by the muxer, so flush packets are ignored
+	// until the output is closed
+	out := func(packet *Packet) error {
+		if packet == nil {
 			return nil
-		}); err != nil {
-			result = err
 		}
-		// Close channel at the end of decoding
-		close(ch)
-	}()
+		return writer.Write(packet)
+	}
 
-	// Encoding
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		for frame := range ch {
-			fmt.Println("TODO: Write frame to output", frame)
+	// Decode frames, and encode them with the matching encoder
+	result := r.DecodeWithContext(ctx, decoders, func(stream int, frame *Frame) error {
+		encoder := writer.Stream(stream)
+		if encoder == nil {
+			return ErrInternalAppError.Withf("no encoder for stream %v", stream)
 		}
-	}()
-
-	// Wait for the process to finish
-	wg.Wait()
+		if err := encoder.transcode(frame, out); err != nil {
+			return fmt.Errorf("stream %v: %w", stream, err)
+		}
+		return nil
+	}, func(stream int, subtitle *Subtitle) error {
+		if err := writer.WriteSubtitle(stream, subtitle); err != nil {
+			return fmt.Errorf("stream %v: %w", stream, err)
+		}
+		return nil
+	})
+
+	// Flush the encoders, unless there was an error other than cancellation
+	if result == nil || errors.Is(result, context.Canceled) || errors.Is(result, context.DeadlineExceeded) {
+		for _, encoder := range writer.encoders {
+			if encoder.isSubtitle() {
+				continue
+			} else if err := encoder.Encode(nil, out); err != nil && !errors.Is(err, io.EOF) {
+				result = errors.Join(result, fmt.Errorf("stream %v: %w", encoder.stream.Id(), err))
+			}
+		}
+	}
 
-	// Return any errors
-	return result
+	// Write the trailer and close the output
+	stats := writer.Stats()
+	return stats, errors.Join(result, writer.Close())
 }
-*/
 
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS - CALLBACK
 
 func (r *reader_callback) Reader(buf []byte) int {
 	n, err := r.r.Read(buf)
-	if err != nil {
+	if n > 0 {
+		return n
+	} else if err != nil {
 		return ff.AVERROR_EOF
 	}
 	return n
@@ -349,6 +722,8 @@ func (r *reader_callback) Seeker(offset int64, whence int) int64 {
 		return -1
 	}
 	switch whence {
+	case ff.AVSEEK_SIZE:
+		return readerSize(seeker)
 	case io.SeekStart, io.SeekCurrent, io.SeekEnd:
 		n, err := seeker.Seek(offset, whence)
 		if err != nil {
@@ -359,6 +734,32 @@ func (r *reader_callback) Seeker(offset int64, whence int) int64 {
 	return -1
 }
 
+// Return the size of the media in bytes, or -1 if unknown
+func readerSize(seeker io.ReadSeeker) int64 {
+	if sizer, ok := seeker.(interface{ Size() int64 }); ok {
+		return sizer.Size()
+	}
+	if file, ok := seeker.(fs.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode().IsRegular() {
+			return info.Size()
+		}
+	}
+
+	// Seek to the end and back again
+	cur, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return -1
+	}
+	size, err := seeker.Seek(0, io.SeekEnd)
+	if err != nil {
+		return -1
+	}
+	if _, err := seeker.Seek(cur, io.SeekStart); err != nil {
+		return -1
+	}
+	return size
+}
+
 func (r *reader_callback) Writer([]byte) int {
 	return ff.AVERROR_EOF
 }
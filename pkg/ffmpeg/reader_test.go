@@ -1,6 +1,7 @@
 package ffmpeg_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"image/jpeg"
@@ -8,6 +9,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/fstest"
 	"time"
 
 	// Packages
@@ -203,3 +205,194 @@ func Test_reader_006(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func Test_reader_007(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	input, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer input.Close()
+
+	// Seek to the middle of the file, frame-accurate
+	ts := input.Duration() / 2
+	if !assert.NoError(input.Seek(ts, -1, ffmpeg.SEEK_ACCURATE)) {
+		t.FailNow()
+	}
+
+	// Map function - only video streams
+	mapfn := func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() == media.VIDEO {
+			return par, nil
+		}
+		return nil, nil
+	}
+
+	// The first frame should be at or after the seek timestamp
+	framefn := func(stream int, frame *ffmpeg.Frame) error {
+		t.Logf("Frame %v[%d] => %v", frame.Type(), stream, time.Duration(frame.Ts()*float64(time.Second)).Truncate(time.Millisecond))
+		assert.GreaterOrEqual(frame.Ts(), ts.Seconds()-0.001)
+		return io.EOF
+	}
+
+	if err := input.Decode(context.Background(), mapfn, framefn); !assert.NoError(err) {
+		t.FailNow()
+	}
+}
+
+func Test_reader_008(t *testing.T) {
+	assert := assert.New(t)
+
+	// Open a file from a file system
+	r, err := ffmpeg.OpenFS(os.DirFS("../../etc/test"), "sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	assert.Greater(r.Duration(), time.Duration(0))
+	assert.NoError(r.Seek(r.Duration()/2, -1, ffmpeg.SEEK_NONE))
+}
+
+func Test_reader_009(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := os.ReadFile("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Open a file from an io.ReaderAt
+	r, err := ffmpeg.NewReaderAt(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+	assert.Greater(r.Duration(), time.Duration(0))
+
+	// Open a file from an in-memory file system
+	r2, err := ffmpeg.OpenFS(fstest.MapFS{
+		"sample.mp4": &fstest.MapFile{Data: data},
+	}, "sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+	assert.Equal(r.Duration(), r2.Duration())
+}
+
+func Test_reader_010(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Transcode the audio to a file
+	tmp, err := os.MkdirTemp("", t.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "sample.mp3")
+	if err := r.TranscodeFile(context.Background(), filename, func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() == media.AUDIO {
+			return par, nil
+		}
+		return nil, nil
+	}); !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Read the output back
+	r2, err := ffmpeg.Open(filename)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+	assert.Equal(media.AUDIO, r2.Type()&media.AUDIO)
+	assert.InDelta(r.Duration().Seconds(), r2.Duration().Seconds(), 1.0)
+}
+
+func Test_reader_011(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Transcode to a writer, cancelling after a short time
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	w, err := os.CreateTemp("", t.Name()+"*.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+
+	err = r.Transcode(ctx, w, func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() == media.AUDIO {
+			return par, nil
+		}
+		return nil, nil
+	})
+	if err != nil {
+		assert.ErrorIs(err, context.DeadlineExceeded)
+	}
+}
+
+func Test_reader_012(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Transcode the video with two passes
+	tmp, err := os.MkdirTemp("", t.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(tmp)
+	filename := filepath.Join(tmp, "sample.mp4")
+	if err := r.TranscodeFile(context.Background(), filename, func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() != media.VIDEO {
+			return nil, nil
+		}
+		if err := par.SetEncoder("mpeg4"); err != nil {
+			return nil, err
+		}
+		if err := par.SetRateControl(ffmpeg.RateControl{BitRate: 200000}); err != nil {
+			return nil, err
+		}
+		return par, nil
+	}, ffmpeg.OptTwoPass()); !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Read the output back
+	r2, err := ffmpeg.Open(filename)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+	assert.Equal("mpeg4", r2.Par(r2.BestStream(media.VIDEO)).CodecID().Name())
+	assert.InDelta(r.Duration().Seconds(), r2.Duration().Seconds(), 1.0)
+
+	// Invalid pass
+	_, err = ffmpeg.Create(filename, ffmpeg.OptPass(3, nil))
+	assert.Error(err)
+}
@@ -15,6 +15,8 @@ type resampler struct {
 	ctx   *ff.SWRContext
 	dest  *Frame
 	force bool
+	pool  *FramePool
+	size  int // Number of samples allocated in the destination frame
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -49,6 +51,7 @@ func NewResampler(par *Par, force bool) (*resampler, error) {
 	// Set parameters
 	resampler.dest = dest
 	resampler.force = force
+	resampler.pool = NewFramePool()
 
 	// Return success
 	return resampler, nil
@@ -60,7 +63,7 @@ func (r *resampler) Close() error {
 		ff.SWResample_free(r.ctx)
 		r.ctx = nil
 	}
-	result := r.dest.Close()
+	result := errors.Join(r.dest.Close(), r.pool.Close())
 	r.dest = nil
 	return result
 }
@@ -106,23 +109,17 @@ func (r *resampler) Frame(src *Frame) (*Frame, error) {
 		return nil, nil
 	}
 
-	// Check buffer
-	// TODO: UGLY CODE ALERT
-	if r.dest.NumSamples() < num_samples {
-		sample_fmt := r.dest.SampleFormat()
-		sample_rate := r.dest.SampleRate()
-		sample_ch := r.dest.ChannelLayout()
-		sample_tb := r.dest.TimeBase()
-		sample_pts := r.dest.Pts()
-		r.dest.Unref()
-		(*ff.AVFrame)(r.dest).SetSampleFormat(sample_fmt)
-		(*ff.AVFrame)(r.dest).SetSampleRate(sample_rate)
-		(*ff.AVFrame)(r.dest).SetChannelLayout(sample_ch)
-		(*ff.AVFrame)(r.dest).SetNumSamples(num_samples)
-		(*ff.AVFrame)(r.dest).SetTimeBase(sample_tb)
-		(*ff.AVFrame)(r.dest).SetPts(sample_pts)
-		if err := r.dest.AllocateBuffers(); err != nil {
-			ff.SWResample_free(r.ctx)
+	// Grow the destination frame, or replace the buffers if the previous
+	// destination frame is still referenced
+	if num_samples > r.size {
+		r.size = num_samples
+		(*ff.AVFrame)(r.dest).SetNumSamples(r.size)
+		if err := r.pool.realloc(r.dest); err != nil {
+			return nil, err
+		}
+	} else {
+		(*ff.AVFrame)(r.dest).SetNumSamples(r.size)
+		if err := r.pool.makeWritable(r.dest); err != nil {
 			return nil, err
 		}
 	}
@@ -146,6 +143,15 @@ func (r *resampler) Frame(src *Frame) (*Frame, error) {
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS
 
+// Discard any samples buffered in the resampling context. The context is
+// re-created when the next frame is resampled.
+func (r *resampler) reset() {
+	if r.ctx != nil {
+		ff.SWResample_free(r.ctx)
+		r.ctx = nil
+	}
+}
+
 func swrConvertFrame(ctx *ff.SWRContext, src, dest *ff.AVFrame) error {
 	return ff.SWResample_convert_frame(ctx, src, dest)
 }
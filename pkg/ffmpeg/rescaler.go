@@ -15,6 +15,7 @@ type rescaler struct {
 	flags ff.SWSFlag
 	force bool
 	dest  *Frame
+	pool  *FramePool
 
 	src_pix_fmt ff.AVPixelFormat
 	src_width   int
@@ -50,13 +51,11 @@ func NewRescaler(par *Par, force bool) (*rescaler, error) {
 	rescaler.dest = dest
 	rescaler.force = force
 	rescaler.flags = ff.SWS_POINT
+	rescaler.pool = NewFramePool()
 
 	// Allocate buffer
-	if err := dest.AllocateBuffers(); err != nil {
-		return nil, errors.Join(err, dest.Close())
-	} else {
-		rescaler.dest = dest
-
+	if err := rescaler.pool.makeWritable(dest); err != nil {
+		return nil, errors.Join(err, rescaler.Close())
 	}
 
 	// Return success
@@ -68,7 +67,7 @@ func (r *rescaler) Close() error {
 	if r.ctx != nil {
 		ff.SWScale_free_context(r.ctx)
 	}
-	result := r.dest.Close()
+	result := errors.Join(r.dest.Close(), r.pool.Close())
 	r.dest = nil
 	r.ctx = nil
 	return result
@@ -110,6 +109,11 @@ func (r *rescaler) Frame(src *Frame) (*Frame, error) {
 		}
 	}
 
+	// The previous destination frame may still be referenced
+	if err := r.pool.makeWritable(r.dest); err != nil {
+		return nil, err
+	}
+
 	// Copy parameters from the source frame
 	if err := r.dest.CopyPropsFromFrame(src); err != nil {
 		return nil, err
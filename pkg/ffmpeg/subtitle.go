@@ -0,0 +1,280 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"time"
+
+	// Packages
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type subtitle struct {
+	Start time.Duration   `json:"start"`
+	End   time.Duration   `json:"end"`
+	Rects []*SubtitleRect `json:"rects,omitempty"`
+}
+
+// Subtitle is a decoded subtitle, which is displayed between the start
+// and end times, with one or more text, ASS or bitmap rectangles
+type Subtitle struct {
+	subtitle
+}
+
+type subtitleRect struct {
+	Type   ff.AVSubtitleType `json:"type"`
+	Text   string            `json:"text,omitempty"`
+	Ass    string            `json:"ass,omitempty"`
+	Bounds *image.Rectangle  `json:"bounds,omitempty"`
+}
+
+// SubtitleRect is a text, ASS or bitmap area of a subtitle
+type SubtitleRect struct {
+	subtitleRect
+	image *image.Paletted
+}
+
+// DecoderSubtitleFn is a function which is called for each subtitle
+// decoded, with the index of the stream. It can return io.EOF to end
+// decoding early.
+type DecoderSubtitleFn func(int, *Subtitle) error
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+// The default ASS header used by text subtitle encoders, with a
+// single "Default" style
+const assHeader = `[Script Info]
+ScriptType: v4.00+
+PlayResX: 384
+PlayResY: 288
+ScaledBorderAndShadow: yes
+YCbCr Matrix: None
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding
+Style: Default,Arial,16,&Hffffff,&Hffffff,&H0,&H0,0,0,0,0,100,100,0,0,1,1,0,2,10,10,10,1
+
+[Events]
+Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text
+`
+
+////////////////////////////////////////////////////////////////////////////////
+// LIFECYCLE
+
+// Create a text subtitle which is displayed between the start and end
+// times, for encoding with Writer.WriteSubtitle. Lines are separated with
+// newlines, and ASS override tags in braces (for example "{\i1}") can be
+// used for formatting.
+func NewSubtitle(start, end time.Duration, text string) *Subtitle {
+	if end < start {
+		end = start
+	}
+	return &Subtitle{
+		subtitle: subtitle{
+			Start: start,
+			End:   end,
+			Rects: []*SubtitleRect{
+				{subtitleRect: subtitleRect{Type: ff.SUBTITLE_TEXT, Text: text}},
+			},
+		},
+	}
+}
+
+// Create a subtitle from an AVSubtitle, with the timestamp in AV_TIME_BASE
+// units. The text and bitmaps are copied, so the AVSubtitle can be freed
+// afterwards.
+func newSubtitle(ctx *ff.AVSubtitle, pts int64) *Subtitle {
+	ms := ff.AVUtil_rational(1, 1000)
+	tb := ff.AVUtil_rational(1, ff.AV_TIME_BASE)
+	start := pts + ff.AVUtil_rational_rescale_q(int64(ctx.StartDisplayTime()), ms, tb)
+	end := pts + ff.AVUtil_rational_rescale_q(int64(ctx.EndDisplayTime()), ms, tb)
+	if end < start {
+		end = start
+	}
+
+	// Copy the rects
+	rects := make([]*SubtitleRect, 0, ctx.NumRects())
+	for _, rect := range ctx.Rects() {
+		rects = append(rects, newSubtitleRect(rect))
+	}
+
+	return &Subtitle{
+		subtitle: subtitle{
+			Start: tsDuration(start, tb),
+			End:   tsDuration(end, tb),
+			Rects: rects,
+		},
+	}
+}
+
+// Create a subtitle rect from an AVSubtitleRect
+func newSubtitleRect(ctx *ff.AVSubtitleRect) *SubtitleRect {
+	rect := &SubtitleRect{
+		subtitleRect: subtitleRect{
+			Type: ctx.Type(),
+			Text: ctx.Text(),
+			Ass:  ctx.Ass(),
+		},
+	}
+
+	// Copy the bitmap into a paletted image, with the palette converted
+	// from ARGB
+	if ctx.Type() == ff.SUBTITLE_BITMAP && ctx.Width() > 0 && ctx.Height() > 0 {
+		palette := make(color.Palette, 0, ctx.NumColors())
+		for _, argb := range ctx.Palette() {
+			palette = append(palette, color.NRGBA{R: uint8(argb >> 16), G: uint8(argb >> 8), B: uint8(argb), A: uint8(argb >> 24)})
+		}
+		bounds := image.Rect(ctx.X(), ctx.Y(), ctx.X()+ctx.Width(), ctx.Y()+ctx.Height())
+		img := image.NewPaletted(bounds, palette)
+		bitmap, stride := ctx.Bitmap(), ctx.Linesize()
+		for y := 0; y < ctx.Height(); y++ {
+			copy(img.Pix[y*img.Stride:y*img.Stride+ctx.Width()], bitmap[y*stride:])
+		}
+		rect.image = img
+		rect.subtitleRect.Bounds = &bounds
+	}
+
+	// Return the rect
+	return rect
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (s *Subtitle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.subtitle)
+}
+
+func (s *Subtitle) String() string {
+	data, _ := json.MarshalIndent(s, "", "  ")
+	return string(data)
+}
+
+func (r *SubtitleRect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.subtitleRect)
+}
+
+func (r *SubtitleRect) String() string {
+	data, _ := json.MarshalIndent(r, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - SUBTITLE
+
+// Return the time when the subtitle is displayed
+func (s *Subtitle) Start() time.Duration {
+	return s.subtitle.Start
+}
+
+// Return the time when the subtitle is hidden, or the start time if
+// the end time is unknown
+func (s *Subtitle) End() time.Duration {
+	return s.subtitle.End
+}
+
+// Return the text, ASS and bitmap rectangles of the subtitle
+func (s *Subtitle) Rects() []*SubtitleRect {
+	return s.subtitle.Rects
+}
+
+// Return the plain text of the subtitle, with a line for each text or
+// ASS rectangle
+func (s *Subtitle) Text() string {
+	lines := make([]string, 0, len(s.subtitle.Rects))
+	for _, rect := range s.subtitle.Rects {
+		if text := rect.Text(); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - RECT
+
+// Return the type of the rectangle
+func (r *SubtitleRect) Type() ff.AVSubtitleType {
+	return r.subtitleRect.Type
+}
+
+// Return the plain text of the rectangle. For ASS rectangles, the dialogue
+// text is returned without the formatting.
+func (r *SubtitleRect) Text() string {
+	switch r.subtitleRect.Type {
+	case ff.SUBTITLE_TEXT:
+		return r.subtitleRect.Text
+	case ff.SUBTITLE_ASS:
+		return assText(r.subtitleRect.Ass)
+	default:
+		return ""
+	}
+}
+
+// Return the ASS event for the rectangle, or an empty string
+func (r *SubtitleRect) Ass() string {
+	return r.subtitleRect.Ass
+}
+
+// Return the bitmap of the rectangle, positioned within the video frame,
+// or nil if the rectangle is not a bitmap
+func (r *SubtitleRect) Image() image.Image {
+	if r.image == nil {
+		return nil
+	}
+	return r.image
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+var (
+	assReplacer  = strings.NewReplacer(`\N`, "\n", `\n`, "\n", `\h`, " ")
+	textReplacer = strings.NewReplacer("\r\n", `\N`, "\n", `\N`)
+)
+
+// Return the ASS event for a rectangle, with the read order of the event
+// within the stream, or an empty string for bitmap rectangles
+func (r *SubtitleRect) assEvent(readorder int) string {
+	switch r.subtitleRect.Type {
+	case ff.SUBTITLE_ASS:
+		return r.subtitleRect.Ass
+	case ff.SUBTITLE_TEXT:
+		return fmt.Sprintf("%d,0,Default,,0,0,0,,%s", readorder, textReplacer.Replace(r.subtitleRect.Text))
+	default:
+		return ""
+	}
+}
+
+// Return the dialogue text from an ASS event, which has the fields
+// ReadOrder, Layer, Style, Name, MarginL, MarginR, MarginV, Effect, Text.
+// Override blocks in braces are removed.
+func assText(event string) string {
+	if fields := strings.SplitN(event, ",", 9); len(fields) == 9 {
+		event = fields[8]
+	}
+
+	// Remove override blocks
+	var text strings.Builder
+	depth := 0
+	for _, ch := range event {
+		switch {
+		case ch == '{':
+			depth++
+		case ch == '}' && depth > 0:
+			depth--
+		case depth == 0:
+			text.WriteRune(ch)
+		}
+	}
+
+	// Replace line breaks and hard spaces
+	return strings.TrimSpace(assReplacer.Replace(text.String()))
+}
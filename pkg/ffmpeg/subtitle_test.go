@@ -0,0 +1,72 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_subtitle_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Open the subtitle file
+	input, err := ffmpeg.Open("../../etc/test/sample.srt")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer input.Close()
+
+	// Decode the subtitle stream
+	var subtitles []*ffmpeg.Subtitle
+	mapfn := func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() == media.SUBTITLE {
+			return par, nil
+		}
+		return nil, nil
+	}
+	err = input.Decode(context.Background(), mapfn, func(stream int, frame *ffmpeg.Frame) error {
+		t.Error("unexpected frame", frame)
+		return nil
+	}, func(stream int, subtitle *ffmpeg.Subtitle) error {
+		t.Log(stream, subtitle)
+		subtitles = append(subtitles, subtitle)
+		return nil
+	})
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Check the subtitles
+	if !assert.Len(subtitles, 3) {
+		t.FailNow()
+	}
+	assert.Equal(time.Second, subtitles[0].Start())
+	assert.Equal(3500*time.Millisecond, subtitles[0].End())
+	assert.Equal("Hello, world!", subtitles[0].Text())
+	assert.Equal("This is a subtitle\non two lines.", subtitles[1].Text())
+	assert.Equal(7250*time.Millisecond, subtitles[2].Start())
+	assert.Equal("Goodbye.", subtitles[2].Text())
+}
+
+func Test_subtitle_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Open the subtitle file
+	input, err := ffmpeg.Open("../../etc/test/sample.srt")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer input.Close()
+
+	// Subtitles are ignored without a subtitle function
+	err = input.Decode(context.Background(), nil, func(stream int, frame *ffmpeg.Frame) error {
+		t.Error("unexpected frame", frame)
+		return nil
+	})
+	assert.NoError(err)
+}
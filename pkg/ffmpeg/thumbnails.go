@@ -0,0 +1,197 @@
+package ffmpeg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"syscall"
+	"time"
+
+	// Packages
+	media "github.com/mutablelogic/go-media"
+	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
+
+	// Namespace imports
+	. "github.com/djthorpe/go-errors"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// ThumbnailFn is called with the timestamp and image of each thumbnail. It
+// should return nil to continue or io.EOF to stop.
+type ThumbnailFn func(time.Duration, image.Image) error
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Generate thumbnails from the best video stream, one for each interval. The
+// reader seeks to the first keyframe at or after the start of each interval,
+// and only keyframes are decoded. Each image is scaled to fit within the
+// bounding box size (for example, "320x240"), preserving the display aspect
+// ratio. Where keyframes are further apart than the interval, intervals
+// without a keyframe are skipped.
+//
+// The thumbnails can be interrupted by cancelling the context, or by the fn
+// returning an error or io.EOF. The latter will end early but will not
+// return an error. The read position of the reader is not restored.
+func (r *Reader) Thumbnails(ctx context.Context, interval time.Duration, size string, fn ThumbnailFn) error {
+	if interval <= 0 {
+		return ErrBadParameter.Withf("invalid interval %v", interval)
+	} else if fn == nil {
+		return ErrBadParameter.With("ThumbnailFn is nil")
+	}
+
+	// Get the video stream and the bounding box
+	stream := r.BestStream(media.VIDEO)
+	if stream < 0 {
+		return ErrNotFound.With("no video stream")
+	}
+	bw, bh, err := ff.AVUtil_parse_video_size(size)
+	if err != nil {
+		return ErrBadParameter.Withf("invalid size %q", size)
+	}
+	w, h := thumbnailSize(r.Par(stream), bw, bh)
+	if w == 0 || h == 0 {
+		return ErrBadParameter.With("unknown video frame size")
+	}
+
+	// Create a decoder which scales to RGBA, and only decodes keyframes
+	decoder, err := NewDecoder(r.input.Stream(stream), VideoPar("rgba", fmt.Sprintf("%dx%d", w, h), 0), r.force)
+	if err != nil {
+		return err
+	}
+	defer decoder.Close()
+	decoder.codec.SetSkipFrame(ff.AVDISCARD_NONKEY)
+
+	// Allocate a packet
+	packet := ff.AVCodec_packet_alloc()
+	if packet == nil {
+		return errors.New("failed to allocate packet")
+	}
+	defer ff.AVCodec_packet_free(packet)
+
+	// Seek to each interval and decode the next keyframe
+	last := time.Duration(-1)
+	for t := time.Duration(0); ; {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Seek to the first keyframe at or after the timestamp. An error
+		// indicates there are no more keyframes
+		pts := ff.AVUtil_rational_rescale_q(int64(t), ff.AVUtil_rational(1, int(time.Second)), decoder.timeBase)
+		if err := ff.AVFormat_seek_frame(r.input, stream, pts, ff.AVSEEK_FLAG_NONE); err != nil {
+			break
+		}
+		decoder.flush(ff.AV_NOPTS_VALUE)
+
+		// Decode the keyframe
+		ts, img, err := r.thumbnail(ctx, decoder, packet)
+		if err != nil {
+			return err
+		} else if img == nil {
+			break
+		}
+
+		// Pass back the thumbnail, unless the keyframe was returned before
+		if ts < 0 {
+			ts = t
+		}
+		if ts > last {
+			if err := fn(ts, img); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return err
+			}
+			last = ts
+		}
+
+		// Advance to the next interval after the keyframe
+		t += interval
+		if ts >= t {
+			t = (ts/interval + 1) * interval
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Read keyframe packets for the decoder stream until a frame is decoded, and
+// return a copy of the frame as an image with the timestamp, or -1 if the
+// timestamp is unknown. Returns a nil image at the end of the stream.
+func (r *Reader) thumbnail(ctx context.Context, decoder *Decoder, packet *ff.AVPacket) (time.Duration, image.Image, error) {
+	var ts time.Duration
+	var img image.Image
+	fn := func(_ int, frame *Frame) error {
+		if src, err := frame.Image(); err != nil {
+			return err
+		} else {
+			dest := image.NewRGBA(src.Bounds())
+			draw.Draw(dest, dest.Bounds(), src, src.Bounds().Min, draw.Src)
+			img = dest
+		}
+		if frame.Pts() == ff.AV_NOPTS_VALUE {
+			ts = -1
+		} else {
+			ts = time.Duration(frame.Ts() * float64(time.Second))
+		}
+		return io.EOF
+	}
+
+	for img == nil {
+		if err := ctx.Err(); err != nil {
+			return 0, nil, err
+		}
+		if err := ff.AVFormat_read_frame(r.input, packet); errors.Is(err, io.EOF) {
+			// Flush the decoder at the end of the stream
+			if err := decoder.decode(nil, fn); err != nil && !errors.Is(err, io.EOF) {
+				return 0, nil, err
+			}
+			break
+		} else if errors.Is(err, syscall.EAGAIN) {
+			continue
+		} else if err != nil {
+			return 0, nil, ErrInternalAppError.With("AVFormat_read_frame: ", err)
+		}
+
+		// Decode keyframes for the stream
+		var err error
+		if packet.StreamIndex() == decoder.stream && packet.Flags().Is(ff.AV_PKT_FLAG_KEY) {
+			err = decoder.decode(packet, fn)
+		}
+		ff.AVCodec_packet_unref(packet)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, nil, err
+		}
+	}
+
+	// Return the thumbnail
+	return ts, img, nil
+}
+
+// Return the size of a frame which fits within the bounding box, preserving
+// the display aspect ratio of the stream
+func thumbnailSize(par *Par, bw, bh int) (int, int) {
+	w, h := par.Width(), par.Height()
+	if w <= 0 || h <= 0 {
+		return 0, 0
+	}
+
+	// Display width, from the sample aspect ratio
+	dw := float64(w)
+	if sar := par.SampleAspectRatio(); sar.Num() > 0 && sar.Den() > 0 {
+		dw = dw * float64(sar.Num()) / float64(sar.Den())
+	}
+
+	// Scale to fit the bounding box
+	scale := min(float64(bw)/dw, float64(bh)/float64(h))
+	return max(int(dw*scale+0.5), 1), max(int(float64(h)*scale+0.5), 1)
+}
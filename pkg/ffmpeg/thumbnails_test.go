@@ -0,0 +1,65 @@
+package ffmpeg_test
+
+import (
+	"context"
+	"image"
+	"io"
+	"testing"
+	"time"
+
+	// Packages
+	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
+	assert "github.com/stretchr/testify/assert"
+)
+
+func Test_thumbnails_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Generate thumbnails each second, within a bounding box
+	var n int
+	last := time.Duration(-1)
+	assert.NoError(r.Thumbnails(context.Background(), time.Second, "160x160", func(ts time.Duration, img image.Image) error {
+		assert.Greater(ts, last)
+		assert.LessOrEqual(img.Bounds().Dx(), 160)
+		assert.LessOrEqual(img.Bounds().Dy(), 160)
+		assert.True(img.Bounds().Dx() == 160 || img.Bounds().Dy() == 160)
+		last = ts
+		n++
+		t.Log(ts, img.Bounds())
+		return nil
+	}))
+	assert.NotZero(n)
+
+	// Stop after the first thumbnail
+	n = 0
+	assert.NoError(r.Thumbnails(context.Background(), time.Second, "160x160", func(ts time.Duration, img image.Image) error {
+		n++
+		return io.EOF
+	}))
+	assert.Equal(1, n)
+}
+
+func Test_thumbnails_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file without video
+	r, err := ffmpeg.Open("../../etc/test/sample.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	fn := func(ts time.Duration, img image.Image) error {
+		return nil
+	}
+	assert.Error(r.Thumbnails(context.Background(), time.Second, "160x160", fn))
+	assert.Error(r.Thumbnails(context.Background(), 0, "160x160", fn))
+	assert.Error(r.Thumbnails(context.Background(), time.Second, "160x160", nil))
+}
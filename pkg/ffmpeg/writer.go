@@ -1,15 +1,21 @@
 package ffmpeg
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
 	"io"
 	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"time"
 
 	// Packages
+	file "github.com/mutablelogic/go-media/pkg/file"
 	ff "github.com/mutablelogic/go-media/sys/ffmpeg61"
 	maps "golang.org/x/exp/maps"
 
@@ -25,10 +31,21 @@ type Writer struct {
 	output   *ff.AVFormatContext
 	header   bool
 	encoders []*Encoder
+	copies   []*ff.AVStream           // Streams which are copied without encoding
+	filters  map[int]*BitstreamFilter // Bitstream filters for copied streams
+	pictures []*picture               // Attached pictures, written after the header
+}
+
+// An attached picture stream and the image data
+type picture struct {
+	stream *ff.AVStream
+	data   []byte
 }
 
 type writer_callback struct {
-	w io.Writer
+	w      io.Writer
+	fn     WriterFragmentFn
+	offset int64
 }
 
 // EncoderFrameFn is a function which is called to receive a frame to encode. It should
@@ -39,6 +56,12 @@ type EncoderFrameFn func(int) (*Frame, error)
 // the stream timebase.
 type EncoderPacketFn func(*Packet) error
 
+// WriterFragmentFn is a function which is called at each fragment boundary
+// when writing fragmented output, before the fragment is written. It receives
+// the byte offset of the fragment, the timestamp of the fragment (or -1 if
+// unknown) and whether the fragment starts with a keyframe.
+type WriterFragmentFn func(offset int64, ts time.Duration, keyframe bool) error
+
 //////////////////////////////////////////////////////////////////////////////
 // GLOBALS
 
@@ -46,6 +69,11 @@ const (
 	bufSize = 4096
 )
 
+// Muxer options for MP4 when the output is not seekable
+const (
+	fragmentedMovFlags = "movflags=frag_keyframe+empty_moov+default_base_moof"
+)
+
 //////////////////////////////////////////////////////////////////////////////
 // LIFECYCLE
 
@@ -82,7 +110,16 @@ func Create(url string, opt ...Opt) (*Writer, error) {
 	return writer.open(options)
 }
 
-// Create a new writer with an io.Writer and options
+// Create a new writer with an io.Writer and options. If the writer does not
+// implement io.ReadSeeker, or cannot seek (for example, a pipe or HTTP
+// response) then the output is streamed:
+//
+//   - MP4 and MOV output is fragmented, unless "movflags" is set with
+//     OptOutputOpt. The fragments can be observed with OptFragment.
+//   - Matroska and WebM output omits the cues and the duration, which are
+//     otherwise written when the writer is closed.
+//   - Other formats which require seeking return an error when the header
+//     is written.
 func NewWriter(w io.Writer, opt ...Opt) (*Writer, error) {
 	options := newOpts()
 	writer := new(Writer)
@@ -111,10 +148,13 @@ func NewWriter(w io.Writer, opt ...Opt) (*Writer, error) {
 	}
 
 	// Allocate the AVIO context
-	avio := ff.AVFormat_avio_alloc_context(bufSize, true, &writer_callback{w})
+	avio := ff.AVFormat_avio_alloc_context(bufSize, true, &writer_callback{w: w, fn: options.fragment})
 	if avio == nil {
 		return nil, errors.New("failed to allocate avio context")
-	} else if ctx, err := ff.AVFormat_open_writer(avio, options.oformat, filename); err != nil {
+	} else {
+		avio.SetSeekable(isSeekable(w))
+	}
+	if ctx, err := ff.AVFormat_open_writer(avio, options.oformat, filename); err != nil {
 		return nil, err
 	} else {
 		writer.output = ctx
@@ -127,9 +167,36 @@ func NewWriter(w io.Writer, opt ...Opt) (*Writer, error) {
 func (writer *Writer) open(options *opts) (*Writer, error) {
 	// Create codec contexts for each stream
 	var result error
+	writer.filters = make(map[int]*BitstreamFilter, len(options.bsf))
 	keys := sort.IntSlice(maps.Keys(options.streams))
 	for _, stream := range keys {
-		encoder, err := NewEncoder(writer.output, stream, options.streams[stream])
+		if options.copies[stream] {
+			// The output stream parameters are set by the bitstream filter
+			par := options.streams[stream]
+			if desc, exists := options.bsf[stream]; exists {
+				if filter, err := NewBitstreamFilter(desc, par); err != nil {
+					result = errors.Join(result, fmt.Errorf("stream %v: %w", stream, err))
+					continue
+				} else {
+					writer.filters[stream] = filter
+					par = filter.Par()
+				}
+			}
+			if copy, err := newStreamCopy(writer.output, stream, par); err != nil {
+				result = errors.Join(result, err)
+			} else {
+				writer.copies = append(writer.copies, copy)
+			}
+			continue
+		}
+		var pass *encoderPass
+		if options.pass > 0 {
+			pass = &encoderPass{pass: options.pass, stats: options.stats[stream]}
+			if options.passlog != "" {
+				pass.log = filepath.Join(options.passlog, fmt.Sprintf("stream%d.log", stream))
+			}
+		}
+		encoder, err := newEncoder(writer.output, stream, options.streams[stream], pass)
 		if err != nil {
 			result = errors.Join(result, err)
 			continue
@@ -138,11 +205,32 @@ func (writer *Writer) open(options *opts) (*Writer, error) {
 		}
 	}
 
+	// Bitstream filters can only be applied to copied streams
+	for stream := range options.bsf {
+		if !options.copies[stream] {
+			result = errors.Join(result, ErrBadParameter.Withf("stream %v: bitstream filter requires a copied stream", stream))
+		}
+	}
+
 	// Return any errors
 	if result != nil {
 		return nil, errors.Join(result, writer.Close())
 	}
 
+	// Set stream metadata and disposition
+	for stream, entries := range options.streammeta {
+		if err := writer.setStreamMetadata(stream, entries); err != nil {
+			return nil, errors.Join(err, writer.Close())
+		}
+	}
+	for stream, disposition := range options.disposition {
+		if s := writer.stream(stream); s == nil {
+			return nil, errors.Join(ErrBadParameter.Withf("invalid stream %v", stream), writer.Close())
+		} else {
+			s.SetDisposition(s.Disposition() | ff.AVDisposition(disposition))
+		}
+	}
+
 	// Add metadata
 	metadata := ff.AVUtil_dict_alloc()
 	if metadata == nil {
@@ -161,22 +249,64 @@ func (writer *Writer) open(options *opts) (*Writer, error) {
 
 	// Add artwork
 	for _, entry := range options.metadata {
-		// Ignore artwork fields
+		// Ignore non-artwork fields
 		if entry.Key() != MetaArtwork || len(entry.Bytes()) == 0 {
 			continue
 		}
-		fmt.Println("TODO: Add artwork")
+		if err := writer.addArtwork(entry); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return nil, errors.Join(err, writer.Close())
+		}
+	}
+
+	// Add chapters, or the chapters of the input media
+	chapters := options.chapters
+	if len(chapters) == 0 && !options.nochapters {
+		chapters = options.inputchaps
+	}
+	for i, chapter := range chapters {
+		if err := chapter.write(writer.output, int64(i+1)); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return nil, errors.Join(err, writer.Close())
+		}
+	}
+
+	// Set the muxer options
+	muxopts := options.muxopts
+	if pb := writer.output.Pb(); pb != nil && !pb.Seekable() && isMov(writer.output.Output()) && !hasOpt(muxopts, "movflags") {
+		muxopts = append([]string{fragmentedMovFlags}, muxopts...)
+	}
+	dict := ff.AVUtil_dict_alloc()
+	defer ff.AVUtil_dict_free(dict)
+	if len(muxopts) > 0 {
+		if err := ff.AVUtil_dict_parse_string(dict, strings.Join(muxopts, " "), "=", " ", 0); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return nil, errors.Join(err, writer.Close())
+		}
 	}
 
 	// Set metadata, write the header
 	// Metadata ownership is transferred to the output context
 	writer.output.SetMetadata(metadata)
-	if err := ff.AVFormat_write_header(writer.output, nil); err != nil {
+	if err := ff.AVFormat_write_header(writer.output, dict); err != nil {
 		return nil, errors.Join(err, writer.Close())
 	} else {
 		writer.header = true
 	}
 
+	// If there are any non-consumed options, then error
+	for _, key := range ff.AVUtil_dict_keys(dict) {
+		result = errors.Join(result, ErrBadParameter.Withf("invalid output option %q", key))
+	}
+	if result != nil {
+		return nil, errors.Join(result, writer.Close())
+	}
+
+	// Write the attached pictures before any other packets
+	if err := writer.writePictures(); err != nil {
+		return nil, errors.Join(err, writer.Close())
+	}
+
 	// Return success
 	return writer, nil
 }
@@ -185,6 +315,18 @@ func (writer *Writer) open(options *opts) (*Writer, error) {
 func (w *Writer) Close() error {
 	var result error
 
+	// Flush the bitstream filters, then close them
+	for stream, filter := range w.filters {
+		if w.header {
+			if err := filter.Packet(nil, func(packet *Packet) error {
+				return w.writePacket(w.stream(stream), packet)
+			}); err != nil && !errors.Is(err, io.EOF) {
+				result = errors.Join(result, err)
+			}
+		}
+		result = errors.Join(result, filter.Close())
+	}
+
 	// Write the trailer if the header was written
 	if w.header {
 		if err := ff.AVFormat_write_trailer(w.output); err != nil {
@@ -205,6 +347,9 @@ func (w *Writer) Close() error {
 	// Free resources
 	w.output = nil
 	w.encoders = nil
+	w.copies = nil
+	w.filters = nil
+	w.pictures = nil
 
 	// Return any errors
 	return result
@@ -237,6 +382,19 @@ func (w *Writer) Stream(stream int) *Encoder {
 	return nil
 }
 
+// Return the first pass statistics for each video stream, when encoding
+// with OptPass(1, nil). The statistics are complete once the encoders have
+// been flushed, and are passed to the second pass with OptPass(2, stats).
+func (w *Writer) Stats() map[int]string {
+	stats := make(map[int]string, len(w.encoders))
+	for _, encoder := range w.encoders {
+		if encoder.pass == 1 {
+			stats[encoder.stream.Id()] = encoder.Stats()
+		}
+	}
+	return stats
+}
+
 // Encode frames from all encoders, calling the callback function to encode
 // the frame. If the callback function returns io.EOF then the encoding for
 // that encoder is stopped after flushing. If the second callback is nil,
@@ -252,9 +410,12 @@ func (w *Writer) Encode(ctx context.Context, in EncoderFrameFn, out EncoderPacke
 		}
 	}
 
-	// Initialise encoders
+	// Initialise encoders. Subtitle encoders are written with WriteSubtitle
 	encoders := make(map[int]*Encoder, len(w.encoders))
 	for _, encoder := range w.encoders {
+		if encoder.isSubtitle() {
+			continue
+		}
 		stream := encoder.stream.Index()
 		if _, exists := encoders[stream]; exists {
 			return ErrBadParameter.Withf("duplicate stream %v", stream)
@@ -303,11 +464,266 @@ func (w *Writer) Write(packet *Packet) error {
 	return ff.AVCodec_interleaved_write_frame(w.output, (*ff.AVPacket)(packet))
 }
 
+// Write a packet from another source (for example, from Reader.Demux) to the
+// output stream with the given identifier. The packet timestamps are rescaled
+// from the packet timebase to the output stream timebase, and the packet is
+// written with Write. If a bitstream filter is set for the stream, then the
+// packet is filtered first. A nil packet flushes the output.
+func (w *Writer) WritePacket(stream int, packet *Packet) error {
+	if packet == nil {
+		return w.Write(nil)
+	}
+
+	// Find the output stream
+	dest := w.stream(stream)
+	if dest == nil {
+		return ErrBadParameter.Withf("invalid stream %v", stream)
+	}
+
+	// Filter the packet
+	if filter, exists := w.filters[stream]; exists {
+		return filter.Packet(packet, func(packet *Packet) error {
+			return w.writePacket(dest, packet)
+		})
+	}
+
+	// Write the packet
+	return w.writePacket(dest, packet)
+}
+
+// Encode a subtitle and write it to the output stream with the given
+// identifier. Subtitles should be written in order of start time, but
+// can be written before or between the audio and video packets, as the
+// packets are interleaved by the muxer.
+func (w *Writer) WriteSubtitle(stream int, subtitle *Subtitle) error {
+	encoder := w.Stream(stream)
+	if encoder == nil {
+		return ErrBadParameter.Withf("invalid stream %v", stream)
+	}
+	return encoder.EncodeSubtitle(subtitle, w.Write)
+}
+
 // Returns -1 if a is before v
 func compareNextPts(a, b *Encoder) int {
 	return ff.AVUtil_compare_ts(a.next_pts, a.stream.TimeBase(), b.next_pts, b.stream.TimeBase())
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - Streams
+
+// Rescale the packet timestamps to the output stream, and write the packet
+func (w *Writer) writePacket(dest *ff.AVStream, packet *Packet) error {
+	pkt := (*ff.AVPacket)(packet)
+	if tb := pkt.TimeBase(); tb.Num() != 0 && tb.Den() != 0 {
+		ff.AVCodec_packet_rescale_ts(pkt, tb, dest.TimeBase())
+	}
+	pkt.SetStreamIndex(dest.Index())
+	pkt.SetTimeBase(dest.TimeBase())
+	pkt.SetPos(-1)
+	return w.Write(packet)
+}
+
+// Return the output stream with the given identifier, which is either
+// encoded or copied, or nil if the stream does not exist
+func (w *Writer) stream(stream int) *ff.AVStream {
+	if encoder := w.Stream(stream); encoder != nil {
+		return encoder.stream
+	}
+	for _, copy := range w.copies {
+		if copy.Id() == stream {
+			return copy
+		}
+	}
+	return nil
+}
+
+// Set metadata on an output stream, replacing any existing entries
+// with the same key
+func (w *Writer) setStreamMetadata(stream int, entries []*Metadata) error {
+	s := w.stream(stream)
+	if s == nil {
+		return ErrBadParameter.Withf("invalid stream %v", stream)
+	}
+	var result error
+	metadata := s.Metadata()
+	for _, entry := range entries {
+		if entry.Key() == MetaArtwork || entry.Key() == "" {
+			continue
+		}
+		if err := ff.AVUtil_dict_set(metadata, entry.Key(), entry.Value(), 0); err != nil {
+			result = err
+			break
+		}
+	}
+
+	// The dictionary may have been reallocated
+	s.SetMetadata(metadata)
+
+	// Return any errors
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - Stream copy
+
+// Create an output stream which copies the codec parameters
+func newStreamCopy(ctx *ff.AVFormatContext, stream int, par *Par) (*ff.AVStream, error) {
+	streamctx := ff.AVFormat_new_stream(ctx, nil)
+	if streamctx == nil {
+		return nil, ErrInternalAppError.With("could not allocate stream")
+	} else if err := ff.AVCodec_parameters_copy(streamctx.CodecPar(), &par.AVCodecParameters); err != nil {
+		return nil, err
+	}
+
+	// Reset the codec tag, as it may not be valid for the output format
+	streamctx.CodecPar().SetCodecTag(0)
+
+	// Set stream identifier and hint the timebase
+	streamctx.SetId(stream)
+	streamctx.SetTimeBase(par.timebase)
+
+	// Return success
+	return streamctx, nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS - Artwork
+
+// Add artwork to the output. MP3, MP4 and FLAC use an attached picture stream,
+// and Matroska uses an attachment. Artwork is ignored for other formats.
+func (w *Writer) addArtwork(entry *Metadata) error {
+	var attachment bool
+	switch w.output.Output().Name() {
+	case "mp3", "mp4", "mov", "ipod", "flac":
+		attachment = false
+	case "matroska":
+		attachment = true
+	default:
+		return nil
+	}
+
+	// Determine the codec from the mimetype
+	data := entry.Bytes()
+	mimetype, ext, err := file.MimeType(data)
+	if err != nil {
+		return err
+	}
+	codec := artworkCodec(mimetype)
+	if codec == ff.AV_CODEC_ID_NONE {
+		return ErrBadParameter.Withf("unsupported artwork mimetype %q", mimetype)
+	}
+
+	// Create the stream
+	stream := ff.AVFormat_new_stream(w.output, nil)
+	if stream == nil {
+		return ErrInternalAppError.With("could not allocate stream")
+	}
+	par := stream.CodecPar()
+	par.SetCodecID(codec)
+
+	// Attachments embed the data in the codec parameters, attached pictures
+	// are written as a single packet after the header
+	metadata := ff.AVUtil_dict_alloc()
+	if attachment {
+		par.SetCodecType(ff.AVMEDIA_TYPE_ATTACHMENT)
+		if err := par.SetExtraData(data); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return err
+		}
+		filename := artworkFilename(w.output, stream, entry.PictureType(), ext)
+		if err := errors.Join(
+			ff.AVUtil_dict_set(metadata, "filename", filename, 0),
+			ff.AVUtil_dict_set(metadata, "mimetype", mimetype, 0),
+			ff.AVUtil_dict_set(metadata, "title", string(entry.PictureType()), 0),
+		); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return err
+		}
+	} else {
+		par.SetCodecType(ff.AVMEDIA_TYPE_VIDEO)
+		if config, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+			par.SetWidth(config.Width)
+			par.SetHeight(config.Height)
+		}
+		stream.SetDisposition(ff.AV_DISPOSITION_ATTACHED_PIC)
+		stream.SetTimeBase(ff.AVUtil_rational(1, 90000))
+		if err := ff.AVUtil_dict_set(metadata, "comment", string(entry.PictureType()), 0); err != nil {
+			ff.AVUtil_dict_free(metadata)
+			return err
+		}
+		w.pictures = append(w.pictures, &picture{stream, data})
+	}
+
+	// Metadata ownership is transferred to the stream
+	stream.SetMetadata(metadata)
+
+	// Return success
+	return nil
+}
+
+// Write the attached pictures, which is done once after the header
+func (w *Writer) writePictures() error {
+	pkt := ff.AVCodec_packet_alloc()
+	if pkt == nil {
+		return ErrInternalAppError.With("could not allocate packet")
+	}
+	defer ff.AVCodec_packet_free(pkt)
+
+	for _, picture := range w.pictures {
+		if err := ff.AVCodec_packet_from_bytes(pkt, picture.data); err != nil {
+			return err
+		}
+		pkt.SetStreamIndex(picture.stream.Index())
+		pkt.SetFlags(ff.AV_PKT_FLAG_KEY)
+		pkt.SetPts(0)
+		pkt.SetDts(0)
+		_, err := ff.AVFormat_write_frame(w.output, pkt)
+		ff.AVCodec_packet_unref(pkt)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Return success
+	return nil
+}
+
+// Return the codec for an artwork mimetype
+func artworkCodec(mimetype string) ff.AVCodecID {
+	switch mimetype {
+	case "image/jpeg":
+		return ff.AV_CODEC_ID_MJPEG
+	case "image/png":
+		return ff.AV_CODEC_ID_PNG
+	case "image/bmp":
+		return ff.AV_CODEC_ID_BMP
+	case "image/gif":
+		return ff.AV_CODEC_ID_GIF
+	case "image/webp":
+		return ff.AV_CODEC_ID_WEBP
+	default:
+		return ff.AV_CODEC_ID_NONE
+	}
+}
+
+// Return a filename for a Matroska attachment. The first front cover uses
+// the "cover" naming convention, other pictures are named by stream index.
+func artworkFilename(ctx *ff.AVFormatContext, stream *ff.AVStream, picture PictureType, ext string) string {
+	cover := "cover" + ext
+	if picture == PictureCoverFront {
+		unique := true
+		for _, other := range ctx.Streams() {
+			if entry := ff.AVUtil_dict_get(other.Metadata(), "filename", nil, 0); entry != nil && entry.Value() == cover {
+				unique = false
+			}
+		}
+		if unique {
+			return cover
+		}
+	}
+	return fmt.Sprintf("picture%d%s", stream.Index(), ext)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // PRIVATE METHODS - Encoding
 
@@ -394,3 +810,54 @@ func (w *writer_callback) Writer(buf []byte) int {
 		return n
 	}
 }
+
+func (w *writer_callback) WriterData(buf []byte, t ff.AVIODataMarker, ts int64) int {
+	// Call the fragment function at a fragment boundary
+	if w.fn != nil && (t == ff.AVIO_DATA_MARKER_SYNC_POINT || t == ff.AVIO_DATA_MARKER_BOUNDARY_POINT) {
+		ts_ := time.Duration(-1)
+		if ts != ff.AV_NOPTS_VALUE {
+			ts_ = time.Duration(ts) * time.Second / time.Duration(ff.AV_TIME_BASE)
+		}
+		if err := w.fn(w.offset, ts_, t == ff.AVIO_DATA_MARKER_SYNC_POINT); err != nil {
+			return -1
+		}
+	}
+
+	// Write the data
+	n := w.Writer(buf)
+	if n > 0 {
+		w.offset += int64(n)
+	}
+	return n
+}
+
+// Return true if the writer can seek, which is required by the
+// callback to seek
+func isSeekable(w io.Writer) bool {
+	seeker, ok := w.(io.ReadSeeker)
+	if !ok {
+		return false
+	}
+	_, err := seeker.Seek(0, io.SeekCurrent)
+	return err == nil
+}
+
+// Return true if the output format is MP4 or MOV
+func isMov(format *ff.AVOutputFormat) bool {
+	switch format.Name() {
+	case "mp4", "mov", "ipod", "ismv", "3gp", "3g2", "psp", "f4v":
+		return true
+	default:
+		return false
+	}
+}
+
+// Return true if an option with the key is in a list of key=value pairs
+func hasOpt(opts []string, key string) bool {
+	for _, opt := range opts {
+		if k, _, _ := strings.Cut(opt, "="); k == key {
+			return true
+		}
+	}
+	return false
+}
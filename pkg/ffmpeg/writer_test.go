@@ -1,12 +1,15 @@
 package ffmpeg_test
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
 	"testing"
+	"time"
 
 	// Packages
+	media "github.com/mutablelogic/go-media"
 	ffmpeg "github.com/mutablelogic/go-media/pkg/ffmpeg"
 	generator "github.com/mutablelogic/go-media/pkg/generator"
 	assert "github.com/stretchr/testify/assert"
@@ -211,3 +214,635 @@ func Test_writer_004(t *testing.T) {
 	}, nil))
 	t.Log("Written to", w.Name())
 }
+
+func Test_writer_005(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Write to a file
+	w, err := os.CreateTemp("", t.Name()+"_*.mkv")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer w.Close()
+
+	// Copy the audio and video streams
+	video, audio := r.BestStream(media.VIDEO), r.BestStream(media.AUDIO)
+	writer, err := ffmpeg.Create(w.Name(),
+		ffmpeg.OptMetadata(ffmpeg.NewMetadata("title", t.Name())),
+		ffmpeg.OptStreamCopy(1, r.Par(video)),
+		ffmpeg.OptStreamCopy(2, r.Par(audio)),
+		ffmpeg.OptChapters(r.Chapters()...),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer writer.Close()
+
+	// Remux the packets
+	mapfn := func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if stream == video || stream == audio {
+			return par, nil
+		}
+		return nil, nil
+	}
+	assert.NoError(r.Demux(context.Background(), mapfn, func(stream int, packet *ffmpeg.Packet) error {
+		switch stream {
+		case video:
+			return writer.WritePacket(1, packet)
+		case audio:
+			return writer.WritePacket(2, packet)
+		}
+		return nil
+	}))
+	t.Log("Written to", w.Name())
+}
+
+func Test_writer_006(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read the artwork
+	artwork, err := os.ReadFile("../../etc/test/sample.jpg")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Write to a file
+	w, err := os.CreateTemp("", t.Name()+"_*.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer w.Close()
+
+	// Create a writer with an audio stream and front and back covers
+	writer, err := ffmpeg.Create(w.Name(),
+		ffmpeg.OptMetadata(ffmpeg.NewMetadata("title", t.Name())),
+		ffmpeg.OptMetadata(ffmpeg.NewArtwork(artwork, ffmpeg.PictureCoverFront)),
+		ffmpeg.OptMetadata(ffmpeg.NewArtwork(artwork, ffmpeg.PictureCoverBack)),
+		ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Make an audio generator
+	audio, err := generator.NewSine(440, -5, writer.Stream(1).Par())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer audio.Close()
+
+	// Write 5 secs of frames
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		frame := audio.Frame()
+		if frame.Ts() >= 5 {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}, nil))
+	assert.NoError(writer.Close())
+
+	// Read the artwork back
+	r, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	pictures := r.Metadata(ffmpeg.MetaArtwork)
+	if assert.Len(pictures, 2) {
+		assert.Equal("image/jpeg", pictures[0].Value())
+		assert.Equal(ffmpeg.PictureCoverFront, pictures[0].PictureType())
+		assert.Equal(artwork, pictures[0].Bytes())
+		assert.Equal(ffmpeg.PictureCoverBack, pictures[1].PictureType())
+	}
+	t.Log("Written to", w.Name())
+}
+
+func Test_writer_007(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write to a file
+	w, err := os.CreateTemp("", t.Name()+"_*.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer w.Close()
+
+	// Create a writer with an audio stream and two chapters
+	writer, err := ffmpeg.Create(w.Name(),
+		ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)),
+		ffmpeg.OptChapter(0, 2*time.Second, ffmpeg.NewMetadata(ffmpeg.MetaTitle, "Chapter 1")),
+		ffmpeg.OptChapter(2*time.Second, 5*time.Second, ffmpeg.NewMetadata(ffmpeg.MetaTitle, "Chapter 2")),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Make an audio generator
+	audio, err := generator.NewSine(440, -5, writer.Stream(1).Par())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer audio.Close()
+
+	// Write 5 secs of frames
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		frame := audio.Frame()
+		if frame.Ts() >= 5 {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}, nil))
+	assert.NoError(writer.Close())
+
+	// Read the chapters back
+	r, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	chapters := r.Chapters()
+	if assert.Len(chapters, 2) {
+		assert.Equal("Chapter 1", chapters[0].Title())
+		assert.Equal(time.Duration(0), chapters[0].Start())
+		assert.Equal(2*time.Second, chapters[0].End())
+		assert.Equal("Chapter 2", chapters[1].Title())
+		assert.Equal(2*time.Second, chapters[1].Start())
+		assert.Equal(5*time.Second, chapters[1].End())
+	}
+	t.Log(chapters)
+}
+
+func Test_writer_008(t *testing.T) {
+	assert := assert.New(t)
+
+	// Open the input
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Write to a file
+	w, err := os.CreateTemp("", t.Name()+"_*.mkv")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer w.Close()
+
+	// Tag the audio stream
+	audio := r.BestStream(media.AUDIO)
+	writer, err := ffmpeg.Create(w.Name(),
+		ffmpeg.OptStreamCopy(1, r.Par(audio)),
+		ffmpeg.OptStreamLanguage(1, "fra"),
+		ffmpeg.OptStreamTitle(1, "Français"),
+		ffmpeg.OptStreamDisposition(1, ffmpeg.DISPOSITION_DEFAULT|ffmpeg.DISPOSITION_HEARING_IMPAIRED),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(writer.Close())
+
+	// Read the stream tags back
+	r2, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+
+	assert.Equal("fra", r2.Language(0))
+	if title := r2.StreamMetadata(0, ffmpeg.MetaTitle); assert.Len(title, 1) {
+		assert.Equal("Français", title[0].Value())
+	}
+	assert.True(r2.Disposition(0).Is(ffmpeg.DISPOSITION_DEFAULT | ffmpeg.DISPOSITION_HEARING_IMPAIRED))
+	assert.False(r2.Disposition(0).Is(ffmpeg.DISPOSITION_FORCED))
+
+	// Invalid language code
+	_, err = ffmpeg.Create(w.Name(), ffmpeg.OptStreamLanguage(1, "french"))
+	assert.Error(err)
+}
+
+func Test_writer_009(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write to a buffer, which cannot seek
+	var buf bytes.Buffer
+	w := struct{ io.Writer }{&buf}
+
+	// Create a fragmented MP4 writer with an audio stream
+	var fragments int
+	writer, err := ffmpeg.NewWriter(w,
+		ffmpeg.OptOutputFormat("mp4"),
+		ffmpeg.OptOutputOpt("frag_duration=500000"),
+		ffmpeg.OptFragment(func(offset int64, ts time.Duration, keyframe bool) error {
+			t.Log("Fragment", offset, ts, keyframe)
+			fragments++
+			return nil
+		}),
+		ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Make an audio generator
+	audio, err := generator.NewSine(440, -5, writer.Stream(1).Par())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer audio.Close()
+
+	// Write 5 secs of frames
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		frame := audio.Frame()
+		if frame.Ts() >= 5 {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}, nil))
+	assert.NoError(writer.Close())
+
+	// Check the output is fragmented
+	assert.Greater(fragments, 1)
+	assert.True(bytes.Contains(buf.Bytes(), []byte("moof")))
+
+	// Invalid output option
+	_, err = ffmpeg.NewWriter(w, ffmpeg.OptOutputFormat("mp4"), ffmpeg.OptOutputOpt("nonexistent=1"), ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)))
+	assert.Error(err)
+}
+
+func Test_writer_010(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a file
+	r, err := ffmpeg.Open("../../etc/test/sample.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Write to an MPEG-TS file
+	w, err := os.CreateTemp("", t.Name()+"_*.ts")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+
+	// Copy the video stream, converting to Annex B
+	video := r.BestStream(media.VIDEO)
+	writer, err := ffmpeg.Create(w.Name(),
+		ffmpeg.OptStreamCopy(1, r.Par(video)),
+		ffmpeg.OptBitstreamFilter(1, "h264_mp4toannexb"),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.NoError(r.Demux(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if stream == video {
+			return par, nil
+		}
+		return nil, nil
+	}, func(stream int, packet *ffmpeg.Packet) error {
+		return writer.WritePacket(1, packet)
+	}))
+	if !assert.NoError(writer.Close()) {
+		t.FailNow()
+	}
+
+	// Read the output back
+	r2, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+	assert.Equal(r.Par(video).CodecID(), r2.Par(r2.BestStream(media.VIDEO)).CodecID())
+
+	// A bitstream filter requires a copied stream
+	_, err = ffmpeg.NewWriter(new(bytes.Buffer), ffmpeg.OptOutputFormat("mpegts"),
+		ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)),
+		ffmpeg.OptBitstreamFilter(1, "null"),
+	)
+	assert.Error(err)
+}
+
+func Test_writer_011(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write to an MP4 file
+	w, err := os.CreateTemp("", t.Name()+"_*.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+
+	// Create a writer with an audio stream and a subtitle stream
+	writer, err := ffmpeg.Create(w.Name(),
+		ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)),
+		ffmpeg.OptStream(2, ffmpeg.SubtitlePar()),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Write the subtitles, which are interleaved with the audio
+	assert.NoError(writer.WriteSubtitle(2, ffmpeg.NewSubtitle(time.Second, 2*time.Second, "Hello")))
+	assert.NoError(writer.WriteSubtitle(2, ffmpeg.NewSubtitle(3*time.Second, 4*time.Second, "Line one\nLine two")))
+	assert.Error(writer.WriteSubtitle(1, ffmpeg.NewSubtitle(0, time.Second, "Not a subtitle stream")))
+
+	// Write 5 secs of audio
+	audio, err := generator.NewSine(440, -5, writer.Stream(1).Par())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer audio.Close()
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		frame := audio.Frame()
+		if frame.Ts() >= 5 {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}, nil))
+	if !assert.NoError(writer.Close()) {
+		t.FailNow()
+	}
+
+	// Read the subtitles back
+	r, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	var subtitles []*ffmpeg.Subtitle
+	assert.NoError(r.Decode(context.Background(), func(stream int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+		if par.Type() == media.SUBTITLE {
+			return par, nil
+		}
+		return nil, nil
+	}, nil, func(stream int, subtitle *ffmpeg.Subtitle) error {
+		t.Log(subtitle)
+		subtitles = append(subtitles, subtitle)
+		return nil
+	}))
+	if assert.Len(subtitles, 2) {
+		assert.Equal(time.Second, subtitles[0].Start())
+		assert.Equal(2*time.Second, subtitles[0].End())
+		assert.Equal("Hello", subtitles[0].Text())
+		assert.Equal(3*time.Second, subtitles[1].Start())
+		assert.Equal("Line one\nLine two", subtitles[1].Text())
+	}
+}
+
+func Test_writer_012(t *testing.T) {
+	assert := assert.New(t)
+
+	// Read a subtitle file
+	r, err := ffmpeg.Open("../../etc/test/sample.srt")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+
+	// Transcode the subtitles to Matroska
+	w, err := os.CreateTemp("", t.Name()+"_*.mkv")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+	if !assert.NoError(r.TranscodeFile(context.Background(), w.Name(), nil)) {
+		t.FailNow()
+	}
+
+	// Read the subtitles back
+	r2, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r2.Close()
+
+	var subtitles []*ffmpeg.Subtitle
+	assert.NoError(r2.Decode(context.Background(), nil, nil, func(stream int, subtitle *ffmpeg.Subtitle) error {
+		subtitles = append(subtitles, subtitle)
+		return nil
+	}))
+	if assert.Len(subtitles, 3) {
+		assert.Equal(time.Second, subtitles[0].Start())
+		assert.Equal(3500*time.Millisecond, subtitles[0].End())
+		assert.Equal("Goodbye.", subtitles[2].Text())
+	}
+}
+
+func Test_writer_013(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write to an MP4 file
+	w, err := os.CreateTemp("", t.Name()+"_*.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+
+	// Choose the encoder and rate control
+	par := ffmpeg.AudioPar("fltp", "stereo", 44100)
+	if !assert.NoError(par.SetEncoder("aac")) {
+		t.FailNow()
+	}
+	if !assert.NoError(par.SetRateControl(ffmpeg.RateControl{Profile: "LC", BitRate: 64000})) {
+		t.FailNow()
+	}
+	writer, err := ffmpeg.Create(w.Name(), ffmpeg.OptStream(1, par))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Write 1 sec of audio
+	audio, err := generator.NewSine(440, -5, writer.Stream(1).Par())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer audio.Close()
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		frame := audio.Frame()
+		if frame.Ts() >= 1 {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}, nil))
+	if !assert.NoError(writer.Close()) {
+		t.FailNow()
+	}
+
+	// Read back the codec
+	r, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+	assert.Equal(par.CodecID(), r.Par(r.BestStream(media.AUDIO)).CodecID())
+
+	// Unsupported profile and rate control settings
+	par.SetRateControl(ffmpeg.RateControl{Profile: "not-a-profile"})
+	_, err = ffmpeg.NewWriter(new(bytes.Buffer), ffmpeg.OptOutputFormat("adts"), ffmpeg.OptStream(1, par))
+	assert.Error(err)
+	par.SetRateControl(ffmpeg.RateControl{CRF: 23})
+	_, err = ffmpeg.NewWriter(new(bytes.Buffer), ffmpeg.OptOutputFormat("adts"), ffmpeg.OptStream(1, par))
+	assert.Error(err)
+
+	// Codec not supported by the output format
+	par = ffmpeg.AudioPar("fltp", "mono", 22050)
+	if assert.NoError(par.SetEncoder("wmav2")) {
+		_, err = ffmpeg.NewWriter(new(bytes.Buffer), ffmpeg.OptOutputFormat("mp4"), ffmpeg.OptStream(1, par))
+		assert.Error(err)
+	}
+}
+
+func Test_writer_014(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write to an MP4 file
+	w, err := os.CreateTemp("", t.Name()+"_*.mp4")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer os.Remove(w.Name())
+	defer w.Close()
+
+	// AAC requires 1024 samples in each frame
+	par := ffmpeg.AudioPar("fltp", "mono", 22050)
+	writer, err := ffmpeg.Create(w.Name(), ffmpeg.OptStream(1, par))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Encode 2 secs of audio in frames of 100 samples
+	frame, err := ffmpeg.NewFrame(par)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer frame.Close()
+	samples, packets := 0, 0
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		if samples >= 2*22050 {
+			return nil, io.EOF
+		}
+		if err := frame.SetFloat32(0, make([]float32, 100)); err != nil {
+			return nil, err
+		}
+		frame.SetPts(int64(samples))
+		samples += frame.NumSamples()
+		return frame, nil
+	}, func(packet *ffmpeg.Packet) error {
+		if packet != nil {
+			packets++
+		}
+		return writer.Write(packet)
+	}))
+	if !assert.NoError(writer.Close()) {
+		t.FailNow()
+	}
+	assert.GreaterOrEqual(packets, samples/1024)
+
+	// Read back the duration
+	r, err := ffmpeg.Open(w.Name())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer r.Close()
+	assert.InDelta(2.0, r.Duration().Seconds(), 0.2)
+}
+
+func Test_writer_015(t *testing.T) {
+	assert := assert.New(t)
+
+	// Write a file with two chapters
+	src, err := os.CreateTemp("", t.Name()+"_*.mp3")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer src.Close()
+
+	writer, err := ffmpeg.Create(src.Name(),
+		ffmpeg.OptStream(1, ffmpeg.AudioPar("fltp", "mono", 22050)),
+		ffmpeg.OptChapter(0, 2*time.Second, ffmpeg.NewMetadata(ffmpeg.MetaTitle, "Chapter 1")),
+		ffmpeg.OptChapter(2*time.Second, 5*time.Second, ffmpeg.NewMetadata(ffmpeg.MetaTitle, "Chapter 2")),
+	)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	audio, err := generator.NewSine(440, -5, writer.Stream(1).Par())
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer audio.Close()
+	assert.NoError(writer.Encode(context.Background(), func(stream int) (*ffmpeg.Frame, error) {
+		frame := audio.Frame()
+		if frame.Ts() >= 5 {
+			return nil, io.EOF
+		}
+		return frame, nil
+	}, nil))
+	assert.NoError(writer.Close())
+
+	// Remux with stream copy, without and with the chapters opt-out
+	for _, nochapters := range []bool{false, true} {
+		r, err := ffmpeg.Open(src.Name())
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		defer r.Close()
+
+		w, err := os.CreateTemp("", t.Name()+"_*.mkv")
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		defer w.Close()
+
+		stream := r.BestStream(media.AUDIO)
+		opts := []ffmpeg.Opt{ffmpeg.OptStreamCopy(1, r.Par(stream)), ffmpeg.OptInputChapters(r.Chapters()...)}
+		if nochapters {
+			opts = append(opts, ffmpeg.OptNoChapters())
+		}
+		writer, err := ffmpeg.Create(w.Name(), opts...)
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		assert.NoError(r.Demux(context.Background(), func(s int, par *ffmpeg.Par) (*ffmpeg.Par, error) {
+			if s == stream {
+				return par, nil
+			}
+			return nil, nil
+		}, func(_ int, packet *ffmpeg.Packet) error {
+			return writer.WritePacket(1, packet)
+		}))
+		assert.NoError(writer.Close())
+
+		// Read the chapters back
+		r2, err := ffmpeg.Open(w.Name())
+		if !assert.NoError(err) {
+			t.FailNow()
+		}
+		defer r2.Close()
+
+		chapters := r2.Chapters()
+		if nochapters {
+			assert.Empty(chapters)
+		} else if assert.Len(chapters, 2) {
+			assert.Equal("Chapter 1", chapters[0].Title())
+			assert.Equal(2*time.Second, chapters[0].End())
+			assert.Equal("Chapter 2", chapters[1].Title())
+			assert.Equal(5*time.Second, chapters[1].End())
+		}
+	}
+}
@@ -13,6 +13,8 @@ import (
 #cgo pkg-config: libavcodec libavutil
 #include <libavcodec/avcodec.h>
 #include <libavutil/opt.h>
+#include <libavutil/mem.h>
+#include <stdlib.h>
 */
 import "C"
 
@@ -31,6 +33,8 @@ type (
 	AVCodecParameters             C.AVCodecParameters
 	AVCodecParser                 C.AVCodecParser
 	AVCodecParserContext          C.AVCodecParserContext
+	AVDiscard                     C.enum_AVDiscard
+	AVPacketFlag                  C.int
 	AVProfile                     C.AVProfile
 )
 
@@ -69,11 +73,22 @@ type jsonAVCodecContext struct {
 // CONSTANTS
 
 const (
-	AV_CODEC_ID_NONE       AVCodecID = C.AV_CODEC_ID_NONE
-	AV_CODEC_ID_MP2        AVCodecID = C.AV_CODEC_ID_MP2
-	AV_CODEC_ID_H264       AVCodecID = C.AV_CODEC_ID_H264
-	AV_CODEC_ID_MPEG1VIDEO AVCodecID = C.AV_CODEC_ID_MPEG1VIDEO
-	AV_CODEC_ID_MPEG2VIDEO AVCodecID = C.AV_CODEC_ID_MPEG2VIDEO
+	AV_CODEC_ID_NONE              AVCodecID = C.AV_CODEC_ID_NONE
+	AV_CODEC_ID_MP2               AVCodecID = C.AV_CODEC_ID_MP2
+	AV_CODEC_ID_H264              AVCodecID = C.AV_CODEC_ID_H264
+	AV_CODEC_ID_MPEG1VIDEO        AVCodecID = C.AV_CODEC_ID_MPEG1VIDEO
+	AV_CODEC_ID_MPEG2VIDEO        AVCodecID = C.AV_CODEC_ID_MPEG2VIDEO
+	AV_CODEC_ID_MJPEG             AVCodecID = C.AV_CODEC_ID_MJPEG
+	AV_CODEC_ID_PNG               AVCodecID = C.AV_CODEC_ID_PNG
+	AV_CODEC_ID_BMP               AVCodecID = C.AV_CODEC_ID_BMP
+	AV_CODEC_ID_GIF               AVCodecID = C.AV_CODEC_ID_GIF
+	AV_CODEC_ID_WEBP              AVCodecID = C.AV_CODEC_ID_WEBP
+	AV_CODEC_ID_SUBRIP            AVCodecID = C.AV_CODEC_ID_SUBRIP
+	AV_CODEC_ID_ASS               AVCodecID = C.AV_CODEC_ID_ASS
+	AV_CODEC_ID_MOV_TEXT          AVCodecID = C.AV_CODEC_ID_MOV_TEXT
+	AV_CODEC_ID_WEBVTT            AVCodecID = C.AV_CODEC_ID_WEBVTT
+	AV_CODEC_ID_DVB_SUBTITLE      AVCodecID = C.AV_CODEC_ID_DVB_SUBTITLE
+	AV_CODEC_ID_HDMV_PGS_SUBTITLE AVCodecID = C.AV_CODEC_ID_HDMV_PGS_SUBTITLE
 )
 
 /**
@@ -87,6 +102,11 @@ const (
 	AV_INPUT_BUFFER_PADDING_SIZE int = C.AV_INPUT_BUFFER_PADDING_SIZE
 )
 
+// Profile is not set or unknown
+const (
+	FF_PROFILE_UNKNOWN int = C.FF_PROFILE_UNKNOWN
+)
+
 /**
  * macroblock decision mode
  * - encoding: Set by user.
@@ -98,6 +118,19 @@ const (
 	FF_MB_DECISION_RD     AVCodecMacroblockDecisionMode = C.FF_MB_DECISION_RD     ///< rate distortion
 )
 
+/**
+ * frames or packets to discard when decoding
+ */
+const (
+	AVDISCARD_NONE     AVDiscard = C.AVDISCARD_NONE     ///< discard nothing
+	AVDISCARD_DEFAULT  AVDiscard = C.AVDISCARD_DEFAULT  ///< discard useless packets like 0 size packets in avi
+	AVDISCARD_NONREF   AVDiscard = C.AVDISCARD_NONREF   ///< discard all non reference
+	AVDISCARD_BIDIR    AVDiscard = C.AVDISCARD_BIDIR    ///< discard all bidirectional frames
+	AVDISCARD_NONINTRA AVDiscard = C.AVDISCARD_NONINTRA ///< discard all non intra frames
+	AVDISCARD_NONKEY   AVDiscard = C.AVDISCARD_NONKEY   ///< discard all frames except keyframes
+	AVDISCARD_ALL      AVDiscard = C.AVDISCARD_ALL      ///< discard all
+)
+
 const (
 	AV_CODEC_FLAG_UNALIGNED      AVCodecFlag  = C.AV_CODEC_FLAG_UNALIGNED      // Allow decoders to produce frames with data planes that are not aligned to CPU requirements
 	AV_CODEC_FLAG_QSCALE         AVCodecFlag  = C.AV_CODEC_FLAG_QSCALE         // Use fixed qscale
@@ -131,6 +164,16 @@ const (
 	AV_CODEC_FLAG2_ICC_PROFILES  AVCodecFlag2 = C.AV_CODEC_FLAG2_ICC_PROFILES  // Generate/parse ICC profiles on encode/decode, as appropriate for the type of file
 )
 
+const (
+	AV_PKT_FLAG_NONE       AVPacketFlag = 0
+	AV_PKT_FLAG_KEY        AVPacketFlag = C.AV_PKT_FLAG_KEY        // The packet contains a keyframe
+	AV_PKT_FLAG_CORRUPT    AVPacketFlag = C.AV_PKT_FLAG_CORRUPT    // The packet content is corrupted
+	AV_PKT_FLAG_DISCARD    AVPacketFlag = C.AV_PKT_FLAG_DISCARD    // The packet is required to maintain valid decoder state but is not required for output
+	AV_PKT_FLAG_TRUSTED    AVPacketFlag = C.AV_PKT_FLAG_TRUSTED    // The packet comes from a trusted source
+	AV_PKT_FLAG_DISPOSABLE AVPacketFlag = C.AV_PKT_FLAG_DISPOSABLE // The packet contains frames that can be discarded by the decoder
+	AV_PKT_FLAG_MAX                     = AV_PKT_FLAG_DISPOSABLE
+)
+
 const (
 	AV_CODEC_CAP_NONE                     AVCodecCap = 0
 	AV_CODEC_CAP_DRAW_HORIZ_BAND          AVCodecCap = C.AV_CODEC_CAP_DRAW_HORIZ_BAND          // Decoder can use draw_horiz_band callback
@@ -434,6 +477,16 @@ func (ctx *AVCodecContext) SetTimeBase(time_base AVRational) {
 	ctx.time_base = C.struct_AVRational(time_base)
 }
 
+// Timebase in which pkt_dts/pts and AVPacket.dts/pts are expressed, when decoding.
+func (ctx *AVCodecContext) PktTimeBase() AVRational {
+	return (AVRational)(ctx.pkt_timebase)
+}
+
+// Timebase in which pkt_dts/pts and AVPacket.dts/pts are expressed, when decoding.
+func (ctx *AVCodecContext) SetPktTimeBase(time_base AVRational) {
+	ctx.pkt_timebase = C.struct_AVRational(time_base)
+}
+
 // Audio sample format.
 func (ctx *AVCodecContext) SampleFormat() AVSampleFormat {
 	return AVSampleFormat(ctx.sample_fmt)
@@ -528,6 +581,16 @@ func (ctx *AVCodecContext) MbDecision() AVCodecMacroblockDecisionMode {
 	return AVCodecMacroblockDecisionMode(ctx.mb_decision)
 }
 
+// Get which frames are skipped when decoding.
+func (ctx *AVCodecContext) SkipFrame() AVDiscard {
+	return AVDiscard(ctx.skip_frame)
+}
+
+// Set which frames are skipped when decoding.
+func (ctx *AVCodecContext) SetSkipFrame(discard AVDiscard) {
+	ctx.skip_frame = C.enum_AVDiscard(discard)
+}
+
 // Get flags
 func (ctx *AVCodecContext) Flags() AVCodecFlag {
 	return AVCodecFlag(ctx.flags)
@@ -549,6 +612,60 @@ func (ctx *AVCodecContext) SetFlags2(flags2 AVCodecFlag2) {
 	ctx.flags2 = C.int(flags2)
 }
 
+// Codec profile, or FF_PROFILE_UNKNOWN.
+func (ctx *AVCodecContext) Profile() int {
+	return int(ctx.profile)
+}
+
+// Set codec profile.
+func (ctx *AVCodecContext) SetProfile(profile int) {
+	ctx.profile = C.int(profile)
+}
+
+// Maximum bitrate.
+func (ctx *AVCodecContext) RcMaxRate() int64 {
+	return int64(ctx.rc_max_rate)
+}
+
+// Set maximum bitrate.
+func (ctx *AVCodecContext) SetRcMaxRate(rate int64) {
+	ctx.rc_max_rate = C.int64_t(rate)
+}
+
+// Decoder bitstream buffer size.
+func (ctx *AVCodecContext) RcBufferSize() int {
+	return int(ctx.rc_buffer_size)
+}
+
+// Set decoder bitstream buffer size.
+func (ctx *AVCodecContext) SetRcBufferSize(size int) {
+	ctx.rc_buffer_size = C.int(size)
+}
+
+// Return the first pass statistics, when encoding with AV_CODEC_FLAG_PASS1.
+func (ctx *AVCodecContext) StatsOut() string {
+	return C.GoString(ctx.stats_out)
+}
+
+// Return the statistics for the second pass, when encoding with AV_CODEC_FLAG_PASS2.
+func (ctx *AVCodecContext) StatsIn() string {
+	return C.GoString(ctx.stats_in)
+}
+
+// Set the statistics for the second pass, which should be set before the
+// codec is opened. The statistics are owned by the caller, so should be
+// released by setting an empty string before the context is freed.
+func (ctx *AVCodecContext) SetStatsIn(stats string) {
+	C.av_free(unsafe.Pointer(ctx.stats_in))
+	if stats == "" {
+		ctx.stats_in = nil
+	} else {
+		cStats := C.CString(stats)
+		defer C.free(unsafe.Pointer(cStats))
+		ctx.stats_in = C.av_strdup(cStats)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // AVProfile
 
@@ -625,6 +742,45 @@ func (v AVCodecCap) FlagString() string {
 	}
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// AVPacketFlag
+
+func (v AVPacketFlag) Is(flag AVPacketFlag) bool {
+	return v&flag == flag
+}
+
+func (v AVPacketFlag) String() string {
+	if v == AV_PKT_FLAG_NONE {
+		return v.FlagString()
+	}
+	str := ""
+	for i := AVPacketFlag(C.int(1)); i <= AV_PKT_FLAG_MAX; i <<= 1 {
+		if v&i == i {
+			str += "|" + i.FlagString()
+		}
+	}
+	return str[1:]
+}
+
+func (v AVPacketFlag) FlagString() string {
+	switch v {
+	case AV_PKT_FLAG_NONE:
+		return "AV_PKT_FLAG_NONE"
+	case AV_PKT_FLAG_KEY:
+		return "AV_PKT_FLAG_KEY"
+	case AV_PKT_FLAG_CORRUPT:
+		return "AV_PKT_FLAG_CORRUPT"
+	case AV_PKT_FLAG_DISCARD:
+		return "AV_PKT_FLAG_DISCARD"
+	case AV_PKT_FLAG_TRUSTED:
+		return "AV_PKT_FLAG_TRUSTED"
+	case AV_PKT_FLAG_DISPOSABLE:
+		return "AV_PKT_FLAG_DISPOSABLE"
+	default:
+		return fmt.Sprintf("AVPacketFlag(0x%08X)", uint32(v))
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // AVCodecID
 
@@ -639,3 +795,21 @@ func (v AVCodecID) Name() string {
 func (v AVCodecID) Type() AVMediaType {
 	return AVMediaType(C.avcodec_get_type(C.enum_AVCodecID(v)))
 }
+
+// Return the long name of the codec, or an empty string if unknown
+func (v AVCodecID) LongName() string {
+	if desc := C.avcodec_descriptor_get(C.enum_AVCodecID(v)); desc == nil {
+		return ""
+	} else {
+		return C.GoString(desc.long_name)
+	}
+}
+
+// Return the name of a profile for the codec, or an empty string if unknown
+func AVCodec_profile_name(codec_id AVCodecID, profile int) string {
+	if name := C.avcodec_profile_name(C.enum_AVCodecID(codec_id), C.int(profile)); name == nil {
+		return ""
+	} else {
+		return C.GoString(name)
+	}
+}
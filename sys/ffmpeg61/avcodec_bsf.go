@@ -0,0 +1,212 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"io"
+	"syscall"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavcodec
+#include <libavcodec/bsf.h>
+#include <stdlib.h>
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	AVBitStreamFilter C.AVBitStreamFilter
+	AVBSFContext      C.AVBSFContext
+)
+
+type jsonAVBitStreamFilter struct {
+	Name     string      `json:"name"`
+	CodecIDs []AVCodecID `json:"codec_ids,omitempty"`
+}
+
+type jsonAVBSFContext struct {
+	Filter      *AVBitStreamFilter `json:"filter,omitempty"`
+	ParIn       *AVCodecParameters `json:"par_in,omitempty"`
+	ParOut      *AVCodecParameters `json:"par_out,omitempty"`
+	TimeBaseIn  AVRational         `json:"time_base_in,omitempty"`
+	TimeBaseOut AVRational         `json:"time_base_out,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (ctx *AVBitStreamFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVBitStreamFilter{
+		Name:     ctx.Name(),
+		CodecIDs: ctx.CodecIDs(),
+	})
+}
+
+func (ctx *AVBSFContext) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVBSFContext{
+		Filter:      ctx.Filter(),
+		ParIn:       ctx.ParIn(),
+		ParOut:      ctx.ParOut(),
+		TimeBaseIn:  ctx.TimeBaseIn(),
+		TimeBaseOut: ctx.TimeBaseOut(),
+	})
+}
+
+func (ctx *AVBitStreamFilter) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+func (ctx *AVBSFContext) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - FILTER
+
+func (ctx *AVBitStreamFilter) Name() string {
+	return C.GoString(ctx.name)
+}
+
+// Return the codec ids supported by the filter, or nil if the filter
+// supports any codec
+func (ctx *AVBitStreamFilter) CodecIDs() []AVCodecID {
+	var result []AVCodecID
+	if ctx.codec_ids == nil {
+		return nil
+	}
+	for ptr := uintptr(unsafe.Pointer(ctx.codec_ids)); ; ptr += unsafe.Sizeof(C.enum_AVCodecID(0)) {
+		id := *(*C.enum_AVCodecID)(unsafe.Pointer(ptr))
+		if id == C.AV_CODEC_ID_NONE {
+			break
+		}
+		result = append(result, AVCodecID(id))
+	}
+	return result
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - CONTEXT
+
+func (ctx *AVBSFContext) Filter() *AVBitStreamFilter {
+	return (*AVBitStreamFilter)(ctx.filter)
+}
+
+// Return the parameters of the input stream, which are set by the caller
+// before initializing the filter
+func (ctx *AVBSFContext) ParIn() *AVCodecParameters {
+	return (*AVCodecParameters)(ctx.par_in)
+}
+
+// Return the parameters of the output stream, which are set by the filter
+// when it is initialized
+func (ctx *AVBSFContext) ParOut() *AVCodecParameters {
+	return (*AVCodecParameters)(ctx.par_out)
+}
+
+func (ctx *AVBSFContext) TimeBaseIn() AVRational {
+	return AVRational(ctx.time_base_in)
+}
+
+func (ctx *AVBSFContext) SetTimeBaseIn(tb AVRational) {
+	ctx.time_base_in = C.AVRational(tb)
+}
+
+func (ctx *AVBSFContext) TimeBaseOut() AVRational {
+	return AVRational(ctx.time_base_out)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Return a bitstream filter with the specified name, or nil if the filter
+// is not found
+func AVCodec_bsf_get_by_name(name string) *AVBitStreamFilter {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return (*AVBitStreamFilter)(C.av_bsf_get_by_name(cName))
+}
+
+// Iterate over all registered bitstream filters
+func AVCodec_bsf_iterate(opaque *uintptr) *AVBitStreamFilter {
+	return (*AVBitStreamFilter)(C.av_bsf_iterate((*unsafe.Pointer)(unsafe.Pointer(opaque))))
+}
+
+// Allocate a context for a given bitstream filter
+func AVCodec_bsf_alloc(filter *AVBitStreamFilter) (*AVBSFContext, error) {
+	var ctx *C.AVBSFContext
+	if err := AVError(C.av_bsf_alloc((*C.AVBitStreamFilter)(filter), &ctx)); err != 0 {
+		return nil, err
+	}
+	return (*AVBSFContext)(ctx), nil
+}
+
+// Parse a string describing a list of bitstream filters, separated by
+// commas, and allocate a context for the list. An empty string returns
+// a context for the null filter, which passes packets unchanged.
+func AVCodec_bsf_list_parse_str(str string) (*AVBSFContext, error) {
+	var ctx *C.AVBSFContext
+	cStr := C.CString(str)
+	defer C.free(unsafe.Pointer(cStr))
+	if err := AVError(C.av_bsf_list_parse_str(cStr, &ctx)); err != 0 {
+		return nil, err
+	}
+	return (*AVBSFContext)(ctx), nil
+}
+
+// Prepare the filter for use, after all the parameters and options have
+// been set
+func AVCodec_bsf_init(ctx *AVBSFContext) error {
+	if err := AVError(C.av_bsf_init((*C.AVBSFContext)(ctx))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Free a bitstream filter context
+func AVCodec_bsf_free(ctx *AVBSFContext) {
+	C.av_bsf_free((**C.AVBSFContext)(unsafe.Pointer(&ctx)))
+}
+
+// Reset the internal bitstream filter state, for example after seeking
+func AVCodec_bsf_flush(ctx *AVBSFContext) {
+	C.av_bsf_flush((*C.AVBSFContext)(ctx))
+}
+
+// Submit a packet for filtering. The filter takes ownership of the packet
+// reference. A nil packet signals the end of the stream. Error return of
+// EAGAIN means that packets must be received before more can be sent.
+func AVCodec_bsf_send_packet(ctx *AVBSFContext, pkt *AVPacket) error {
+	if err := AVError(C.av_bsf_send_packet((*C.AVBSFContext)(ctx), (*C.AVPacket)(pkt))); err != 0 {
+		if err == AVERROR_EOF {
+			return io.EOF
+		} else if err.IsErrno(syscall.EAGAIN) {
+			return syscall.EAGAIN
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+// Retrieve a filtered packet. Error return of EAGAIN means that more packets
+// need to be sent, and io.EOF means that there will be no further output.
+func AVCodec_bsf_receive_packet(ctx *AVBSFContext, pkt *AVPacket) error {
+	if err := AVError(C.av_bsf_receive_packet((*C.AVBSFContext)(ctx), (*C.AVPacket)(pkt))); err != 0 {
+		if err == AVERROR_EOF {
+			return io.EOF
+		} else if err.IsErrno(syscall.EAGAIN) {
+			return syscall.EAGAIN
+		} else {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package ffmpeg_test
+
+import (
+	"testing"
+
+	// Packages
+	"github.com/stretchr/testify/assert"
+
+	// Namespace imports
+	. "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+func Test_avcodec_bsf_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Iterate over the bitstream filters
+	var opaque uintptr
+	for {
+		filter := AVCodec_bsf_iterate(&opaque)
+		if filter == nil {
+			break
+		}
+		t.Log(filter)
+	}
+
+	// Get filters by name
+	assert.NotNil(AVCodec_bsf_get_by_name("h264_mp4toannexb"))
+	assert.Nil(AVCodec_bsf_get_by_name("nonexistent"))
+}
+
+func Test_avcodec_bsf_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Allocate a filter
+	ctx, err := AVCodec_bsf_alloc(AVCodec_bsf_get_by_name("null"))
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	defer AVCodec_bsf_free(ctx)
+
+	// Set parameters and initialize
+	ctx.ParIn().SetCodecType(AVMEDIA_TYPE_AUDIO)
+	ctx.ParIn().SetCodecID(AV_CODEC_ID_MP2)
+	ctx.SetTimeBaseIn(AVUtil_rational(1, 44100))
+	if !assert.NoError(AVCodec_bsf_init(ctx)) {
+		t.FailNow()
+	}
+	assert.Equal(AV_CODEC_ID_MP2, ctx.ParOut().CodecID())
+	assert.Equal(AVUtil_rational(1, 44100), ctx.TimeBaseOut())
+	t.Log(ctx)
+}
+
+func Test_avcodec_bsf_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Parse a list of filters
+	ctx, err := AVCodec_bsf_list_parse_str("h264_mp4toannexb,dump_extra")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	AVCodec_bsf_free(ctx)
+
+	_, err = AVCodec_bsf_list_parse_str("nonexistent")
+	assert.Error(err)
+}
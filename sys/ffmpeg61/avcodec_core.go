@@ -64,6 +64,12 @@ func AVCodec_open(ctx *AVCodecContext, codec *AVCodec, options *AVDictionary) er
 	return nil
 }
 
+// Reset the internal codec state and flush internal buffers. Should be called
+// when seeking or switching to a different stream.
+func AVCodec_flush_buffers(ctx *AVCodecContext) {
+	C.avcodec_flush_buffers((*C.struct_AVCodecContext)(ctx))
+}
+
 // Iterate over all registered codecs.
 func AVCodec_iterate(opaque *uintptr) *AVCodec {
 	return (*AVCodec)(C.av_codec_iterate((*unsafe.Pointer)(unsafe.Pointer(opaque))))
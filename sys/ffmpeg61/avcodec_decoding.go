@@ -1,8 +1,11 @@
 package ffmpeg
 
 import (
+	"errors"
 	"io"
+	"sync"
 	"syscall"
+	"unsafe"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -12,9 +15,52 @@ import (
 #cgo pkg-config: libavcodec
 #include <libavcodec/avcodec.h>
 #include <stdlib.h>
+
+extern int avcodec_get_buffer_callback(void* key, AVCodecContext* ctx, AVFrame* frame, int flags);
+
+enum { AVERROR_ENOMEM_ = AVERROR(ENOMEM) };
+
+// Allocate frame buffers with the callback, unless the decoder does not
+// support custom allocators or uses hardware frames. The opaque field is
+// the context which the callback was set on, and is copied to the contexts
+// of decoding threads.
+static int avcodec_get_buffer2_(AVCodecContext* ctx, AVFrame* frame, int flags) {
+	if (!(ctx->codec->capabilities & AV_CODEC_CAP_DR1) || ctx->hw_frames_ctx) {
+		return avcodec_default_get_buffer2(ctx, frame, flags);
+	}
+	return avcodec_get_buffer_callback(ctx->opaque, ctx, frame, flags);
+}
+
+static void avcodec_set_get_buffer2_(AVCodecContext* ctx, int callback) {
+	ctx->get_buffer2 = callback ? avcodec_get_buffer2_ : avcodec_default_get_buffer2;
+	ctx->opaque = callback ? ctx : NULL;
+}
+
+static int avcodec_align_dimensions_(AVCodecContext* ctx, int* width, int* height) {
+	int align[AV_NUM_DATA_POINTERS];
+	int result = 1;
+	avcodec_align_dimensions2(ctx, width, height, align);
+	for (int i = 0; i < AV_NUM_DATA_POINTERS; i++) {
+		result = FFMAX(result, align[i]);
+	}
+	return result;
+}
 */
 import "C"
 
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+// AVCodecGetBufferFunc allocates reference-counted buffers for a frame
+// decoded by a codec context. The format, width and height (for video) or
+// format, nb_samples and ch_layout (for audio) are set on the frame.
+type AVCodecGetBufferFunc func(ctx *AVCodecContext, frame *AVFrame) error
+
+var (
+	getBuffers   = make(map[uintptr]AVCodecGetBufferFunc)
+	getBuffersMu sync.RWMutex
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
@@ -53,3 +99,50 @@ func AVCodec_send_packet(ctx *AVCodecContext, pkt *AVPacket) error {
 	}
 	return nil
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// FRAME BUFFERS
+
+// Set a function which allocates the buffers for decoded frames, or nil to
+// use the default allocator. This should be called before the codec is
+// opened, and with nil before the codec context is freed. Decoders which do
+// not support custom allocators, or decode to hardware frames, use the
+// default allocator. The function may be called from decoding threads.
+func AVCodec_set_get_buffer(ctx *AVCodecContext, fn AVCodecGetBufferFunc) {
+	getBuffersMu.Lock()
+	defer getBuffersMu.Unlock()
+	if fn == nil {
+		delete(getBuffers, uintptr(unsafe.Pointer(ctx)))
+	} else {
+		getBuffers[uintptr(unsafe.Pointer(ctx))] = fn
+	}
+	C.avcodec_set_get_buffer2_((*C.AVCodecContext)(ctx), boolToInt(fn != nil))
+}
+
+// Return the width and height of video frame buffers for a codec context,
+// enlarged so that the decoder can read and write beyond the edges of the
+// frame, and the alignment in bytes required for each linesize
+func AVCodec_align_dimensions(ctx *AVCodecContext, width, height int) (int, int, int) {
+	w, h := C.int(width), C.int(height)
+	align := C.avcodec_align_dimensions_((*C.AVCodecContext)(ctx), &w, &h)
+	return int(w), int(h), int(align)
+}
+
+//export avcodec_get_buffer_callback
+func avcodec_get_buffer_callback(key unsafe.Pointer, ctx *C.AVCodecContext, frame *C.AVFrame, flags C.int) C.int {
+	getBuffersMu.RLock()
+	fn, exists := getBuffers[uintptr(key)]
+	getBuffersMu.RUnlock()
+	if !exists {
+		return C.avcodec_default_get_buffer2(ctx, frame, flags)
+	}
+
+	var averr AVError
+	if err := fn((*AVCodecContext)(ctx), (*AVFrame)(frame)); err == nil {
+		return 0
+	} else if errors.As(err, &averr) {
+		return C.int(averr)
+	} else {
+		return C.AVERROR_ENOMEM_
+	}
+}
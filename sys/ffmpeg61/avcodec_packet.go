@@ -2,6 +2,8 @@ package ffmpeg
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"unsafe"
 )
 
@@ -11,12 +13,33 @@ import (
 /*
 #cgo pkg-config: libavcodec
 #include <libavcodec/avcodec.h>
+#include <string.h>
+
+// Attach a buffer from the pool to a packet, with the padding zeroed. The
+// buffers in the pool must be at least size + AV_INPUT_BUFFER_PADDING_SIZE
+// bytes.
+static int av_packet_get_pool_buffer(AVPacket* pkt, AVBufferPool* pool, int size) {
+	AVBufferRef* buf = av_buffer_pool_get(pool);
+	if (!buf) {
+		return AVERROR(ENOMEM);
+	}
+	memset(buf->data + size, 0, AV_INPUT_BUFFER_PADDING_SIZE);
+	av_packet_unref(pkt);
+	pkt->buf = buf;
+	pkt->data = buf->data;
+	pkt->size = size;
+	return 0;
+}
 */
 import "C"
 
 ////////////////////////////////////////////////////////////////////////////////
 // TYPES
 
+type (
+	AVPacketSideDataType C.enum_AVPacketSideDataType
+)
+
 type jsonAVPacket struct {
 	Pts           int64      `json:"pts,omitempty"`
 	Dts           int64      `json:"dts,omitempty"`
@@ -29,9 +52,58 @@ type jsonAVPacket struct {
 	Pos           int64      `json:"pos,omitempty"`
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	AV_PKT_DATA_PALETTE                    AVPacketSideDataType = C.AV_PKT_DATA_PALETTE                    // Palette with AVPALETTE_SIZE bytes
+	AV_PKT_DATA_NEW_EXTRADATA              AVPacketSideDataType = C.AV_PKT_DATA_NEW_EXTRADATA              // New extradata for the codec
+	AV_PKT_DATA_PARAM_CHANGE               AVPacketSideDataType = C.AV_PKT_DATA_PARAM_CHANGE               // Parameter changes
+	AV_PKT_DATA_H263_MB_INFO               AVPacketSideDataType = C.AV_PKT_DATA_H263_MB_INFO               // H.263 macroblock info
+	AV_PKT_DATA_REPLAYGAIN                 AVPacketSideDataType = C.AV_PKT_DATA_REPLAYGAIN                 // AVReplayGain structure
+	AV_PKT_DATA_DISPLAYMATRIX              AVPacketSideDataType = C.AV_PKT_DATA_DISPLAYMATRIX              // 3x3 display transformation matrix
+	AV_PKT_DATA_STEREO3D                   AVPacketSideDataType = C.AV_PKT_DATA_STEREO3D                   // AVStereo3D structure
+	AV_PKT_DATA_AUDIO_SERVICE_TYPE         AVPacketSideDataType = C.AV_PKT_DATA_AUDIO_SERVICE_TYPE         // AVAudioServiceType enum
+	AV_PKT_DATA_QUALITY_STATS              AVPacketSideDataType = C.AV_PKT_DATA_QUALITY_STATS              // Encoder quality statistics
+	AV_PKT_DATA_FALLBACK_TRACK             AVPacketSideDataType = C.AV_PKT_DATA_FALLBACK_TRACK             // Fallback track
+	AV_PKT_DATA_CPB_PROPERTIES             AVPacketSideDataType = C.AV_PKT_DATA_CPB_PROPERTIES             // AVCPBProperties structure
+	AV_PKT_DATA_SKIP_SAMPLES               AVPacketSideDataType = C.AV_PKT_DATA_SKIP_SAMPLES               // Number of samples to skip at the start and end
+	AV_PKT_DATA_JP_DUALMONO                AVPacketSideDataType = C.AV_PKT_DATA_JP_DUALMONO                // Japanese dual mono mode
+	AV_PKT_DATA_STRINGS_METADATA           AVPacketSideDataType = C.AV_PKT_DATA_STRINGS_METADATA           // Metadata as key/value strings
+	AV_PKT_DATA_SUBTITLE_POSITION          AVPacketSideDataType = C.AV_PKT_DATA_SUBTITLE_POSITION          // Subtitle position
+	AV_PKT_DATA_MATROSKA_BLOCKADDITIONAL   AVPacketSideDataType = C.AV_PKT_DATA_MATROSKA_BLOCKADDITIONAL   // Matroska BlockAdditional data
+	AV_PKT_DATA_WEBVTT_IDENTIFIER          AVPacketSideDataType = C.AV_PKT_DATA_WEBVTT_IDENTIFIER          // WebVTT cue identifier
+	AV_PKT_DATA_WEBVTT_SETTINGS            AVPacketSideDataType = C.AV_PKT_DATA_WEBVTT_SETTINGS            // WebVTT cue settings
+	AV_PKT_DATA_METADATA_UPDATE            AVPacketSideDataType = C.AV_PKT_DATA_METADATA_UPDATE            // Metadata updates as key/value strings
+	AV_PKT_DATA_MPEGTS_STREAM_ID           AVPacketSideDataType = C.AV_PKT_DATA_MPEGTS_STREAM_ID           // MPEG-TS stream ID
+	AV_PKT_DATA_MASTERING_DISPLAY_METADATA AVPacketSideDataType = C.AV_PKT_DATA_MASTERING_DISPLAY_METADATA // Mastering display metadata
+	AV_PKT_DATA_SPHERICAL                  AVPacketSideDataType = C.AV_PKT_DATA_SPHERICAL                  // Spherical video mapping
+	AV_PKT_DATA_CONTENT_LIGHT_LEVEL        AVPacketSideDataType = C.AV_PKT_DATA_CONTENT_LIGHT_LEVEL        // Content light level
+	AV_PKT_DATA_A53_CC                     AVPacketSideDataType = C.AV_PKT_DATA_A53_CC                     // ATSC A53 closed captions
+	AV_PKT_DATA_ENCRYPTION_INIT_INFO       AVPacketSideDataType = C.AV_PKT_DATA_ENCRYPTION_INIT_INFO       // Encryption initialization data
+	AV_PKT_DATA_ENCRYPTION_INFO            AVPacketSideDataType = C.AV_PKT_DATA_ENCRYPTION_INFO            // Encryption info
+	AV_PKT_DATA_AFD                        AVPacketSideDataType = C.AV_PKT_DATA_AFD                        // Active format description
+	AV_PKT_DATA_PRFT                       AVPacketSideDataType = C.AV_PKT_DATA_PRFT                       // Producer reference time
+	AV_PKT_DATA_ICC_PROFILE                AVPacketSideDataType = C.AV_PKT_DATA_ICC_PROFILE                // ICC profile
+	AV_PKT_DATA_DOVI_CONF                  AVPacketSideDataType = C.AV_PKT_DATA_DOVI_CONF                  // Dolby Vision configuration
+	AV_PKT_DATA_S12M_TIMECODE              AVPacketSideDataType = C.AV_PKT_DATA_S12M_TIMECODE              // SMPTE ST 12-1 timecodes
+	AV_PKT_DATA_DYNAMIC_HDR10_PLUS         AVPacketSideDataType = C.AV_PKT_DATA_DYNAMIC_HDR10_PLUS         // HDR10+ dynamic metadata
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
+func (v AVPacketSideDataType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+func (v AVPacketSideDataType) String() string {
+	if name := C.av_packet_side_data_name(C.enum_AVPacketSideDataType(v)); name != nil {
+		return C.GoString(name)
+	}
+	return fmt.Sprintf("AVPacketSideDataType(%d)", int(v))
+}
+
 func (ctx *AVPacket) MarshalJSON() ([]byte, error) {
 	return json.Marshal(jsonAVPacket{
 		Pts:           int64(ctx.pts),
@@ -81,6 +153,24 @@ func AVCodec_new_packet(pkt *AVPacket, size int) error {
 	}
 }
 
+// Allocate the payload of a packet from a pool. The buffers in the pool must
+// be at least size + AV_INPUT_BUFFER_PADDING_SIZE bytes.
+func AVCodec_packet_get_pool_buffer(pkt *AVPacket, pool *AVBufferPool, size int) error {
+	if err := AVError(C.av_packet_get_pool_buffer((*C.struct_AVPacket)(pkt), (*C.AVBufferPool)(pool), C.int(size))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Allocate the payload of a packet and copy the data into it.
+func AVCodec_packet_from_bytes(pkt *AVPacket, data []byte) error {
+	if err := AVCodec_new_packet(pkt, len(data)); err != nil {
+		return err
+	}
+	copy(cByteSlice(unsafe.Pointer(pkt.data), pkt.size), data)
+	return nil
+}
+
 // Reduce packet size, correctly zeroing padding.
 func AVCodec_shrink_packet(pkt *AVPacket, size int) {
 	C.av_shrink_packet((*C.struct_AVPacket)(pkt), C.int(size))
@@ -105,6 +195,37 @@ func AVCodec_packet_unref(pkt *AVPacket) {
 	C.av_packet_unref((*C.struct_AVPacket)(pkt))
 }
 
+// Setup a new reference to the data described by a given packet, and copy
+// the packet properties.
+func AVCodec_packet_ref(dst, src *AVPacket) error {
+	if err := AVError(C.av_packet_ref((*C.struct_AVPacket)(dst), (*C.struct_AVPacket)(src))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Return the side data of a type, or nil if the packet does not contain the
+// side data. The returned slice is valid until the packet is unreferenced.
+func AVCodec_packet_get_side_data(pkt *AVPacket, t AVPacketSideDataType) []byte {
+	var size C.size_t
+	data := C.av_packet_get_side_data((*C.struct_AVPacket)(pkt), C.enum_AVPacketSideDataType(t), &size)
+	if data == nil {
+		return nil
+	}
+	return cByteSlice(unsafe.Pointer(data), C.int(size))
+}
+
+// Add side data of a type to a packet, replacing any existing side data of
+// the same type.
+func AVCodec_packet_add_side_data(pkt *AVPacket, t AVPacketSideDataType, data []byte) error {
+	ptr := C.av_packet_new_side_data((*C.struct_AVPacket)(pkt), C.enum_AVPacketSideDataType(t), C.size_t(len(data)))
+	if ptr == nil {
+		return errors.New("av_packet_new_side_data failed")
+	}
+	copy(cByteSlice(unsafe.Pointer(ptr), C.int(len(data))), data)
+	return nil
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // AVPacket
 
@@ -132,10 +253,30 @@ func (ctx *AVPacket) Dts() int64 {
 	return int64(ctx.dts)
 }
 
+func (ctx *AVPacket) SetPts(pts int64) {
+	ctx.pts = C.int64_t(pts)
+}
+
+func (ctx *AVPacket) SetDts(dts int64) {
+	ctx.dts = C.int64_t(dts)
+}
+
+func (ctx *AVPacket) Flags() AVPacketFlag {
+	return AVPacketFlag(ctx.flags)
+}
+
+func (ctx *AVPacket) SetFlags(flags AVPacketFlag) {
+	ctx.flags = C.int(flags)
+}
+
 func (ctx *AVPacket) Duration() int64 {
 	return int64(ctx.duration)
 }
 
+func (ctx *AVPacket) SetDuration(duration int64) {
+	ctx.duration = C.int64_t(duration)
+}
+
 func (ctx *AVPacket) Pos() int64 {
 	return int64(ctx.pos)
 }
@@ -151,3 +292,15 @@ func (ctx *AVPacket) Bytes() []byte {
 func (ctx *AVPacket) Size() int {
 	return int(ctx.size)
 }
+
+// Return the types of side data in the packet
+func (ctx *AVPacket) SideDataTypes() []AVPacketSideDataType {
+	if ctx.side_data == nil || ctx.side_data_elems == 0 {
+		return nil
+	}
+	result := make([]AVPacketSideDataType, 0, int(ctx.side_data_elems))
+	for _, sd := range unsafe.Slice(ctx.side_data, int(ctx.side_data_elems)) {
+		result = append(result, AVPacketSideDataType(sd._type))
+	}
+	return result
+}
@@ -26,3 +26,30 @@ func Test_avcodec_packet_000(t *testing.T) {
 	AVCodec_packet_unref(packet)
 	AVCodec_packet_free(packet)
 }
+
+func Test_avcodec_packet_001(t *testing.T) {
+	assert := assert.New(t)
+	packet := AVCodec_packet_alloc()
+	if !assert.NotNil(packet) {
+		t.SkipNow()
+	}
+	defer AVCodec_packet_free(packet)
+
+	assert.Nil(AVCodec_packet_get_side_data(packet, AV_PKT_DATA_SKIP_SAMPLES))
+	assert.Nil(packet.SideDataTypes())
+
+	// Add side data
+	data := []byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	if !assert.NoError(AVCodec_packet_add_side_data(packet, AV_PKT_DATA_SKIP_SAMPLES, data)) {
+		t.FailNow()
+	}
+	assert.Equal(data, AVCodec_packet_get_side_data(packet, AV_PKT_DATA_SKIP_SAMPLES))
+	assert.Equal([]AVPacketSideDataType{AV_PKT_DATA_SKIP_SAMPLES}, packet.SideDataTypes())
+	t.Log(AV_PKT_DATA_SKIP_SAMPLES)
+
+	// Flags
+	packet.SetFlags(AV_PKT_FLAG_KEY | AV_PKT_FLAG_CORRUPT)
+	assert.True(packet.Flags().Is(AV_PKT_FLAG_KEY))
+	assert.False(packet.Flags().Is(AV_PKT_FLAG_DISCARD))
+	assert.Equal("AV_PKT_FLAG_KEY|AV_PKT_FLAG_CORRUPT", packet.Flags().String())
+}
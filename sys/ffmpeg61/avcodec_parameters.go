@@ -3,6 +3,7 @@ package ffmpeg
 import (
 	"encoding/json"
 	"errors"
+	"unsafe"
 )
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -12,6 +13,7 @@ import (
 #cgo pkg-config: libavcodec libavutil
 #include <libavcodec/avcodec.h>
 #include <libavutil/opt.h>
+#include <libavutil/mem.h>
 */
 import "C"
 
@@ -91,6 +93,38 @@ func (ctx *AVCodecParameters) CodecID() AVCodecID {
 	return AVCodecID(ctx.codec_id)
 }
 
+func (ctx *AVCodecParameters) SetCodecID(id AVCodecID) {
+	ctx.codec_id = C.enum_AVCodecID(id)
+}
+
+// Return a copy of the extra binary data needed for initializing the decoder
+func (ctx *AVCodecParameters) ExtraData() []byte {
+	if ctx.extradata == nil || ctx.extradata_size <= 0 {
+		return nil
+	}
+	return C.GoBytes(unsafe.Pointer(ctx.extradata), ctx.extradata_size)
+}
+
+// Set the extra binary data, which replaces any existing extra data. The data
+// is copied and padded with AV_INPUT_BUFFER_PADDING_SIZE zero bytes.
+func (ctx *AVCodecParameters) SetExtraData(data []byte) error {
+	if ctx.extradata != nil {
+		C.av_freep(unsafe.Pointer(&ctx.extradata))
+		ctx.extradata_size = 0
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	ptr := C.av_mallocz(C.size_t(len(data) + AV_INPUT_BUFFER_PADDING_SIZE))
+	if ptr == nil {
+		return errors.New("failed to allocate extradata")
+	}
+	copy(cByteSlice(ptr, C.int(len(data))), data)
+	ctx.extradata = (*C.uint8_t)(ptr)
+	ctx.extradata_size = C.int(len(data))
+	return nil
+}
+
 func (ctx *AVCodecParameters) CodecTag() uint32 {
 	return uint32(ctx.codec_tag)
 }
@@ -99,6 +133,24 @@ func (ctx *AVCodecParameters) SetCodecTag(tag uint32) {
 	ctx.codec_tag = C.uint32_t(tag)
 }
 
+// Audio and Video
+func (ctx *AVCodecParameters) Profile() int {
+	return int(ctx.profile)
+}
+
+func (ctx *AVCodecParameters) SetProfile(profile int) {
+	ctx.profile = C.int(profile)
+}
+
+// Audio and Video
+func (ctx *AVCodecParameters) Level() int {
+	return int(ctx.level)
+}
+
+func (ctx *AVCodecParameters) SetLevel(level int) {
+	ctx.level = C.int(level)
+}
+
 // Audio and Video
 func (ctx *AVCodecParameters) Format() int {
 	return int(ctx.format)
@@ -19,14 +19,24 @@ func AVCodec_parser_iterate(opaque *uintptr) *AVCodecParser {
 	return (*AVCodecParser)(C.av_parser_iterate((*unsafe.Pointer)(unsafe.Pointer(opaque))))
 }
 
+// Initialize a parser for a codec, or return nil if there is no parser.
 func AVCodec_parser_init(codec_id AVCodecID) *AVCodecParserContext {
 	return (*AVCodecParserContext)(C.av_parser_init(C.int(codec_id)))
 }
 
+// Free a parser.
 func AVCodec_parser_close(parser *AVCodecParserContext) {
 	C.av_parser_close((*C.AVCodecParserContext)(parser))
 }
 
+// Parse a packet from the buffer, returning the number of bytes consumed. The
+// packet data is set to point to the parsed frame, or is empty if more data
+// is needed. The buffer should have AV_INPUT_BUFFER_PADDING_SIZE bytes of
+// padding, and an empty buffer flushes the parser at the end of the stream.
 func AVCodec_parser_parse(parser *AVCodecParserContext, ctx *AVCodecContext, packet *AVPacket, buf []byte, pts int64, dts int64, pos int64) int {
-	return int(C.av_parser_parse2((*C.AVCodecParserContext)(parser), (*C.AVCodecContext)(ctx), &packet.data, &packet.size, (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.int(len(buf)), C.int64_t(pts), C.int64_t(dts), C.int64_t(pos)))
+	var data *C.uint8_t
+	if len(buf) > 0 {
+		data = (*C.uint8_t)(unsafe.Pointer(&buf[0]))
+	}
+	return int(C.av_parser_parse2((*C.AVCodecParserContext)(parser), (*C.AVCodecContext)(ctx), &packet.data, &packet.size, data, C.int(len(buf)), C.int64_t(pts), C.int64_t(dts), C.int64_t(pos)))
 }
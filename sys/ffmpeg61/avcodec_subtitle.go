@@ -0,0 +1,325 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"errors"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavcodec libavutil
+#include <libavcodec/avcodec.h>
+#include <libavutil/mem.h>
+#include <stdlib.h>
+
+// Append a text or ASS rectangle to a subtitle, which takes ownership
+static AVSubtitleRect* avsubtitle_add_rect(AVSubtitle* sub, enum AVSubtitleType type, const char* text) {
+	AVSubtitleRect** rects = av_realloc_array(sub->rects, sub->num_rects + 1, sizeof(*rects));
+	if (rects == NULL) {
+		return NULL;
+	}
+	sub->rects = rects;
+	AVSubtitleRect* rect = av_mallocz(sizeof(AVSubtitleRect));
+	if (rect == NULL) {
+		return NULL;
+	}
+	rect->type = type;
+	if (type == SUBTITLE_ASS) {
+		rect->ass = av_strdup(text);
+	} else {
+		rect->text = av_strdup(text);
+	}
+	if (rect->ass == NULL && rect->text == NULL) {
+		av_free(rect);
+		return NULL;
+	}
+	sub->rects[sub->num_rects++] = rect;
+	return rect;
+}
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	AVSubtitle     C.AVSubtitle
+	AVSubtitleRect C.AVSubtitleRect
+	AVSubtitleType C.enum_AVSubtitleType
+)
+
+type jsonAVSubtitle struct {
+	Format           uint16            `json:"format"`
+	StartDisplayTime uint32            `json:"start_display_time"`
+	EndDisplayTime   uint32            `json:"end_display_time"`
+	Pts              AVTimestamp       `json:"pts"`
+	Rects            []*AVSubtitleRect `json:"rects,omitempty"`
+}
+
+type jsonAVSubtitleRect struct {
+	Type      AVSubtitleType `json:"type"`
+	X         int            `json:"x,omitempty"`
+	Y         int            `json:"y,omitempty"`
+	Width     int            `json:"width,omitempty"`
+	Height    int            `json:"height,omitempty"`
+	NumColors int            `json:"num_colors,omitempty"`
+	Text      string         `json:"text,omitempty"`
+	Ass       string         `json:"ass,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	SUBTITLE_NONE   AVSubtitleType = C.SUBTITLE_NONE
+	SUBTITLE_BITMAP AVSubtitleType = C.SUBTITLE_BITMAP // A bitmap, pict will be set
+	SUBTITLE_TEXT   AVSubtitleType = C.SUBTITLE_TEXT   // Plain text, the text field must be set by the decoder
+	SUBTITLE_ASS    AVSubtitleType = C.SUBTITLE_ASS    // Formatted text, the ass field must be set by the decoder
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (ctx *AVSubtitle) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVSubtitle{
+		Format:           ctx.Format(),
+		StartDisplayTime: ctx.StartDisplayTime(),
+		EndDisplayTime:   ctx.EndDisplayTime(),
+		Pts:              AVTimestamp(ctx.pts),
+		Rects:            ctx.Rects(),
+	})
+}
+
+func (ctx *AVSubtitleRect) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVSubtitleRect{
+		Type:      ctx.Type(),
+		X:         ctx.X(),
+		Y:         ctx.Y(),
+		Width:     ctx.Width(),
+		Height:    ctx.Height(),
+		NumColors: ctx.NumColors(),
+		Text:      ctx.Text(),
+		Ass:       ctx.Ass(),
+	})
+}
+
+func (v AVSubtitleType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
+
+func (ctx *AVSubtitle) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+func (ctx *AVSubtitleRect) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+func (v AVSubtitleType) String() string {
+	switch v {
+	case SUBTITLE_NONE:
+		return "SUBTITLE_NONE"
+	case SUBTITLE_BITMAP:
+		return "SUBTITLE_BITMAP"
+	case SUBTITLE_TEXT:
+		return "SUBTITLE_TEXT"
+	case SUBTITLE_ASS:
+		return "SUBTITLE_ASS"
+	default:
+		return "[AVSubtitleType]"
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - SUBTITLE
+
+// Return the format of the subtitle, which is 0 for graphics and 1 for text
+func (ctx *AVSubtitle) Format() uint16 {
+	return uint16(ctx.format)
+}
+
+// Return the display start time, in milliseconds relative to the pts
+func (ctx *AVSubtitle) StartDisplayTime() uint32 {
+	return uint32(ctx.start_display_time)
+}
+
+// Return the display end time, in milliseconds relative to the pts
+func (ctx *AVSubtitle) EndDisplayTime() uint32 {
+	return uint32(ctx.end_display_time)
+}
+
+// Return the presentation timestamp, in AV_TIME_BASE units
+func (ctx *AVSubtitle) Pts() int64 {
+	return int64(ctx.pts)
+}
+
+// Set the display start time, in milliseconds relative to the pts
+func (ctx *AVSubtitle) SetStartDisplayTime(ms uint32) {
+	ctx.start_display_time = C.uint32_t(ms)
+}
+
+// Set the display end time, in milliseconds relative to the pts
+func (ctx *AVSubtitle) SetEndDisplayTime(ms uint32) {
+	ctx.end_display_time = C.uint32_t(ms)
+}
+
+// Set the presentation timestamp, in AV_TIME_BASE units
+func (ctx *AVSubtitle) SetPts(pts int64) {
+	ctx.pts = C.int64_t(pts)
+}
+
+func (ctx *AVSubtitle) NumRects() uint {
+	return uint(ctx.num_rects)
+}
+
+func (ctx *AVSubtitle) Rects() []*AVSubtitleRect {
+	return cAVSubtitleRectSlice(unsafe.Pointer(ctx.rects), C.int(ctx.num_rects))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - SUBTITLE RECT
+
+func (ctx *AVSubtitleRect) Type() AVSubtitleType {
+	return AVSubtitleType(ctx._type)
+}
+
+// Return the left position of the bitmap
+func (ctx *AVSubtitleRect) X() int {
+	return int(ctx.x)
+}
+
+// Return the top position of the bitmap
+func (ctx *AVSubtitleRect) Y() int {
+	return int(ctx.y)
+}
+
+// Return the width of the bitmap
+func (ctx *AVSubtitleRect) Width() int {
+	return int(ctx.w)
+}
+
+// Return the height of the bitmap
+func (ctx *AVSubtitleRect) Height() int {
+	return int(ctx.h)
+}
+
+// Return the number of colors in the bitmap palette
+func (ctx *AVSubtitleRect) NumColors() int {
+	return int(ctx.nb_colors)
+}
+
+// Return the plain text, for SUBTITLE_TEXT
+func (ctx *AVSubtitleRect) Text() string {
+	return C.GoString(ctx.text)
+}
+
+// Return the ASS event line, for SUBTITLE_ASS
+func (ctx *AVSubtitleRect) Ass() string {
+	return C.GoString(ctx.ass)
+}
+
+// Return the bitmap as palette indexes, one byte per pixel with the
+// stride returned by Linesize, for SUBTITLE_BITMAP
+func (ctx *AVSubtitleRect) Bitmap() []byte {
+	if ctx.data[0] == nil || ctx.h <= 0 {
+		return nil
+	}
+	return cByteSlice(unsafe.Pointer(ctx.data[0]), ctx.linesize[0]*ctx.h)
+}
+
+// Return the stride of the bitmap
+func (ctx *AVSubtitleRect) Linesize() int {
+	return int(ctx.linesize[0])
+}
+
+// Return the bitmap palette as 32-bit ARGB values, for SUBTITLE_BITMAP
+func (ctx *AVSubtitleRect) Palette() []uint32 {
+	if ctx.data[1] == nil || ctx.nb_colors <= 0 {
+		return nil
+	}
+	return cUint32Slice(unsafe.Pointer(ctx.data[1]), ctx.nb_colors)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - CODEC CONTEXT
+
+// Return the subtitle header, which is the ASS script header for text
+// subtitle codecs
+func (ctx *AVCodecContext) SubtitleHeader() string {
+	if ctx.subtitle_header == nil || ctx.subtitle_header_size <= 0 {
+		return ""
+	}
+	return C.GoStringN((*C.char)(unsafe.Pointer(ctx.subtitle_header)), ctx.subtitle_header_size)
+}
+
+// Set the subtitle header, which replaces any existing header. The header
+// is copied and freed with the codec context.
+func (ctx *AVCodecContext) SetSubtitleHeader(header string) error {
+	if ctx.subtitle_header != nil {
+		C.av_freep(unsafe.Pointer(&ctx.subtitle_header))
+		ctx.subtitle_header_size = 0
+	}
+	if header == "" {
+		return nil
+	}
+	ptr := C.av_mallocz(C.size_t(len(header) + 1))
+	if ptr == nil {
+		return errors.New("failed to allocate subtitle header")
+	}
+	copy(cByteSlice(ptr, C.int(len(header))), header)
+	ctx.subtitle_header = (*C.uint8_t)(ptr)
+	ctx.subtitle_header_size = C.int(len(header))
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Decode a subtitle message. Returns true if a subtitle was decoded,
+// in which case the subtitle should be freed with AVCodec_subtitle_free
+// after use. A packet with no data flushes the decoder.
+func AVCodec_decode_subtitle(ctx *AVCodecContext, sub *AVSubtitle, pkt *AVPacket) (bool, error) {
+	var got C.int
+	if err := AVError(C.avcodec_decode_subtitle2((*C.AVCodecContext)(ctx), (*C.AVSubtitle)(sub), &got, (*C.AVPacket)(pkt))); err < 0 {
+		return false, err
+	}
+	return got != 0, nil
+}
+
+// Encode a subtitle into the buffer, and return the number of bytes used.
+// The start display time of the subtitle must be zero.
+func AVCodec_encode_subtitle(ctx *AVCodecContext, buf []byte, sub *AVSubtitle) (int, error) {
+	if len(buf) == 0 {
+		return 0, errors.New("empty buffer")
+	}
+	n := C.avcodec_encode_subtitle((*C.AVCodecContext)(ctx), (*C.uint8_t)(unsafe.Pointer(&buf[0])), C.int(len(buf)), (*C.AVSubtitle)(sub))
+	if err := AVError(n); err < 0 {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// Append a text or ASS rectangle to the subtitle. For SUBTITLE_ASS the
+// text is an ASS event line. The subtitle should be freed with
+// AVCodec_subtitle_free after use.
+func AVCodec_subtitle_add_rect(sub *AVSubtitle, t AVSubtitleType, text string) error {
+	if t != SUBTITLE_TEXT && t != SUBTITLE_ASS {
+		return errors.New("invalid subtitle type")
+	}
+	cText := C.CString(text)
+	defer C.free(unsafe.Pointer(cText))
+	if rect := C.avsubtitle_add_rect((*C.AVSubtitle)(sub), C.enum_AVSubtitleType(t), cText); rect == nil {
+		return errors.New("failed to allocate subtitle rect")
+	}
+	return nil
+}
+
+// Free all allocated data in the given subtitle struct
+func AVCodec_subtitle_free(sub *AVSubtitle) {
+	C.avsubtitle_free((*C.AVSubtitle)(sub))
+}
@@ -0,0 +1,178 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavfilter libavutil
+#include <libavfilter/avfilter.h>
+#include <libavfilter/buffersrc.h>
+#include <libavutil/mem.h>
+#include <stdlib.h>
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	AVFilter        C.AVFilter
+	AVFilterContext C.AVFilterContext
+	AVFilterGraph   C.AVFilterGraph
+	AVFilterInOut   C.AVFilterInOut
+	AVBufferSrcFlag C.int
+)
+
+type jsonAVFilter struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	NumInputs   uint   `json:"num_inputs"`
+	NumOutputs  uint   `json:"num_outputs"`
+}
+
+type jsonAVFilterContext struct {
+	Name   string    `json:"name,omitempty"`
+	Filter *AVFilter `json:"filter,omitempty"`
+}
+
+type jsonAVFilterGraph struct {
+	Filters []*AVFilterContext `json:"filters,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// GLOBALS
+
+const (
+	AV_BUFFERSRC_FLAG_NONE            AVBufferSrcFlag = 0
+	AV_BUFFERSRC_FLAG_NO_CHECK_FORMAT AVBufferSrcFlag = C.AV_BUFFERSRC_FLAG_NO_CHECK_FORMAT // Do not check for format changes
+	AV_BUFFERSRC_FLAG_PUSH            AVBufferSrcFlag = C.AV_BUFFERSRC_FLAG_PUSH            // Immediately push the frame to the output
+	AV_BUFFERSRC_FLAG_KEEP_REF        AVBufferSrcFlag = C.AV_BUFFERSRC_FLAG_KEEP_REF        // Keep a reference to the frame
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (ctx *AVFilter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVFilter{
+		Name:        ctx.Name(),
+		Description: ctx.Description(),
+		NumInputs:   ctx.NumInputs(),
+		NumOutputs:  ctx.NumOutputs(),
+	})
+}
+
+func (ctx *AVFilterContext) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVFilterContext{
+		Name:   ctx.Name(),
+		Filter: ctx.Filter(),
+	})
+}
+
+func (ctx *AVFilterGraph) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVFilterGraph{
+		Filters: ctx.Filters(),
+	})
+}
+
+func (ctx *AVFilter) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+func (ctx *AVFilterContext) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+func (ctx *AVFilterGraph) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - FILTER
+
+func (ctx *AVFilter) Name() string {
+	return C.GoString(ctx.name)
+}
+
+func (ctx *AVFilter) Description() string {
+	return C.GoString(ctx.description)
+}
+
+// Return the number of input pads
+func (ctx *AVFilter) NumInputs() uint {
+	return uint(C.avfilter_filter_pad_count((*C.AVFilter)(ctx), 0))
+}
+
+// Return the number of output pads
+func (ctx *AVFilter) NumOutputs() uint {
+	return uint(C.avfilter_filter_pad_count((*C.AVFilter)(ctx), 1))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - FILTER CONTEXT
+
+func (ctx *AVFilterContext) Name() string {
+	return C.GoString(ctx.name)
+}
+
+func (ctx *AVFilterContext) Filter() *AVFilter {
+	return (*AVFilter)(ctx.filter)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - FILTER GRAPH
+
+// Return the filters in the graph
+func (ctx *AVFilterGraph) Filters() []*AVFilterContext {
+	return cAVFilterContextSlice(unsafe.Pointer(ctx.filters), C.int(ctx.nb_filters))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES - FILTER INPUTS AND OUTPUTS
+
+func (ctx *AVFilterInOut) Name() string {
+	return C.GoString(ctx.name)
+}
+
+// Set the name of the link. The name is copied.
+func (ctx *AVFilterInOut) SetName(name string) {
+	C.av_free(unsafe.Pointer(ctx.name))
+	if name == "" {
+		ctx.name = nil
+	} else {
+		cName := C.CString(name)
+		defer C.free(unsafe.Pointer(cName))
+		ctx.name = C.av_strdup(cName)
+	}
+}
+
+func (ctx *AVFilterInOut) FilterCtx() *AVFilterContext {
+	return (*AVFilterContext)(ctx.filter_ctx)
+}
+
+func (ctx *AVFilterInOut) SetFilterCtx(filter *AVFilterContext) {
+	ctx.filter_ctx = (*C.AVFilterContext)(filter)
+}
+
+func (ctx *AVFilterInOut) PadIdx() int {
+	return int(ctx.pad_idx)
+}
+
+func (ctx *AVFilterInOut) SetPadIdx(idx int) {
+	ctx.pad_idx = C.int(idx)
+}
+
+func (ctx *AVFilterInOut) Next() *AVFilterInOut {
+	return (*AVFilterInOut)(ctx.next)
+}
+
+func (ctx *AVFilterInOut) SetNext(next *AVFilterInOut) {
+	ctx.next = (*C.AVFilterInOut)(next)
+}
@@ -0,0 +1,116 @@
+package ffmpeg
+
+import (
+	"io"
+	"syscall"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavfilter
+#include <libavfilter/buffersrc.h>
+#include <libavfilter/buffersink.h>
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - BUFFER SOURCE
+
+// Add a frame to the buffer source. A nil frame marks the end of the stream,
+// so that the graph is flushed.
+func AVBufferSrc_add_frame(ctx *AVFilterContext, frame *AVFrame, flags AVBufferSrcFlag) error {
+	if err := AVError(C.av_buffersrc_add_frame_flags((*C.AVFilterContext)(ctx), (*C.AVFrame)(frame), C.int(flags))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Close the buffer source after EOF, with the timestamp of the end of the
+// stream in the timebase of the buffer source
+func AVBufferSrc_close(ctx *AVFilterContext, pts int64, flags AVBufferSrcFlag) error {
+	if err := AVError(C.av_buffersrc_close((*C.AVFilterContext)(ctx), C.int64_t(pts), C.uint(flags))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - BUFFER SINK
+
+// Get a frame with filtered data from the sink and put it in frame. Error
+// return of EAGAIN means that more input is needed to produce output, and
+// io.EOF means that no more output will be produced.
+func AVBufferSink_get_frame(ctx *AVFilterContext, frame *AVFrame) error {
+	if err := AVError(C.av_buffersink_get_frame((*C.AVFilterContext)(ctx), (*C.AVFrame)(frame))); err != 0 {
+		if err == AVERROR_EOF {
+			return io.EOF
+		} else if err.IsErrno(syscall.EAGAIN) {
+			return syscall.EAGAIN
+		} else {
+			return err
+		}
+	}
+	return nil
+}
+
+// Return the media type of the sink output
+func AVBufferSink_type(ctx *AVFilterContext) AVMediaType {
+	return AVMediaType(C.av_buffersink_get_type((*C.AVFilterContext)(ctx)))
+}
+
+// Return the timebase of the sink output
+func AVBufferSink_time_base(ctx *AVFilterContext) AVRational {
+	return AVRational(C.av_buffersink_get_time_base((*C.AVFilterContext)(ctx)))
+}
+
+// Return the frame rate of the sink output, for video
+func AVBufferSink_frame_rate(ctx *AVFilterContext) AVRational {
+	return AVRational(C.av_buffersink_get_frame_rate((*C.AVFilterContext)(ctx)))
+}
+
+// Return the width of the sink output, for video
+func AVBufferSink_width(ctx *AVFilterContext) int {
+	return int(C.av_buffersink_get_w((*C.AVFilterContext)(ctx)))
+}
+
+// Return the height of the sink output, for video
+func AVBufferSink_height(ctx *AVFilterContext) int {
+	return int(C.av_buffersink_get_h((*C.AVFilterContext)(ctx)))
+}
+
+// Return the sample aspect ratio of the sink output, for video
+func AVBufferSink_sample_aspect_ratio(ctx *AVFilterContext) AVRational {
+	return AVRational(C.av_buffersink_get_sample_aspect_ratio((*C.AVFilterContext)(ctx)))
+}
+
+// Return the pixel format of the sink output, for video
+func AVBufferSink_pix_fmt(ctx *AVFilterContext) AVPixelFormat {
+	return AVPixelFormat(C.av_buffersink_get_format((*C.AVFilterContext)(ctx)))
+}
+
+// Return the sample format of the sink output, for audio
+func AVBufferSink_sample_fmt(ctx *AVFilterContext) AVSampleFormat {
+	return AVSampleFormat(C.av_buffersink_get_format((*C.AVFilterContext)(ctx)))
+}
+
+// Return the sample rate of the sink output, for audio
+func AVBufferSink_sample_rate(ctx *AVFilterContext) int {
+	return int(C.av_buffersink_get_sample_rate((*C.AVFilterContext)(ctx)))
+}
+
+// Return the channel layout of the sink output, for audio
+func AVBufferSink_ch_layout(ctx *AVFilterContext) (AVChannelLayout, error) {
+	var ch AVChannelLayout
+	if err := AVError(C.av_buffersink_get_ch_layout((*C.AVFilterContext)(ctx), (*C.AVChannelLayout)(&ch))); err != 0 {
+		return ch, err
+	}
+	return ch, nil
+}
+
+// Set the number of samples for each frame returned by the sink, for audio.
+// This is useful for encoders which require a fixed frame size.
+func AVBufferSink_set_frame_size(ctx *AVFilterContext, frame_size int) {
+	C.av_buffersink_set_frame_size((*C.AVFilterContext)(ctx), C.uint(frame_size))
+}
@@ -0,0 +1,105 @@
+package ffmpeg
+
+import (
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavfilter libavutil
+#include <libavfilter/avfilter.h>
+#include <libavutil/mem.h>
+#include <stdlib.h>
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - FILTERS
+
+// Get a filter definition matching the given name, or nil if no
+// filter is found
+func AVFilter_get_by_name(name string) *AVFilter {
+	cName := C.CString(name)
+	defer C.free(unsafe.Pointer(cName))
+	return (*AVFilter)(C.avfilter_get_by_name(cName))
+}
+
+// Iterate over all registered filters
+func AVFilter_iterate(opaque *uintptr) *AVFilter {
+	return (*AVFilter)(C.av_filter_iterate((*unsafe.Pointer)(unsafe.Pointer(opaque))))
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - GRAPH
+
+// Allocate a filter graph
+func AVFilterGraph_alloc() *AVFilterGraph {
+	return (*AVFilterGraph)(C.avfilter_graph_alloc())
+}
+
+// Free a graph, destroy its links, and set *graph to NULL
+func AVFilterGraph_free(graph *AVFilterGraph) {
+	C.avfilter_graph_free((**C.AVFilterGraph)(unsafe.Pointer(&graph)))
+}
+
+// Create and add a filter instance into an existing graph. The filter
+// instance is created from the filter and initialized with the args
+// parameter.
+func AVFilterGraph_create_filter(graph *AVFilterGraph, filter *AVFilter, name, args string) (*AVFilterContext, error) {
+	var ctx *C.AVFilterContext
+	var cName, cArgs *C.char
+	if name != "" {
+		cName = C.CString(name)
+		defer C.free(unsafe.Pointer(cName))
+	}
+	if args != "" {
+		cArgs = C.CString(args)
+		defer C.free(unsafe.Pointer(cArgs))
+	}
+	if err := AVError(C.avfilter_graph_create_filter(&ctx, (*C.AVFilter)(filter), cName, cArgs, nil, (*C.AVFilterGraph)(graph))); err != 0 {
+		return nil, err
+	}
+	return (*AVFilterContext)(ctx), nil
+}
+
+// Add a graph described by a string to a graph. The inputs and outputs
+// are the open links of the graph, and are updated to contain the links
+// which remain open after parsing.
+func AVFilterGraph_parse(graph *AVFilterGraph, filters string, inputs, outputs **AVFilterInOut) error {
+	cFilters := C.CString(filters)
+	defer C.free(unsafe.Pointer(cFilters))
+	if err := AVError(C.avfilter_graph_parse_ptr((*C.AVFilterGraph)(graph), cFilters, (**C.AVFilterInOut)(unsafe.Pointer(inputs)), (**C.AVFilterInOut)(unsafe.Pointer(outputs)), nil)); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Check validity and configure all the links and formats in the graph
+func AVFilterGraph_config(graph *AVFilterGraph) error {
+	if err := AVError(C.avfilter_graph_config((*C.AVFilterGraph)(graph), nil)); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Dump a graph into a human-readable string representation
+func AVFilterGraph_dump(graph *AVFilterGraph) string {
+	cStr := C.avfilter_graph_dump((*C.AVFilterGraph)(graph), nil)
+	defer C.av_free(unsafe.Pointer(cStr))
+	return C.GoString(cStr)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS - INPUTS AND OUTPUTS
+
+// Allocate a single AVFilterInOut entry
+func AVFilterInOut_alloc() *AVFilterInOut {
+	return (*AVFilterInOut)(C.avfilter_inout_alloc())
+}
+
+// Free the supplied list of AVFilterInOut
+func AVFilterInOut_free(inout *AVFilterInOut) {
+	C.avfilter_inout_free((**C.AVFilterInOut)(unsafe.Pointer(&inout)))
+}
@@ -0,0 +1,108 @@
+package ffmpeg_test
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	// Packages
+	"github.com/stretchr/testify/assert"
+
+	// Namespace imports
+	. "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+func Test_avfilter_graph_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Iterate over the filters
+	var opaque uintptr
+	for {
+		filter := AVFilter_iterate(&opaque)
+		if filter == nil {
+			break
+		}
+		t.Log(filter)
+	}
+
+	// Get filters by name
+	assert.NotNil(AVFilter_get_by_name("abuffer"))
+	assert.NotNil(AVFilter_get_by_name("abuffersink"))
+	assert.Nil(AVFilter_get_by_name("nonexistent"))
+}
+
+func Test_avfilter_graph_001(t *testing.T) {
+	assert := assert.New(t)
+
+	graph := AVFilterGraph_alloc()
+	if !assert.NotNil(graph) {
+		t.SkipNow()
+	}
+	defer AVFilterGraph_free(graph)
+
+	// Create the source and sink
+	src, err := AVFilterGraph_create_filter(graph, AVFilter_get_by_name("abuffer"), "in", "time_base=1/44100:sample_rate=44100:sample_fmt=fltp:channel_layout=mono")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	sink, err := AVFilterGraph_create_filter(graph, AVFilter_get_by_name("abuffersink"), "out", "")
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+
+	// Connect the source and sink to the parsed graph
+	outputs := AVFilterInOut_alloc()
+	outputs.SetName("in")
+	outputs.SetFilterCtx(src)
+	inputs := AVFilterInOut_alloc()
+	inputs.SetName("out")
+	inputs.SetFilterCtx(sink)
+	err = AVFilterGraph_parse(graph, "volume=0.5", &inputs, &outputs)
+	AVFilterInOut_free(inputs)
+	AVFilterInOut_free(outputs)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	if !assert.NoError(AVFilterGraph_config(graph)) {
+		t.FailNow()
+	}
+	t.Log(AVFilterGraph_dump(graph))
+	assert.Equal(44100, AVBufferSink_sample_rate(sink))
+	assert.Equal(AVMEDIA_TYPE_AUDIO, AVBufferSink_type(sink))
+
+	// Create a frame of silence
+	frame := AVUtil_frame_alloc()
+	if !assert.NotNil(frame) {
+		t.FailNow()
+	}
+	defer AVUtil_frame_free(frame)
+	var ch AVChannelLayout
+	AVUtil_channel_layout_default(&ch, 1)
+	frame.SetSampleFormat(AV_SAMPLE_FMT_FLTP)
+	frame.SetSampleRate(44100)
+	frame.SetNumSamples(1024)
+	frame.SetPts(0)
+	if !assert.NoError(frame.SetChannelLayout(ch)) {
+		t.FailNow()
+	}
+	if !assert.NoError(AVUtil_frame_get_buffer(frame, false)) {
+		t.FailNow()
+	}
+
+	// Filter the frame, then flush
+	assert.NoError(AVBufferSrc_add_frame(src, frame, AV_BUFFERSRC_FLAG_NONE))
+	assert.NoError(AVBufferSrc_add_frame(src, nil, AV_BUFFERSRC_FLAG_NONE))
+
+	// Receive the frame
+	samples := 0
+	for {
+		if err := AVBufferSink_get_frame(sink, frame); errors.Is(err, io.EOF) {
+			break
+		} else if !assert.NoError(err) {
+			t.FailNow()
+		}
+		samples += frame.NumSamples()
+		AVUtil_frame_unref(frame)
+	}
+	assert.Equal(1024, samples)
+}
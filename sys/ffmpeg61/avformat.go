@@ -19,12 +19,14 @@ import "C"
 // TYPES
 
 type (
+	AVChapter       C.struct_AVChapter
 	AVDisposition   C.int
 	AVFormat        C.int
 	AVFormatContext C.struct_AVFormatContext
 	AVFormatFlag    C.int
 	AVInputFormat   C.struct_AVInputFormat
 	AVIOContext     C.struct_AVIOContext
+	AVIODataMarker  C.int
 	AVIOFlag        C.int
 	AVOutputFormat  C.struct_AVOutputFormat
 	AVStream        C.struct_AVStream
@@ -63,6 +65,19 @@ const (
 	AVSEEK_FORCE = C.AVSEEK_FORCE
 )
 
+const (
+	AVSEEK_FLAG_NONE     = 0
+	AVSEEK_FLAG_BACKWARD = C.AVSEEK_FLAG_BACKWARD ///< seek backward
+	AVSEEK_FLAG_BYTE     = C.AVSEEK_FLAG_BYTE     ///< seeking based on position in bytes
+	AVSEEK_FLAG_ANY      = C.AVSEEK_FLAG_ANY      ///< seek to any frame, even non-keyframes
+	AVSEEK_FLAG_FRAME    = C.AVSEEK_FLAG_FRAME    ///< seeking based on frame number
+)
+
+const (
+	AVINDEX_KEYFRAME      = C.AVINDEX_KEYFRAME      ///< index entry is a keyframe
+	AVINDEX_DISCARD_FRAME = C.AVINDEX_DISCARD_FRAME ///< index entry should be discarded after decoding
+)
+
 const (
 	AVIO_FLAG_NONE       AVIOFlag = 0
 	AVIO_FLAG_READ       AVIOFlag = C.AVIO_FLAG_READ
@@ -239,6 +254,10 @@ func (ctx *AVFormatContext) SetMetadata(dict *AVDictionary) {
 	}
 }
 
+func (ctx *AVFormatContext) Pb() *AVIOContext {
+	return (*AVIOContext)(ctx.pb)
+}
+
 func (ctx *AVFormatContext) SetPb(pb *AVIOContextEx) {
 	if pb == nil {
 		ctx.pb = nil
@@ -255,6 +274,14 @@ func (ctx *AVFormatContext) Streams() []*AVStream {
 	return cAVStreamSlice(unsafe.Pointer(ctx.streams), C.int(ctx.nb_streams))
 }
 
+func (ctx *AVFormatContext) NumChapters() uint {
+	return uint(ctx.nb_chapters)
+}
+
+func (ctx *AVFormatContext) Chapters() []*AVChapter {
+	return cAVChapterSlice(unsafe.Pointer(ctx.chapters), C.int(ctx.nb_chapters))
+}
+
 func (ctx *AVFormatContext) Stream(stream int) *AVStream {
 	streams := ctx.Streams()
 	if stream < 0 || stream >= len(streams) {
@@ -276,6 +303,15 @@ func (ctx *AVFormatContext) Duration() int64 {
 	return int64(ctx.duration)
 }
 
+func (ctx *AVFormatContext) StartTime() int64 {
+	return int64(ctx.start_time)
+}
+
+// Total stream bitrate in bit/s, 0 if not available
+func (ctx *AVFormatContext) BitRate() int64 {
+	return int64(ctx.bit_rate)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // AVFormatFlag
 
@@ -15,6 +15,16 @@ import (
 extern int avio_read_callback(void* userInfo, uint8_t* buf, int buf_size);
 extern int avio_write_callback(void* userInfo, uint8_t* buf, int buf_size);
 extern int64_t avio_seek_callback(void* userInfo, int64_t offset, int whence);
+extern int avio_write_data_type_callback(void* userInfo, uint8_t* buf, int buf_size, int type, int64_t time);
+
+static int avio_write_data_type_(void* userInfo, const uint8_t* buf, int buf_size, enum AVIODataMarkerType type, int64_t time) {
+	return avio_write_data_type_callback(userInfo, (uint8_t* )buf, buf_size, (int)type, time);
+}
+
+static void avio_set_write_data_type_(AVIOContext* ctx) {
+	ctx->write_data_type = (__typeof__(ctx->write_data_type))avio_write_data_type_;
+	ctx->ignore_boundary_point = 0;
+}
 
 static AVIOContext* avio_alloc_context_(int sz, int writeable, void* userInfo) {
 	uint8_t* buf = av_malloc(sz);
@@ -41,10 +51,29 @@ type AVIOContextCallback interface {
 	Seeker(offset int64, whence int) int64
 }
 
+// Optional callback for AVIOContextEx, which receives written data with
+// the type of data, and the timestamp in AV_TIME_BASE units or AV_NOPTS_VALUE.
+// When implemented, this is called instead of Writer.
+type AVIOContextDataCallback interface {
+	WriterData(buf []byte, t AVIODataMarker, ts int64) int
+}
+
 var (
 	callbacks = make(map[uintptr]AVIOContextCallback)
 )
 
+////////////////////////////////////////////////////////////////////////////////
+// CONSTANTS
+
+const (
+	AVIO_DATA_MARKER_HEADER         AVIODataMarker = C.AVIO_DATA_MARKER_HEADER         // Header data; this needs to be present for the stream to be decodeable.
+	AVIO_DATA_MARKER_SYNC_POINT     AVIODataMarker = C.AVIO_DATA_MARKER_SYNC_POINT     // A point in the output bytestream where a decoder can start decoding (i.e. a keyframe).
+	AVIO_DATA_MARKER_BOUNDARY_POINT AVIODataMarker = C.AVIO_DATA_MARKER_BOUNDARY_POINT // A point in the output bytestream where a demuxer can start parsing (for non self synchronizing bytestream formats).
+	AVIO_DATA_MARKER_UNKNOWN        AVIODataMarker = C.AVIO_DATA_MARKER_UNKNOWN        // This is any, unlabelled data.
+	AVIO_DATA_MARKER_TRAILER        AVIODataMarker = C.AVIO_DATA_MARKER_TRAILER        // Trailer data, which doesn't contain actual content, but only for finalizing the output file.
+	AVIO_DATA_MARKER_FLUSH_POINT    AVIODataMarker = C.AVIO_DATA_MARKER_FLUSH_POINT    // A point in the output bytestream where the underlying AVIOContext might flush the buffer depending on latency or buffering requirements.
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // FUNCTIONS
 
@@ -67,6 +96,11 @@ func AVFormat_avio_alloc_context(sz int, writeable bool, callback AVIOContextCal
 		return nil
 	}
 
+	// Set the data marker callback
+	if _, ok := callback.(AVIOContextDataCallback); ok && writeable {
+		C.avio_set_write_data_type_((*C.struct_AVIOContext)(ctx.AVIOContext))
+	}
+
 	return ctx
 }
 
@@ -143,6 +177,21 @@ func AVFormat_avio_read(ctx *AVIOContextEx, buf []byte) int {
 	return int(C.avio_read((*C.struct_AVIOContext)(ctx.AVIOContext), (*C.uint8_t)(&buf[0]), C.int(len(buf))))
 }
 
+// Return true if the context supports seeking
+func (ctx *AVIOContext) Seekable() bool {
+	return ctx.seekable&C.AVIO_SEEKABLE_NORMAL != 0
+}
+
+// Set whether the context supports seeking. Muxers which need to seek
+// back to rewrite headers check this before writing.
+func (ctx *AVIOContext) SetSeekable(seekable bool) {
+	if seekable {
+		ctx.seekable = C.AVIO_SEEKABLE_NORMAL
+	} else {
+		ctx.seekable = 0
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 // CALLBACKS
 
@@ -175,3 +224,13 @@ func avio_seek_callback(userInfo unsafe.Pointer, offset C.int64_t, whence C.int)
 	}
 	return C.int64_t(callback.Seeker(int64(offset), int(whence)))
 }
+
+//export avio_write_data_type_callback
+func avio_write_data_type_callback(userInfo unsafe.Pointer, buf *C.uint8_t, size C.int, t C.int, ts C.int64_t) C.int {
+	ptr := uintptr(userInfo)
+	callback, ok := callbacks[ptr]
+	if !ok {
+		panic("avio_write_data_type_callback: callback not found")
+	}
+	return C.int(callback.(AVIOContextDataCallback).WriterData(cByteSlice(unsafe.Pointer(buf), size), AVIODataMarker(t), int64(ts)))
+}
@@ -0,0 +1,102 @@
+package ffmpeg
+
+import (
+	"encoding/json"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavformat libavutil
+#include <libavformat/avformat.h>
+#include <libavutil/mem.h>
+
+// Allocate a chapter and append it to the format context, which takes ownership
+static AVChapter* avformat_new_chapter(AVFormatContext* ctx, int64_t id, AVRational time_base, int64_t start, int64_t end) {
+	AVChapter* chapter = av_mallocz(sizeof(AVChapter));
+	if (chapter == NULL) {
+		return NULL;
+	}
+	if (av_dynarray_add_nofree(&ctx->chapters, (int* )&ctx->nb_chapters, chapter) < 0) {
+		av_free(chapter);
+		return NULL;
+	}
+	chapter->id = id;
+	chapter->time_base = time_base;
+	chapter->start = start;
+	chapter->end = end;
+	return chapter;
+}
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type jsonAVChapter struct {
+	Id       int64         `json:"id"`
+	TimeBase AVRational    `json:"time_base,omitempty"`
+	Start    int64         `json:"start"`
+	End      int64         `json:"end"`
+	Metadata *AVDictionary `json:"metadata,omitempty"`
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// STRINGIFY
+
+func (ctx *AVChapter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonAVChapter{
+		Id:       int64(ctx.id),
+		TimeBase: AVRational(ctx.time_base),
+		Start:    int64(ctx.start),
+		End:      int64(ctx.end),
+		Metadata: ctx.Metadata(),
+	})
+}
+
+func (ctx *AVChapter) String() string {
+	data, _ := json.MarshalIndent(ctx, "", "  ")
+	return string(data)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+func (ctx *AVChapter) Id() int64 {
+	return int64(ctx.id)
+}
+
+func (ctx *AVChapter) TimeBase() AVRational {
+	return AVRational(ctx.time_base)
+}
+
+func (ctx *AVChapter) Start() int64 {
+	return int64(ctx.start)
+}
+
+func (ctx *AVChapter) End() int64 {
+	return int64(ctx.end)
+}
+
+func (ctx *AVChapter) Metadata() *AVDictionary {
+	return &AVDictionary{ctx.metadata}
+}
+
+// Set the chapter metadata. Ownership of the dictionary is transferred to the chapter.
+func (ctx *AVChapter) SetMetadata(dict *AVDictionary) {
+	if dict == nil {
+		ctx.metadata = nil
+	} else {
+		ctx.metadata = dict.ctx
+	}
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Create a new chapter with a unique identifier, timebase and start and end
+// times in the timebase. The chapter is owned by the format context.
+func AVFormat_new_chapter(ctx *AVFormatContext, id int64, tb AVRational, start, end int64) *AVChapter {
+	return (*AVChapter)(C.avformat_new_chapter((*C.struct_AVFormatContext)(ctx), C.int64_t(id), C.AVRational(tb), C.int64_t(start), C.int64_t(end)))
+}
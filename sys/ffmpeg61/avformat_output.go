@@ -113,3 +113,9 @@ func AVFormat_guess_format(format, filename, mimetype string) *AVOutputFormat {
 	defer C.free(unsafe.Pointer(cMimeType))
 	return (*AVOutputFormat)(C.av_guess_format(cFormat, cFilename, cMimeType))
 }
+
+// Return true if the codec can be stored in the output format, or if it
+// cannot be determined whether the codec can be stored.
+func AVFormat_query_codec(ofmt *AVOutputFormat, codec_id AVCodecID) bool {
+	return C.avformat_query_codec((*C.AVOutputFormat)(ofmt), C.enum_AVCodecID(codec_id), C.FF_COMPLIANCE_NORMAL) != 0
+}
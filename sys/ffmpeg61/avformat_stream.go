@@ -75,10 +75,53 @@ func (ctx *AVStream) SetTimeBase(time_base AVRational) {
 	ctx.time_base = C.AVRational(time_base)
 }
 
+func (ctx *AVStream) StartTime() int64 {
+	return int64(ctx.start_time)
+}
+
+func (ctx *AVStream) Duration() int64 {
+	return int64(ctx.duration)
+}
+
+func (ctx *AVStream) NumFrames() int64 {
+	return int64(ctx.nb_frames)
+}
+
+// Average framerate
+func (ctx *AVStream) AvgFrameRate() AVRational {
+	return AVRational(ctx.avg_frame_rate)
+}
+
+// Real base framerate, which is the lowest framerate with which all timestamps can be represented accurately
+func (ctx *AVStream) RFrameRate() AVRational {
+	return AVRational(ctx.r_frame_rate)
+}
+
+func (ctx *AVStream) SampleAspectRatio() AVRational {
+	return AVRational(ctx.sample_aspect_ratio)
+}
+
 func (ctx *AVStream) Disposition() AVDisposition {
 	return AVDisposition(ctx.disposition)
 }
 
+func (ctx *AVStream) SetDisposition(disposition AVDisposition) {
+	ctx.disposition = C.int(disposition)
+}
+
+func (ctx *AVStream) Metadata() *AVDictionary {
+	return &AVDictionary{ctx.metadata}
+}
+
+// Set the stream metadata. Ownership of the dictionary is transferred to the stream.
+func (ctx *AVStream) SetMetadata(dict *AVDictionary) {
+	if dict == nil {
+		ctx.metadata = nil
+	} else {
+		ctx.metadata = dict.ctx
+	}
+}
+
 func (ctx *AVStream) AttachedPic() *AVPacket {
 	if ctx.disposition&C.AV_DISPOSITION_ATTACHED_PIC == 0 {
 		return nil
@@ -90,6 +133,21 @@ func (ctx *AVStream) AttachedPic() *AVPacket {
 ////////////////////////////////////////////////////////////////////////////////
 // PUBLIC METHODS
 
+// Add an index entry for a stream, with the byte position and timestamp in the
+// stream timebase. The flags are AVINDEX_KEYFRAME for keyframes.
+func AVFormat_add_index_entry(stream *AVStream, pos, timestamp int64, size, distance, flags int) error {
+	if ret := C.av_add_index_entry((*C.struct_AVStream)(stream), C.int64_t(pos), C.int64_t(timestamp), C.int(size), C.int(distance), C.int(flags)); ret < 0 {
+		return AVError(ret)
+	}
+	// Return success
+	return nil
+}
+
+// Return the number of index entries for a stream.
+func AVFormat_index_get_entries_count(stream *AVStream) int {
+	return int(C.avformat_index_get_entries_count((*C.struct_AVStream)(stream)))
+}
+
 func AVFormat_new_stream(ctx *AVFormatContext, c *AVCodec) *AVStream {
 	return (*AVStream)(C.avformat_new_stream((*C.struct_AVFormatContext)(ctx), (*C.struct_AVCodec)(c)))
 }
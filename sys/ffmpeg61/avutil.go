@@ -69,6 +69,7 @@ type (
 	AVPictureType      C.enum_AVPictureType
 	AVPixelFormat      C.enum_AVPixelFormat
 	AVPixFmtDescriptor C.AVPixFmtDescriptor
+	AVPixFmtFlag       C.uint64_t
 	AVRounding         C.enum_AVRounding
 	AVSampleFormat     C.enum_AVSampleFormat
 )
@@ -0,0 +1,105 @@
+package ffmpeg
+
+import (
+	"errors"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavutil
+#include <libavutil/audio_fifo.h>
+#include <libavutil/frame.h>
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	AVAudioFifo C.AVAudioFifo
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Allocate an audio FIFO with the sample format, number of channels and
+// initial allocation size in samples. The FIFO grows as needed.
+func AVUtil_audio_fifo_alloc(sample_fmt AVSampleFormat, channels, nb_samples int) *AVAudioFifo {
+	return (*AVAudioFifo)(C.av_audio_fifo_alloc(C.enum_AVSampleFormat(sample_fmt), C.int(channels), C.int(nb_samples)))
+}
+
+// Free an audio FIFO
+func AVUtil_audio_fifo_free(fifo *AVAudioFifo) {
+	C.av_audio_fifo_free((*C.AVAudioFifo)(fifo))
+}
+
+// Reallocate an audio FIFO to hold at least nb_samples samples
+func AVUtil_audio_fifo_realloc(fifo *AVAudioFifo, nb_samples int) error {
+	if err := AVError(C.av_audio_fifo_realloc((*C.AVAudioFifo)(fifo), C.int(nb_samples))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Write the samples of an audio frame to the FIFO, which must have the same
+// sample format and number of channels. Returns the number of samples written.
+func AVUtil_audio_fifo_write(fifo *AVAudioFifo, frame *AVFrame) (int, error) {
+	if frame.nb_samples <= 0 {
+		return 0, nil
+	}
+	n := C.av_audio_fifo_write((*C.AVAudioFifo)(fifo), (*unsafe.Pointer)(unsafe.Pointer(frame.extended_data)), frame.nb_samples)
+	if err := AVError(n); err < 0 {
+		return 0, err
+	} else if n != frame.nb_samples {
+		return int(n), errors.New("short write to audio fifo")
+	}
+	return int(n), nil
+}
+
+// Read up to nb_samples samples from the FIFO into the audio frame, which must
+// have buffers allocated for at least nb_samples samples. The number of samples
+// in the frame is set to the number of samples read, which is returned.
+func AVUtil_audio_fifo_read(fifo *AVAudioFifo, frame *AVFrame, nb_samples int) (int, error) {
+	n := C.av_audio_fifo_read((*C.AVAudioFifo)(fifo), (*unsafe.Pointer)(unsafe.Pointer(frame.extended_data)), C.int(nb_samples))
+	if err := AVError(n); err < 0 {
+		return 0, err
+	}
+	frame.nb_samples = n
+	return int(n), nil
+}
+
+// Read up to nb_samples samples from the FIFO into the audio frame without
+// removing them from the FIFO. Returns the number of samples read.
+func AVUtil_audio_fifo_peek(fifo *AVAudioFifo, frame *AVFrame, nb_samples int) (int, error) {
+	n := C.av_audio_fifo_peek((*C.AVAudioFifo)(fifo), (*unsafe.Pointer)(unsafe.Pointer(frame.extended_data)), C.int(nb_samples))
+	if err := AVError(n); err < 0 {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// Remove nb_samples samples from the FIFO
+func AVUtil_audio_fifo_drain(fifo *AVAudioFifo, nb_samples int) error {
+	if err := AVError(C.av_audio_fifo_drain((*C.AVAudioFifo)(fifo), C.int(nb_samples))); err != 0 {
+		return err
+	}
+	return nil
+}
+
+// Remove all samples from the FIFO
+func AVUtil_audio_fifo_reset(fifo *AVAudioFifo) {
+	C.av_audio_fifo_reset((*C.AVAudioFifo)(fifo))
+}
+
+// Return the number of samples available for reading
+func AVUtil_audio_fifo_size(fifo *AVAudioFifo) int {
+	return int(C.av_audio_fifo_size((*C.AVAudioFifo)(fifo)))
+}
+
+// Return the number of samples available for writing without reallocation
+func AVUtil_audio_fifo_space(fifo *AVAudioFifo) int {
+	return int(C.av_audio_fifo_space((*C.AVAudioFifo)(fifo)))
+}
@@ -0,0 +1,56 @@
+package ffmpeg_test
+
+import (
+	"testing"
+
+	// Packages
+	"github.com/stretchr/testify/assert"
+
+	// Namespace imports
+	. "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+func Test_avutil_audio_fifo_000(t *testing.T) {
+	assert := assert.New(t)
+
+	fifo := AVUtil_audio_fifo_alloc(AV_SAMPLE_FMT_FLTP, 2, 1024)
+	if !assert.NotNil(fifo) {
+		t.SkipNow()
+	}
+	defer AVUtil_audio_fifo_free(fifo)
+
+	// Make a stereo frame with 1000 samples
+	frame := AVUtil_frame_alloc()
+	if !assert.NotNil(frame) {
+		t.SkipNow()
+	}
+	defer AVUtil_frame_free(frame)
+	var ch AVChannelLayout
+	AVUtil_channel_layout_default(&ch, 2)
+	frame.SetSampleFormat(AV_SAMPLE_FMT_FLTP)
+	frame.SetNumSamples(1000)
+	assert.NoError(frame.SetChannelLayout(ch))
+	if !assert.NoError(AVUtil_frame_get_buffer(frame, false)) {
+		t.FailNow()
+	}
+	AVUtil_frame_set_silence(frame, 0, 1000)
+
+	// Write the frame twice
+	for i := 0; i < 2; i++ {
+		n, err := AVUtil_audio_fifo_write(fifo, frame)
+		assert.NoError(err)
+		assert.Equal(1000, n)
+	}
+	assert.Equal(2000, AVUtil_audio_fifo_size(fifo))
+
+	// Read in chunks of 768 samples
+	total := 0
+	for AVUtil_audio_fifo_size(fifo) > 0 {
+		n, err := AVUtil_audio_fifo_read(fifo, frame, 768)
+		assert.NoError(err)
+		assert.Equal(n, frame.NumSamples())
+		total += n
+	}
+	assert.Equal(2000, total)
+	assert.Equal(2000-768-768, frame.NumSamples())
+}
@@ -0,0 +1,239 @@
+package ffmpeg
+
+import (
+	"sync"
+	"unsafe"
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// CGO
+
+/*
+#cgo pkg-config: libavutil
+#include <libavutil/buffer.h>
+#include <libavutil/frame.h>
+#include <libavutil/imgutils.h>
+#include <libavutil/mem.h>
+#include <libavutil/common.h>
+#include <libavutil/samplefmt.h>
+#include <stdatomic.h>
+
+// Allocate a buffer for a pool, counting the buffers allocated
+static AVBufferRef* av_buffer_pool_alloc_(void* opaque, size_t size) {
+	AVBufferRef* buf = av_buffer_alloc(size);
+	if (buf) {
+		atomic_fetch_add((atomic_int*)opaque, 1);
+	}
+	return buf;
+}
+
+// Create a buffer pool with a counter of the buffers allocated, which is
+// freed with the pool
+static AVBufferPool* av_buffer_pool_init_(size_t size, void** allocs) {
+	*allocs = av_mallocz(sizeof(atomic_int));
+	if (!*allocs) {
+		return NULL;
+	}
+	AVBufferPool* pool = av_buffer_pool_init2(size, *allocs, av_buffer_pool_alloc_, av_free);
+	if (!pool) {
+		av_freep(allocs);
+	}
+	return pool;
+}
+
+static int av_buffer_pool_allocs_(void* allocs) {
+	return atomic_load((atomic_int*)allocs);
+}
+
+// Padding between the planes of a video frame, as per av_frame_get_buffer
+#define FRAME_PLANE_PADDING(align) FFMAX(16 + 16, align)
+
+// Set the linesizes of a video frame and return the size of a buffer which
+// holds the planes, with the same layout as av_frame_get_buffer: the
+// linesizes are aligned, the height is padded to a multiple of 32 and each
+// plane is padded so SIMD code can read past the end of a plane
+static int av_frame_video_buffer_size(const AVFrame* frame, int align, int linesize[4], size_t sizes[4]) {
+	int ret = 0;
+	if (align <= 0) {
+		align = 32;
+	}
+	for (int i = 1; i <= align; i += i) {
+		if ((ret = av_image_fill_linesizes(linesize, frame->format, FFALIGN(frame->width, i))) < 0) {
+			return ret;
+		}
+		if (!(linesize[0] & (align - 1))) {
+			break;
+		}
+	}
+	ptrdiff_t linesizes[4];
+	for (int i = 0; i < 4; i++) {
+		if (linesize[i]) {
+			linesize[i] = FFALIGN(linesize[i], align);
+		}
+		linesizes[i] = linesize[i];
+	}
+	if ((ret = av_image_fill_plane_sizes(sizes, frame->format, FFALIGN(frame->height, 32), linesizes)) < 0) {
+		return ret;
+	}
+	size_t total = 4 * FRAME_PLANE_PADDING(align);
+	for (int i = 0; i < 4; i++) {
+		if (sizes[i] > INT_MAX - total) {
+			return AVERROR(EINVAL);
+		}
+		total += sizes[i];
+	}
+	return (int)total;
+}
+
+// Return the size of a buffer which holds the frame data with the alignment
+static int av_frame_get_buffer_size(const AVFrame* frame, int align) {
+	if (frame->nb_samples > 0) {
+		return av_samples_get_buffer_size(NULL, frame->ch_layout.nb_channels, frame->nb_samples, frame->format, align);
+	} else {
+		int linesize[4];
+		size_t sizes[4];
+		return av_frame_video_buffer_size(frame, align, linesize, sizes);
+	}
+}
+
+// Attach a buffer from the pool to a frame, and set the data pointers and
+// linesizes. The format, width and height (for video) or format, nb_samples
+// and ch_layout (for audio) must be set.
+static int av_frame_get_pool_buffer(AVFrame* frame, AVBufferPool* pool, int align) {
+	int size = av_frame_get_buffer_size(frame, align);
+	if (size < 0) {
+		return size;
+	}
+	AVBufferRef* buf = av_buffer_pool_get(pool);
+	if (!buf) {
+		return AVERROR(ENOMEM);
+	} else if (buf->size < size) {
+		av_buffer_unref(&buf);
+		return AVERROR(EINVAL);
+	}
+
+	int ret;
+	if (frame->nb_samples > 0) {
+		int channels = frame->ch_layout.nb_channels;
+		int planes = av_sample_fmt_is_planar(frame->format) ? channels : 1;
+		if (planes > AV_NUM_DATA_POINTERS) {
+			frame->extended_data = av_calloc(planes, sizeof(*frame->extended_data));
+			if (!frame->extended_data) {
+				av_buffer_unref(&buf);
+				return AVERROR(ENOMEM);
+			}
+		} else {
+			frame->extended_data = frame->data;
+		}
+		ret = av_samples_fill_arrays(frame->extended_data, &frame->linesize[0], buf->data, channels, frame->nb_samples, frame->format, align);
+		for (int i = 0; ret >= 0 && i < FFMIN(planes, AV_NUM_DATA_POINTERS); i++) {
+			frame->data[i] = frame->extended_data[i];
+		}
+	} else {
+		int linesize[4];
+		size_t sizes[4];
+		align = align > 0 ? align : 32;
+		ret = av_frame_video_buffer_size(frame, align, linesize, sizes);
+		for (int i = 0; ret >= 0 && i < 4; i++) {
+			frame->linesize[i] = linesize[i];
+		}
+		if (ret >= 0) {
+			ret = av_image_fill_pointers(frame->data, frame->format, FFALIGN(frame->height, 32), buf->data, frame->linesize);
+		}
+		for (int i = 1; ret >= 0 && i < 4; i++) {
+			if (frame->data[i]) {
+				frame->data[i] += i * FRAME_PLANE_PADDING(align);
+			}
+		}
+		frame->extended_data = frame->data;
+	}
+	if (ret < 0) {
+		if (frame->extended_data != frame->data) {
+			av_freep(&frame->extended_data);
+		}
+		frame->extended_data = NULL;
+		av_buffer_unref(&buf);
+		return ret;
+	}
+
+	frame->buf[0] = buf;
+	return 0;
+}
+*/
+import "C"
+
+////////////////////////////////////////////////////////////////////////////////
+// TYPES
+
+type (
+	AVBufferPool C.AVBufferPool
+)
+
+var (
+	bufferPoolAllocs   = make(map[uintptr]unsafe.Pointer)
+	bufferPoolAllocsMu sync.RWMutex
+)
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+// Allocate a pool of buffers of size bytes
+func AVUtil_buffer_pool_init(size int) *AVBufferPool {
+	var allocs unsafe.Pointer
+	pool := C.av_buffer_pool_init_(C.size_t(size), &allocs)
+	if pool == nil {
+		return nil
+	}
+
+	bufferPoolAllocsMu.Lock()
+	defer bufferPoolAllocsMu.Unlock()
+	bufferPoolAllocs[uintptr(unsafe.Pointer(pool))] = allocs
+	return (*AVBufferPool)(pool)
+}
+
+// Mark the pool as being available for freeing. The pool is freed when all
+// the buffers from the pool have been released.
+func AVUtil_buffer_pool_uninit(pool *AVBufferPool) {
+	bufferPoolAllocsMu.Lock()
+	delete(bufferPoolAllocs, uintptr(unsafe.Pointer(pool)))
+	bufferPoolAllocsMu.Unlock()
+	C.av_buffer_pool_uninit((**C.AVBufferPool)(unsafe.Pointer(&pool)))
+}
+
+// Return the number of buffers which the pool has allocated, rather than
+// re-used from the buffers released to the pool. Returns zero after the
+// pool has been uninitialized.
+func AVUtil_buffer_pool_allocs(pool *AVBufferPool) int {
+	bufferPoolAllocsMu.RLock()
+	defer bufferPoolAllocsMu.RUnlock()
+	if allocs, exists := bufferPoolAllocs[uintptr(unsafe.Pointer(pool))]; exists {
+		return int(C.av_buffer_pool_allocs_(allocs))
+	}
+	return 0
+}
+
+// Return the size of a buffer which holds the frame data with the alignment.
+// Video frames have the same layout as AVUtil_frame_get_buffer, with padding
+// after each plane.
+func AVUtil_frame_get_buffer_size(frame *AVFrame, align int) (int, error) {
+	size := C.av_frame_get_buffer_size((*C.AVFrame)(frame), C.int(align))
+	if size < 0 {
+		return 0, AVError(size)
+	}
+	return int(size), nil
+}
+
+// Allocate the frame data from a pool, where the size of the pool buffers
+// is returned by AVUtil_frame_get_buffer_size with the same alignment
+func AVUtil_frame_get_pool_buffer(frame *AVFrame, pool *AVBufferPool, align int) error {
+	if ret := AVError(C.av_frame_get_pool_buffer((*C.AVFrame)(frame), (*C.AVBufferPool)(pool), C.int(align))); ret != 0 {
+		return ret
+	}
+	return nil
+}
+
+// Return true if the frame data is writable (there is only one reference
+// to each buffer)
+func AVUtil_frame_is_writable(frame *AVFrame) bool {
+	return C.av_frame_is_writable((*C.AVFrame)(frame)) != 0
+}
@@ -0,0 +1,144 @@
+package ffmpeg_test
+
+import (
+	"testing"
+	"unsafe"
+
+	// Packages
+	"github.com/stretchr/testify/assert"
+
+	// Namespace imports
+	. "github.com/mutablelogic/go-media/sys/ffmpeg61"
+)
+
+func Test_avutil_buffer_000(t *testing.T) {
+	assert := assert.New(t)
+
+	// Make a video frame
+	frame := AVUtil_frame_alloc()
+	if !assert.NotNil(frame) {
+		t.SkipNow()
+	}
+	defer AVUtil_frame_free(frame)
+	frame.SetPixFmt(AV_PIX_FMT_YUV420P)
+	frame.SetWidth(640)
+	frame.SetHeight(480)
+
+	size, err := AVUtil_frame_get_buffer_size(frame, 64)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.GreaterOrEqual(size, 640*480*3/2)
+
+	pool := AVUtil_buffer_pool_init(size)
+	if !assert.NotNil(pool) {
+		t.SkipNow()
+	}
+	defer AVUtil_buffer_pool_uninit(pool)
+
+	// Allocate from the pool, and release
+	for i := 0; i < 10; i++ {
+		frame.SetPixFmt(AV_PIX_FMT_YUV420P)
+		frame.SetWidth(640)
+		frame.SetHeight(480)
+		if !assert.NoError(AVUtil_frame_get_pool_buffer(frame, pool, 64)) {
+			t.FailNow()
+		}
+		assert.True(AVUtil_frame_is_allocated(frame))
+		assert.True(AVUtil_frame_is_writable(frame))
+		assert.Equal(640, frame.Linesize(0))
+		assert.Equal(320, frame.Linesize(1))
+		assert.Len(frame.Bytes(0), 640*480)
+		assert.Len(frame.Bytes(1), 320*240)
+		AVUtil_frame_unref(frame)
+	}
+
+	// Released buffers are re-used
+	assert.Equal(1, AVUtil_buffer_pool_allocs(pool))
+}
+
+func Test_avutil_buffer_001(t *testing.T) {
+	assert := assert.New(t)
+
+	// Make a planar audio frame
+	frame := AVUtil_frame_alloc()
+	if !assert.NotNil(frame) {
+		t.SkipNow()
+	}
+	defer AVUtil_frame_free(frame)
+
+	var ch AVChannelLayout
+	AVUtil_channel_layout_default(&ch, 2)
+	frame.SetSampleFormat(AV_SAMPLE_FMT_FLTP)
+	frame.SetNumSamples(1024)
+	assert.NoError(frame.SetChannelLayout(ch))
+
+	size, err := AVUtil_frame_get_buffer_size(frame, 0)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	pool := AVUtil_buffer_pool_init(size)
+	if !assert.NotNil(pool) {
+		t.SkipNow()
+	}
+	defer AVUtil_buffer_pool_uninit(pool)
+
+	if !assert.NoError(AVUtil_frame_get_pool_buffer(frame, pool, 0)) {
+		t.FailNow()
+	}
+	assert.Len(frame.Float32(0), 1024)
+	assert.Len(frame.Float32(1), 1024)
+	AVUtil_frame_set_silence(frame, 0, 1024)
+
+	// A second reference makes the frame non-writable
+	ref := AVUtil_frame_alloc()
+	if !assert.NotNil(ref) {
+		t.SkipNow()
+	}
+	defer AVUtil_frame_free(ref)
+	assert.NoError(AVUtil_frame_ref(ref, frame))
+	assert.False(AVUtil_frame_is_writable(frame))
+	AVUtil_frame_unref(ref)
+	assert.True(AVUtil_frame_is_writable(frame))
+}
+
+func Test_avutil_buffer_002(t *testing.T) {
+	assert := assert.New(t)
+
+	// Make a video frame with a height which is not a multiple of 32
+	frame := AVUtil_frame_alloc()
+	if !assert.NotNil(frame) {
+		t.SkipNow()
+	}
+	defer AVUtil_frame_free(frame)
+	frame.SetPixFmt(AV_PIX_FMT_YUV420P)
+	frame.SetWidth(630)
+	frame.SetHeight(360)
+
+	// The buffer has the same layout as AVUtil_frame_get_buffer, with the
+	// height padded to 384 lines and padding after each plane
+	size, err := AVUtil_frame_get_buffer_size(frame, 64)
+	if !assert.NoError(err) {
+		t.FailNow()
+	}
+	assert.GreaterOrEqual(size, 640*384+2*320*192+4*64)
+
+	pool := AVUtil_buffer_pool_init(size)
+	if !assert.NotNil(pool) {
+		t.SkipNow()
+	}
+	defer AVUtil_buffer_pool_uninit(pool)
+
+	if !assert.NoError(AVUtil_frame_get_pool_buffer(frame, pool, 64)) {
+		t.FailNow()
+	}
+	defer AVUtil_frame_unref(frame)
+	assert.Equal(640, frame.Linesize(0))
+	assert.Equal(320, frame.Linesize(1))
+	assert.Equal(320, frame.Linesize(2))
+
+	// Planes do not overlap, including the padding
+	y, u, v := frame.Bytes(0), frame.Bytes(1), frame.Bytes(2)
+	assert.GreaterOrEqual(uintptr(unsafe.Pointer(&u[0]))-uintptr(unsafe.Pointer(&y[0])), uintptr(640*384+64))
+	assert.GreaterOrEqual(uintptr(unsafe.Pointer(&v[0]))-uintptr(unsafe.Pointer(&u[0])), uintptr(320*192+64))
+}
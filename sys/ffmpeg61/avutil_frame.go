@@ -112,6 +112,21 @@ func AVUtil_frame_unref(frame *AVFrame) {
 	C.av_frame_unref((*C.AVFrame)(frame))
 }
 
+// Set up a new reference to the data described by the source frame, and copy
+// the frame properties.
+func AVUtil_frame_ref(dst, src *AVFrame) error {
+	if ret := AVError(C.av_frame_ref((*C.struct_AVFrame)(dst), (*C.struct_AVFrame)(src))); ret != 0 {
+		return ret
+	}
+	return nil
+}
+
+// Move everything contained in src to dst and reset src. The destination
+// frame should be unreferenced first.
+func AVUtil_frame_move_ref(dst, src *AVFrame) {
+	C.av_frame_move_ref((*C.struct_AVFrame)(dst), (*C.struct_AVFrame)(src))
+}
+
 // Allocate new buffer(s) for audio or video data.
 // The following fields must be set on frame before calling this function:
 // format, width and height for video,
@@ -269,12 +284,33 @@ func (ctx *AVFrame) Linesize(plane int) int {
 
 // Return size of a plane in bytes
 func (ctx *AVFrame) Planesize(plane int) int {
-	if plane < 0 || plane >= int(C.AV_NUM_DATA_POINTERS) {
+	if plane < 0 {
 		return 0
 	}
 	if ctx.NumSamples() > 0 && ctx.SampleFormat() != AV_SAMPLE_FMT_NONE {
-		return AVUtil_get_bytes_per_sample(AVSampleFormat(ctx.format)) * ctx.NumSamples() * ctx.ChannelLayout().NumChannels()
+		// Planar audio has one plane per channel, packed audio has one plane
+		// with the channels interleaved
+		sz := AVUtil_get_bytes_per_sample(AVSampleFormat(ctx.format)) * ctx.NumSamples()
+		if AVUtil_sample_fmt_is_planar(AVSampleFormat(ctx.format)) {
+			if plane >= ctx.ChannelLayout().NumChannels() {
+				return 0
+			}
+			return sz
+		} else if plane > 0 {
+			return 0
+		}
+		return sz * ctx.ChannelLayout().NumChannels()
+	} else if plane >= int(C.AV_NUM_DATA_POINTERS) {
+		return 0
 	} else if ctx.Height() > 0 && ctx.PixFmt() != AV_PIX_FMT_NONE {
+		// The palette is stored in the second plane, and chroma planes are
+		// subsampled vertically
+		desc := AVUtil_get_pix_fmt_desc(ctx.PixFmt())
+		if desc != nil && desc.Flags().Is(AV_PIX_FMT_FLAG_PAL) && plane == 1 {
+			return AVPALETTE_SIZE
+		} else if desc != nil && (plane == 1 || plane == 2) {
+			return ctx.Linesize(plane) * -((-ctx.Height()) >> desc.Log2ChromaH())
+		}
 		return ctx.Linesize(plane) * ctx.Height()
 	} else {
 		return 0
@@ -317,7 +353,7 @@ func (ctx *AVFrame) Uint8(plane int) []uint8 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cUint8Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)))
+	return cUint8Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)))
 }
 
 // Returns a plane as a int8 array.
@@ -325,7 +361,7 @@ func (ctx *AVFrame) Int8(plane int) []int8 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cInt8Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)))
+	return cInt8Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)))
 }
 
 // Returns a plane as a uint16 array.
@@ -333,7 +369,7 @@ func (ctx *AVFrame) Uint16(plane int) []uint16 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cUint16Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)>>1))
+	return cUint16Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>1))
 }
 
 // Returns a plane as a int16 array.
@@ -341,7 +377,7 @@ func (ctx *AVFrame) Int16(plane int) []int16 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cInt16Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)>>1))
+	return cInt16Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>1))
 }
 
 // Returns a plane as a uint32 array.
@@ -349,7 +385,7 @@ func (ctx *AVFrame) Uint32(plane int) []uint32 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cUint32Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)>>2))
+	return cUint32Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>2))
 }
 
 // Returns a plane as a int32 array.
@@ -357,7 +393,7 @@ func (ctx *AVFrame) Int32(plane int) []int32 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cInt32Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)>>2))
+	return cInt32Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>2))
 }
 
 // Returns a plane as a float32 array.
@@ -365,7 +401,7 @@ func (ctx *AVFrame) Float32(plane int) []float32 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cFloat32Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)>>2))
+	return cFloat32Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>2))
 }
 
 // Returns a plane as a float64 array.
@@ -373,7 +409,15 @@ func (ctx *AVFrame) Float64(plane int) []float64 {
 	if !AVUtil_frame_is_allocated(ctx) {
 		return nil
 	}
-	return cFloat64Slice(unsafe.Pointer(ctx.data[plane]), C.int(ctx.Planesize(plane)>>3))
+	return cFloat64Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>3))
+}
+
+// Returns a plane as a int64 array.
+func (ctx *AVFrame) Int64(plane int) []int64 {
+	if !AVUtil_frame_is_allocated(ctx) {
+		return nil
+	}
+	return cInt64Slice(ctx.plane(plane), C.int(ctx.Planesize(plane)>>3))
 }
 
 // Returns the data as a set of planes and strides
@@ -386,3 +430,19 @@ func (ctx *AVFrame) Data() ([][]byte, []int) {
 	}
 	return planes, strides
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// PRIVATE METHODS
+
+// Return a pointer to the start of a plane, or nil. Audio planes are read
+// from extended_data, which has a plane for each channel when there are more
+// channels than data pointers.
+func (ctx *AVFrame) plane(plane int) unsafe.Pointer {
+	if ctx.Planesize(plane) == 0 {
+		return nil
+	} else if ctx.NumSamples() > 0 && ctx.extended_data != nil {
+		return unsafe.Pointer(unsafe.Slice(ctx.extended_data, plane+1)[plane])
+	} else {
+		return unsafe.Pointer(ctx.data[plane])
+	}
+}
@@ -247,6 +247,23 @@ const (
 	//AV_PIX_FMT_RGBAF32LE AVPixelFormat = C.AV_PIX_FMT_RGBAF32LE ///< IEEE-754 single precision packed RGBA 32:32:32:32, 128bpp, RGBARGBA..., little-endian
 )
 
+const (
+	AV_PIX_FMT_FLAG_BE        AVPixFmtFlag = C.AV_PIX_FMT_FLAG_BE        // Pixel format is big-endian
+	AV_PIX_FMT_FLAG_PAL       AVPixFmtFlag = C.AV_PIX_FMT_FLAG_PAL       // Pixel format has a palette in data[1]
+	AV_PIX_FMT_FLAG_BITSTREAM AVPixFmtFlag = C.AV_PIX_FMT_FLAG_BITSTREAM // All values of a component are bit-wise packed end to end
+	AV_PIX_FMT_FLAG_HWACCEL   AVPixFmtFlag = C.AV_PIX_FMT_FLAG_HWACCEL   // Pixel format is an HW accelerated format
+	AV_PIX_FMT_FLAG_PLANAR    AVPixFmtFlag = C.AV_PIX_FMT_FLAG_PLANAR    // At least one pixel component is not in the first data plane
+	AV_PIX_FMT_FLAG_RGB       AVPixFmtFlag = C.AV_PIX_FMT_FLAG_RGB       // The pixel format contains RGB-like data
+	AV_PIX_FMT_FLAG_ALPHA     AVPixFmtFlag = C.AV_PIX_FMT_FLAG_ALPHA     // The pixel format has an alpha channel
+	AV_PIX_FMT_FLAG_BAYER     AVPixFmtFlag = C.AV_PIX_FMT_FLAG_BAYER     // The pixel format is following a Bayer pattern
+	AV_PIX_FMT_FLAG_FLOAT     AVPixFmtFlag = C.AV_PIX_FMT_FLAG_FLOAT     // The pixel format contains IEEE-754 floating point values
+)
+
+const (
+	// Size of the palette for paletted pixel formats, in bytes
+	AVPALETTE_SIZE = C.AVPALETTE_SIZE
+)
+
 ////////////////////////////////////////////////////////////////////////////////
 // STRINGIFY
 
@@ -301,3 +318,56 @@ func AVUtil_get_pix_fmt_desc(pixfmt AVPixelFormat) *AVPixFmtDescriptor {
 func AVUtil_pix_fmt_count_planes(pixfmt AVPixelFormat) int {
 	return int(C.av_pix_fmt_count_planes(C.enum_AVPixelFormat(pixfmt)))
 }
+
+////////////////////////////////////////////////////////////////////////////////
+// PROPERTIES
+
+// Return the name of the pixel format
+func (ctx *AVPixFmtDescriptor) Name() string {
+	return C.GoString(ctx.name)
+}
+
+// Return the number of components in the pixel format
+func (ctx *AVPixFmtDescriptor) NumComponents() int {
+	return int(ctx.nb_components)
+}
+
+// Return the number of bits in a component, or zero if the component
+// does not exist
+func (ctx *AVPixFmtDescriptor) Depth(comp int) int {
+	if comp < 0 || comp >= int(ctx.nb_components) {
+		return 0
+	}
+	return int(ctx.comp[comp].depth)
+}
+
+// Return the plane which stores a component, or -1 if the component
+// does not exist
+func (ctx *AVPixFmtDescriptor) Plane(comp int) int {
+	if comp < 0 || comp >= int(ctx.nb_components) {
+		return -1
+	}
+	return int(ctx.comp[comp].plane)
+}
+
+// Return the amount to shift the luma width right to get the chroma width
+func (ctx *AVPixFmtDescriptor) Log2ChromaW() int {
+	return int(ctx.log2_chroma_w)
+}
+
+// Return the amount to shift the luma height right to get the chroma height
+func (ctx *AVPixFmtDescriptor) Log2ChromaH() int {
+	return int(ctx.log2_chroma_h)
+}
+
+// Return the pixel format flags
+func (ctx *AVPixFmtDescriptor) Flags() AVPixFmtFlag {
+	return AVPixFmtFlag(ctx.flags)
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// PUBLIC METHODS
+
+func (v AVPixFmtFlag) Is(flag AVPixFmtFlag) bool {
+	return v&flag == flag
+}
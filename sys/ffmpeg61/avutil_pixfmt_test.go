@@ -3,6 +3,9 @@ package ffmpeg_test
 import (
 	"testing"
 
+	// Packages
+	"github.com/stretchr/testify/assert"
+
 	// Namespace imports
 	. "github.com/mutablelogic/go-media/sys/ffmpeg61"
 )
@@ -17,3 +20,25 @@ func Test_avutil_pixfmt_002(t *testing.T) {
 		t.Logf("pixel_fmt[%d]=%v", fmt, AVUtil_get_pix_fmt_name(fmt))
 	}
 }
+
+func Test_avutil_pixfmt_003(t *testing.T) {
+	assert := assert.New(t)
+
+	desc := AVUtil_get_pix_fmt_desc(AV_PIX_FMT_YUV420P10LE)
+	if !assert.NotNil(desc) {
+		t.FailNow()
+	}
+	assert.Equal("yuv420p10le", desc.Name())
+	assert.Equal(3, desc.NumComponents())
+	assert.Equal(10, desc.Depth(0))
+	assert.Equal(1, desc.Log2ChromaW())
+	assert.Equal(1, desc.Log2ChromaH())
+	assert.True(desc.Flags().Is(AV_PIX_FMT_FLAG_PLANAR))
+	assert.False(desc.Flags().Is(AV_PIX_FMT_FLAG_BE))
+
+	desc = AVUtil_get_pix_fmt_desc(AV_PIX_FMT_PAL8)
+	if !assert.NotNil(desc) {
+		t.FailNow()
+	}
+	assert.True(desc.Flags().Is(AV_PIX_FMT_FLAG_PAL))
+}
@@ -25,6 +25,29 @@ func (r AVRational) MarshalJSON() ([]byte, error) {
 	return json.Marshal(fmt.Sprintf("%d/%d", r.num, r.den))
 }
 
+func (r *AVRational) UnmarshalJSON(data []byte) error {
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	switch v := v.(type) {
+	case float64:
+		if v != 0 {
+			return fmt.Errorf("invalid rational: %v", v)
+		}
+		*r = AVRational{}
+	case string:
+		var num, den int
+		if _, err := fmt.Sscanf(v, "%d/%d", &num, &den); err != nil {
+			return fmt.Errorf("invalid rational: %q", v)
+		}
+		*r = AVUtil_rational(num, den)
+	default:
+		return fmt.Errorf("invalid rational: %s", data)
+	}
+	return nil
+}
+
 func (r AVRational) String() string {
 	data, _ := json.MarshalIndent(r, "", "  ")
 	return string(data)
@@ -98,3 +121,11 @@ func AVUtil_rescale_rnd(a, b, c int64, rnd AVRounding) int64 {
 func AVUtil_compare_ts(a int64, a_tb AVRational, b int64, b_tb AVRational) int {
 	return int(C.av_compare_ts(C.int64_t(a), C.AVRational(a_tb), C.int64_t(b), C.AVRational(b_tb)))
 }
+
+// Reduce a fraction, returning the reduced fraction and true if the
+// reduction is exact.
+func AVUtil_reduce(num, den, max int64) (AVRational, bool) {
+	var dst_num, dst_den C.int
+	exact := C.av_reduce(&dst_num, &dst_den, C.int64_t(num), C.int64_t(den), C.int64_t(max))
+	return AVUtil_rational(int(dst_num), int(dst_den)), exact != 0
+}
@@ -12,6 +12,7 @@ import (
 #cgo pkg-config: libavutil
 #include <libavutil/avutil.h>
 #include <libavutil/samplefmt.h>
+#include <libavutil/frame.h>
 */
 import "C"
 
@@ -157,3 +158,8 @@ func AVUtil_samples_copy(dst, src *AVSamples, dst_offset, src_offset, nb_samples
 func AVUtil_samples_set_silence(data *AVSamples, offset int, nb_samples int) {
 	C.av_samples_set_silence(&data.planes[0], C.int(offset), C.int(nb_samples), data.nb_channels, data.sample_fmt)
 }
+
+// Fill the samples of an audio frame with silence, from the offset
+func AVUtil_frame_set_silence(frame *AVFrame, offset int, nb_samples int) {
+	C.av_samples_set_silence(frame.extended_data, C.int(offset), C.int(nb_samples), frame.ch_layout.nb_channels, C.enum_AVSampleFormat(frame.format))
+}
@@ -72,6 +72,13 @@ func cInt32Slice(p unsafe.Pointer, sz C.int) []int32 {
 	return (*[1 << 30]int32)(p)[:int(sz)]
 }
 
+func cInt64Slice(p unsafe.Pointer, sz C.int) []int64 {
+	if p == nil {
+		return nil
+	}
+	return (*[1 << 30]int64)(p)[:int(sz)]
+}
+
 func cFloat32Slice(p unsafe.Pointer, sz C.int) []float32 {
 	if p == nil {
 		return nil
@@ -93,6 +100,27 @@ func cAVStreamSlice(p unsafe.Pointer, sz C.int) []*AVStream {
 	return (*[1 << 30]*AVStream)(p)[:int(sz)]
 }
 
+func cAVChapterSlice(p unsafe.Pointer, sz C.int) []*AVChapter {
+	if p == nil {
+		return nil
+	}
+	return (*[1 << 30]*AVChapter)(p)[:int(sz)]
+}
+
+func cAVSubtitleRectSlice(p unsafe.Pointer, sz C.int) []*AVSubtitleRect {
+	if p == nil {
+		return nil
+	}
+	return (*[1 << 30]*AVSubtitleRect)(p)[:int(sz)]
+}
+
+func cAVFilterContextSlice(p unsafe.Pointer, sz C.int) []*AVFilterContext {
+	if p == nil {
+		return nil
+	}
+	return (*[1 << 30]*AVFilterContext)(p)[:int(sz)]
+}
+
 func cAVDeviceInfoSlice(p unsafe.Pointer, sz C.int) []*AVDeviceInfo {
 	if p == nil {
 		return nil